@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
 	"LogLens/internal/app"
@@ -15,49 +16,258 @@ import (
 )
 
 type App struct {
-	ctx     context.Context
-	loglens *app.LogLens
+	ctx        context.Context
+	mu         sync.RWMutex
+	loglens    *lease
+	workspaces *app.WorkspaceManager
+	library    *app.QueryLibrary
+	// retiring tracks workspaces closed by a switch that are still in use.
+	retiring sync.WaitGroup
+	retired  map[*lease]bool
+	// switching serializes workspace opens and deletes.
+	switching sync.Mutex
+}
+
+// lease is an open LogLens together with the calls still using it. The lock
+// only guards swapping it, so a long import never blocks a workspace switch.
+type lease struct {
+	ll    *app.LogLens
+	path  string
+	users sync.WaitGroup
 }
 
 func NewApp() *App {
-	return &App{}
+	return &App{retired: make(map[*lease]bool)}
 }
 
 func (a *App) startup(ctx context.Context) {
 	a.ctx = ctx
 	
-	config := app.Config{
-		DatabasePath: a.getDatabasePath(),
+	workspaces, err := app.NewWorkspaceManager(a.getDataDir())
+	if err != nil {
+		fmt.Printf("Failed to initialize workspaces: %v\n", err)
+		return
+	}
+	a.workspaces = workspaces
+
+	library, err := app.NewQueryLibrary(filepath.Join(a.getDataDir(), "library.db"))
+	if err != nil {
+		fmt.Printf("Failed to open query library: %v\n", err)
+	} else {
+		a.library = library
+	}
+
+	ws, err := workspaces.Current()
+	if err != nil {
+		fmt.Printf("Failed to resolve workspace: %v\n", err)
+		return
 	}
 	
-	loglens, err := app.NewLogLens(config)
+	loglens, err := a.openLogLens(ws.Path)
 	if err != nil {
 		fmt.Printf("Failed to initialize LogLens: %v\n", err)
 		return
 	}
 	
-	a.loglens = loglens
+	a.loglens = &lease{ll: loglens, path: ws.Path}
+	workspaces.MarkOpened(ws.Name)
 }
 
 func (a *App) shutdown(ctx context.Context) {
-	if a.loglens != nil {
-		a.loglens.Close()
+	a.mu.Lock()
+	current := a.loglens
+	a.loglens = nil
+	a.mu.Unlock()
+
+	if current != nil {
+		current.users.Wait()
+		current.ll.Close()
+	}
+	a.retiring.Wait()
+	if a.library != nil {
+		a.library.Close()
 	}
 }
 
-func (a *App) getDatabasePath() string {
+func (a *App) openLogLens(dbPath string) (*app.LogLens, error) {
+	config := app.Config{
+		DatabasePath:      dbPath,
+		RetentionInterval: time.Hour,
+	}
+	return app.NewLogLens(config)
+}
+
+// acquire returns the active LogLens and keeps it open until release is
+// called, so a workspace switch never closes a database under a running call.
+func (a *App) acquire() (*app.LogLens, func(), error) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.loglens == nil {
+		return nil, nil, fmt.Errorf("LogLens not initialized")
+	}
+	current := a.loglens
+	current.users.Add(1)
+	return current.ll, current.users.Done, nil
+}
+
+// retire closes l once the calls still using it return. Close itself waits
+// for the instance's background work.
+func (a *App) retire(l *lease) {
+	a.mu.Lock()
+	a.retired[l] = true
+	a.mu.Unlock()
+
+	a.retiring.Add(1)
+	go func() {
+		defer a.retiring.Done()
+		l.users.Wait()
+		if err := l.ll.Close(); err != nil {
+			fmt.Printf("Failed to close workspace: %v\n", err)
+		}
+		a.mu.Lock()
+		delete(a.retired, l)
+		a.mu.Unlock()
+	}()
+}
+
+// inUse reports whether the database at path is open, either as the current
+// workspace or as one still closing after a switch.
+func (a *App) inUse(path string) bool {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.loglens != nil && a.loglens.path == path {
+		return true
+	}
+	for l := range a.retired {
+		if l.path == path {
+			return true
+		}
+	}
+	return false
+}
+
+func (a *App) getDataDir() string {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return "./loglens.db"
+		return "."
 	}
 	
 	loglensDir := filepath.Join(homeDir, ".loglens")
 	if err := os.MkdirAll(loglensDir, 0755); err != nil {
 		fmt.Printf("Warning: failed to create directory %s: %v\n", loglensDir, err)
-		return filepath.Join(".", "loglens.db")
+		return "."
 	}
 	
-	return filepath.Join(loglensDir, "loglens.db")
+	return loglensDir
+}
+
+func (a *App) ListWorkspaces() ([]app.Workspace, error) {
+	if a.workspaces == nil {
+		return nil, fmt.Errorf("workspaces not initialized")
+	}
+	return a.workspaces.List()
+}
+
+func (a *App) CurrentWorkspace() (*app.Workspace, error) {
+	if a.workspaces == nil {
+		return nil, fmt.Errorf("workspaces not initialized")
+	}
+	return a.workspaces.Current()
+}
+
+func (a *App) SelectWorkspaceDirectory() (string, error) {
+	if a.ctx == nil {
+		return "", fmt.Errorf("app not initialized")
+	}
+
+	return runtime.OpenDirectoryDialog(a.ctx, runtime.OpenDialogOptions{
+		Title:                "Select workspace folder",
+		CanCreateDirectories: true,
+	})
+}
+
+func (a *App) CreateWorkspace(name string, dir string, description string) (*app.Workspace, error) {
+	if a.workspaces == nil {
+		return nil, fmt.Errorf("workspaces not initialized")
+	}
+	return a.workspaces.Create(name, dir, description)
+}
+
+// OpenWorkspace opens the named workspace and swaps it in without a restart.
+// Opening the current workspace does nothing. The previous database stays
+// active if the new one fails to open; otherwise it is closed once the calls
+// still using it, such as an import, return.
+func (a *App) OpenWorkspace(name string) (*app.Workspace, error) {
+	if a.workspaces == nil {
+		return nil, fmt.Errorf("workspaces not initialized")
+	}
+
+	a.switching.Lock()
+	defer a.switching.Unlock()
+
+	ws, err := a.workspaces.Get(name)
+	if err != nil {
+		return nil, err
+	}
+
+	a.mu.RLock()
+	current := a.loglens != nil && a.loglens.path == ws.Path
+	a.mu.RUnlock()
+	if current {
+		return ws, nil
+	}
+
+	loglens, err := a.openLogLens(ws.Path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open workspace %s: %w", name, err)
+	}
+	if err := a.workspaces.MarkOpened(ws.Name); err != nil {
+		loglens.Close()
+		return nil, err
+	}
+
+	a.mu.Lock()
+	previous := a.loglens
+	a.loglens = &lease{ll: loglens, path: ws.Path}
+	a.mu.Unlock()
+
+	if previous != nil {
+		a.retire(previous)
+	}
+
+	if a.ctx != nil {
+		runtime.EventsEmit(a.ctx, "workspace:changed", ws)
+	}
+	return ws, nil
+}
+
+func (a *App) RenameWorkspace(oldName string, newName string) error {
+	if a.workspaces == nil {
+		return fmt.Errorf("workspaces not initialized")
+	}
+	return a.workspaces.Rename(oldName, newName)
+}
+
+// DeleteWorkspace refuses to remove the files of a workspace that is still
+// open, including one that is closing after a switch.
+func (a *App) DeleteWorkspace(name string, removeFiles bool) error {
+	if a.workspaces == nil {
+		return fmt.Errorf("workspaces not initialized")
+	}
+
+	a.switching.Lock()
+	defer a.switching.Unlock()
+
+	if removeFiles {
+		ws, err := a.workspaces.Get(name)
+		if err != nil {
+			return err
+		}
+		if a.inUse(ws.Path) {
+			return fmt.Errorf("workspace %s is still in use", name)
+		}
+	}
+	return a.workspaces.Delete(name, removeFiles)
 }
 
 func (a *App) SelectLogFile() (string, error) {
@@ -79,16 +289,96 @@ func (a *App) SelectLogFile() (string, error) {
 	return path, nil
 }
 
+func (a *App) GetContext(id string, before, after int) (*domain.RecordContext, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return ll.GetContext(a.ctx, id, before, after)
+}
+
 func (a *App) GetTimeline(req domain.TimelineRequest) ([]domain.TimelinePoint, error) {
-	if a.loglens == nil {
-		return nil, fmt.Errorf("LogLens not initialized")
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	start := time.Now()
+	points, err := ll.GetTimeline(a.ctx, req)
+	a.recordQuery(app.TimelineQuery(req), start, nil, err)
+	return points, err
+}
+
+func (a *App) GetTimelineSeries(req domain.TimelineRequest) (*domain.TimelineResult, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	start := time.Now()
+	result, err := ll.GetTimelineSeries(a.ctx, req)
+	a.recordQuery(app.TimelineQuery(req), start, nil, err)
+	return result, err
+}
+
+func (a *App) GetCorrelationFields() ([]string, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return ll.GetCorrelationFields(a.ctx)
+}
+
+func (a *App) Correlate(id string, field string) (*domain.Correlation, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return ll.Correlate(a.ctx, id, field)
+}
+
+func (a *App) ListTraces(query domain.Query, limit int) ([]domain.TraceSummary, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return ll.ListTraces(a.ctx, query, limit)
+}
+
+func (a *App) GetTrace(traceID string) (*domain.Trace, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return ll.GetTrace(a.ctx, traceID)
+}
+
+func (a *App) DetectAnomalies(req domain.AnomalyRequest) ([]domain.Anomaly, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
 	}
-	return a.loglens.GetTimeline(a.ctx, req)
+	defer release()
+	return ll.DetectAnomalies(a.ctx, req)
+}
+
+func (a *App) TopAnomalies(limit int) ([]domain.Anomaly, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	return ll.TopAnomalies(a.ctx, limit)
 }
 
 func (a *App) ExportReport(query domain.Query, bucketMs int64) (string, error) {
-	if a.loglens == nil {
-		return "", fmt.Errorf("LogLens not initialized")
+	if a.ctx == nil {
+		return "", fmt.Errorf("app not initialized")
 	}
 
 	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
@@ -106,12 +396,18 @@ func (a *App) ExportReport(query domain.Query, bucketMs int64) (string, error) {
 		return "", nil
 	}
 
-	res, err := a.loglens.Query(a.ctx, query)
+	ll, release, err := a.acquire()
 	if err != nil {
 		return "", err
 	}
+	defer release()
 
-	points, err := a.loglens.GetTimeline(a.ctx, domain.TimelineRequest{Filters: query.Filters, BucketMs: bucketMs})
+	res, err := ll.Query(a.ctx, query)
+	if err != nil {
+		return "", err
+	}
+
+	points, err := ll.GetTimeline(a.ctx, domain.TimelineRequest{Filters: query.Filters, BucketMs: bucketMs})
 	if err != nil {
 		return "", err
 	}
@@ -137,60 +433,389 @@ func (a *App) ExportReport(query domain.Query, bucketMs int64) (string, error) {
 	return path, nil
 }
 
+func (a *App) GetQueryHistory(limit int) ([]domain.QueryHistoryEntry, error) {
+	if a.library == nil {
+		return nil, fmt.Errorf("query library not initialized")
+	}
+	return a.library.GetQueryHistory(a.ctx, limit)
+}
+
+func (a *App) ClearQueryHistory() error {
+	if a.library == nil {
+		return fmt.Errorf("query library not initialized")
+	}
+	return a.library.ClearQueryHistory(a.ctx)
+}
+
+func (a *App) SaveQuery(q domain.SavedQuery) (*domain.SavedQuery, error) {
+	if a.library == nil {
+		return nil, fmt.Errorf("query library not initialized")
+	}
+	return a.library.SaveQuery(a.ctx, q)
+}
+
+func (a *App) ListSavedQueries(search string) ([]domain.SavedQuery, error) {
+	if a.library == nil {
+		return nil, fmt.Errorf("query library not initialized")
+	}
+	return a.library.ListSavedQueries(a.ctx, search)
+}
+
+func (a *App) RenameSavedQuery(id int64, name string) error {
+	if a.library == nil {
+		return fmt.Errorf("query library not initialized")
+	}
+	return a.library.RenameSavedQuery(a.ctx, id, name)
+}
+
+func (a *App) DeleteSavedQuery(id int64) error {
+	if a.library == nil {
+		return fmt.Errorf("query library not initialized")
+	}
+	return a.library.DeleteSavedQuery(a.ctx, id)
+}
+
+// ExportSavedQueries writes the saved query library to a JSON file chosen by
+// the user and returns its path, or "" if the dialog was cancelled.
+func (a *App) ExportSavedQueries() (string, error) {
+	if a.ctx == nil || a.library == nil {
+		return "", fmt.Errorf("query library not initialized")
+	}
+
+	path, err := runtime.SaveFileDialog(a.ctx, runtime.SaveDialogOptions{
+		Title:           "Export Saved Queries",
+		DefaultFilename: "loglens-queries.json",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "JSON", Pattern: "*.json"},
+			{DisplayName: "All files", Pattern: "*"},
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		return "", nil
+	}
+
+	data, err := a.library.ExportSavedQueries(a.ctx)
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", err
+	}
+
+	return path, nil
+}
+
+// ImportSavedQueries reads a saved query library from a JSON file chosen by
+// the user and returns how many queries were imported.
+func (a *App) ImportSavedQueries() (int, error) {
+	if a.ctx == nil || a.library == nil {
+		return 0, fmt.Errorf("query library not initialized")
+	}
+
+	path, err := runtime.OpenFileDialog(a.ctx, runtime.OpenDialogOptions{
+		Title: "Import Saved Queries",
+		Filters: []runtime.FileFilter{
+			{DisplayName: "JSON", Pattern: "*.json"},
+			{DisplayName: "All files", Pattern: "*"},
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+	if path == "" {
+		return 0, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	return a.library.ImportSavedQueries(a.ctx, data)
+}
+
 func (a *App) ImportFile(filePath string, parserConfig domain.ParserConfig) (*domain.ImportResult, error) {
-	if a.loglens == nil {
-		return nil, fmt.Errorf("LogLens not initialized")
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 	reporter := &wailsProgressReporter{ctx: a.ctx}
-	return a.loglens.ImportFile(a.ctx, filePath, parserConfig, reporter)
+	return ll.ImportFile(a.ctx, filePath, parserConfig, reporter)
+}
+
+func (a *App) ImportFileWithOptions(filePath string, parserConfig domain.ParserConfig, options domain.ImportOptions) (*domain.ImportResult, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	reporter := &wailsProgressReporter{ctx: a.ctx}
+	return ll.ImportFileWithOptions(a.ctx, filePath, parserConfig, options, reporter)
+}
+
+func (a *App) AutoImportFileWithOptions(filePath string, options domain.ImportOptions) (*domain.ImportResult, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	reporter := &wailsProgressReporter{ctx: a.ctx}
+	return ll.AutoImportFileWithOptions(a.ctx, filePath, options, reporter)
 }
 
 func (a *App) AutoImportFile(filePath string) (*domain.ImportResult, error) {
-	if a.loglens == nil {
-		return nil, fmt.Errorf("LogLens not initialized")
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 	reporter := &wailsProgressReporter{ctx: a.ctx}
-	return a.loglens.AutoImportFile(a.ctx, filePath, reporter)
+	return ll.AutoImportFile(a.ctx, filePath, reporter)
 }
 
 func (a *App) Query(query domain.Query) (*domain.QueryResult, error) {
-	if a.loglens == nil {
-		return nil, fmt.Errorf("LogLens not initialized")
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 	
-	return a.loglens.Query(a.ctx, query)
+	start := time.Now()
+	result, err := ll.Query(a.ctx, query)
+	a.recordQuery(query, start, result, err)
+	return result, err
+}
+
+// recordQuery adds query, run from start, to the history of the query
+// library.
+func (a *App) recordQuery(query domain.Query, start time.Time, result *domain.QueryResult, err error) {
+	if a.library != nil {
+		a.library.RecordQuery(query, start, result, err)
+	}
 }
 
 func (a *App) ExplainQuery(query domain.Query) (string, error) {
-	if a.loglens == nil {
-		return "", fmt.Errorf("LogLens not initialized")
+	ll, release, err := a.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	
+	return ll.ExplainQuery(query)
+}
+
+func (a *App) ParseQuery(text string) ([]domain.FilterCondition, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	
+	return ll.ParseQuery(text)
+}
+
+func (a *App) FormatQuery(filters []domain.FilterCondition) (string, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	
+	return ll.FormatQuery(filters)
+}
+
+func (a *App) Facets(query domain.Query, fields []string, topN int) ([]domain.Facet, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 	
-	return a.loglens.ExplainQuery(query)
+	start := time.Now()
+	facets, err := ll.Facets(a.ctx, query, fields, topN)
+	a.recordQuery(query, start, nil, err)
+	return facets, err
+}
+
+func (a *App) GetPatterns(query domain.Query, limit int) ([]domain.LogGroup, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	
+	start := time.Now()
+	groups, err := ll.GetPatterns(a.ctx, query, limit)
+	a.recordQuery(query, start, nil, err)
+	return groups, err
+}
+
+func (a *App) QueryPattern(query domain.Query, pattern string) (*domain.QueryResult, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	
+	query = app.PatternQuery(query, pattern)
+	start := time.Now()
+	result, err := ll.Query(a.ctx, query)
+	a.recordQuery(query, start, result, err)
+	return result, err
 }
 
 func (a *App) GetRecord(id string) (*domain.LogRecord, error) {
-	if a.loglens == nil {
-		return nil, fmt.Errorf("LogLens not initialized")
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	
+	return ll.GetRecord(a.ctx, id)
+}
+
+func (a *App) GetFieldCatalog(source string) ([]domain.FieldInfo, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	
+	return ll.GetFieldCatalog(a.ctx, source)
+}
+
+func (a *App) DeleteByQuery(filters []domain.FilterCondition, dryRun bool) (*domain.DeleteResult, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	
+	return ll.DeleteByQuery(a.ctx, filters, dryRun)
+}
+
+func (a *App) GetRetentionPolicies() ([]domain.RetentionPolicy, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	
+	return ll.GetRetentionPolicies(a.ctx)
+}
+
+func (a *App) SetRetentionPolicies(policies []domain.RetentionPolicy) error {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+	
+	return ll.SetRetentionPolicies(a.ctx, policies)
+}
+
+func (a *App) ApplyRetention(dryRun bool) (*domain.RetentionResult, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	
+	return ll.ApplyRetention(a.ctx, dryRun)
+}
+
+func (a *App) CompactDatabase() (*domain.CompactResult, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 	
-	return a.loglens.GetRecord(a.ctx, id)
+	return ll.Compact(a.ctx)
+}
+
+func (a *App) GetPartitioning() (domain.PartitionGranularity, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	
+	return ll.GetPartitioning(a.ctx)
+}
+
+func (a *App) SetPartitioning(granularity domain.PartitionGranularity) error {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+	
+	return ll.SetPartitioning(a.ctx, granularity)
+}
+
+func (a *App) ListPartitions() ([]domain.PartitionInfo, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	
+	return ll.ListPartitions(a.ctx)
+}
+
+func (a *App) DropPartitionsBefore(beforeMs int64, dryRun bool) (*domain.DeleteResult, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+	
+	return ll.DropPartitionsBefore(a.ctx, beforeMs, dryRun)
+}
+
+func (a *App) GetCompression() (domain.CompressionMode, error) {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return "", err
+	}
+	defer release()
+	
+	return ll.GetCompression(a.ctx)
+}
+
+func (a *App) SetCompression(mode domain.CompressionMode) error {
+	ll, release, err := a.acquire()
+	if err != nil {
+		return err
+	}
+	defer release()
+	
+	return ll.SetCompression(a.ctx, mode)
 }
 
 func (a *App) GetSupportedParserTypes() []domain.ParserType {
-	if a.loglens == nil {
+	ll, release, err := a.acquire()
+	if err != nil {
 		return []domain.ParserType{}
 	}
+	defer release()
 	
-	return a.loglens.GetSupportedParserTypes()
+	return ll.GetSupportedParserTypes()
 }
 
 func (a *App) GetStats() (*app.Stats, error) {
-	if a.loglens == nil {
-		return nil, fmt.Errorf("LogLens not initialized")
+	ll, release, err := a.acquire()
+	if err != nil {
+		return nil, err
 	}
+	defer release()
 	
-	return a.loglens.GetStats(a.ctx)
+	return ll.GetStats(a.ctx)
 }
 
 type wailsProgressReporter struct {
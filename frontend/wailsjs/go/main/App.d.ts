@@ -3,22 +3,106 @@
 import {domain} from '../models';
 import {app} from '../models';
 
+export function ApplyRetention(arg1:boolean):Promise<domain.RetentionResult>;
+
 export function AutoImportFile(arg1:string):Promise<domain.ImportResult>;
 
+export function AutoImportFileWithOptions(arg1:string,arg2:domain.ImportOptions):Promise<domain.ImportResult>;
+
+export function ClearQueryHistory():Promise<void>;
+
+export function CompactDatabase():Promise<domain.CompactResult>;
+
+export function Correlate(arg1:string,arg2:string):Promise<domain.Correlation>;
+
+export function CreateWorkspace(arg1:string,arg2:string,arg3:string):Promise<app.Workspace>;
+
+export function CurrentWorkspace():Promise<app.Workspace>;
+
+export function DeleteByQuery(arg1:Array<domain.FilterCondition>,arg2:boolean):Promise<domain.DeleteResult>;
+
+export function DeleteSavedQuery(arg1:number):Promise<void>;
+
+export function DeleteWorkspace(arg1:string,arg2:boolean):Promise<void>;
+
+export function DetectAnomalies(arg1:domain.AnomalyRequest):Promise<Array<domain.Anomaly>>;
+
+export function DropPartitionsBefore(arg1:number,arg2:boolean):Promise<domain.DeleteResult>;
+
 export function ExplainQuery(arg1:domain.Query):Promise<string>;
 
 export function ExportReport(arg1:domain.Query,arg2:number):Promise<string>;
 
+export function ExportSavedQueries():Promise<string>;
+
+export function Facets(arg1:domain.Query,arg2:Array<string>,arg3:number):Promise<Array<domain.Facet>>;
+
+export function FormatQuery(arg1:Array<domain.FilterCondition>):Promise<string>;
+
+export function GetCompression():Promise<domain.CompressionMode>;
+
+export function GetContext(arg1:string,arg2:number,arg3:number):Promise<domain.RecordContext>;
+
+export function GetCorrelationFields():Promise<Array<string>>;
+
+export function GetFieldCatalog(arg1:string):Promise<Array<domain.FieldInfo>>;
+
+export function GetPartitioning():Promise<domain.PartitionGranularity>;
+
+export function GetPatterns(arg1:domain.Query,arg2:number):Promise<Array<domain.LogGroup>>;
+
+export function GetQueryHistory(arg1:number):Promise<Array<domain.QueryHistoryEntry>>;
+
 export function GetRecord(arg1:string):Promise<domain.LogRecord>;
 
+export function GetRetentionPolicies():Promise<Array<domain.RetentionPolicy>>;
+
 export function GetStats():Promise<app.Stats>;
 
 export function GetSupportedParserTypes():Promise<Array<domain.ParserType>>;
 
 export function GetTimeline(arg1:domain.TimelineRequest):Promise<Array<domain.TimelinePoint>>;
 
+export function GetTimelineSeries(arg1:domain.TimelineRequest):Promise<domain.TimelineResult>;
+
+export function GetTrace(arg1:string):Promise<domain.Trace>;
+
 export function ImportFile(arg1:string,arg2:domain.ParserConfig):Promise<domain.ImportResult>;
 
+export function ImportFileWithOptions(arg1:string,arg2:domain.ParserConfig,arg3:domain.ImportOptions):Promise<domain.ImportResult>;
+
+export function ImportSavedQueries():Promise<number>;
+
+export function ListPartitions():Promise<Array<domain.PartitionInfo>>;
+
+export function ListSavedQueries(arg1:string):Promise<Array<domain.SavedQuery>>;
+
+export function ListTraces(arg1:domain.Query,arg2:number):Promise<Array<domain.TraceSummary>>;
+
+export function ListWorkspaces():Promise<Array<app.Workspace>>;
+
+export function OpenWorkspace(arg1:string):Promise<app.Workspace>;
+
+export function ParseQuery(arg1:string):Promise<Array<domain.FilterCondition>>;
+
 export function Query(arg1:domain.Query):Promise<domain.QueryResult>;
 
+export function QueryPattern(arg1:domain.Query,arg2:string):Promise<domain.QueryResult>;
+
+export function RenameSavedQuery(arg1:number,arg2:string):Promise<void>;
+
+export function RenameWorkspace(arg1:string,arg2:string):Promise<void>;
+
+export function SaveQuery(arg1:domain.SavedQuery):Promise<domain.SavedQuery>;
+
 export function SelectLogFile():Promise<string>;
+
+export function SelectWorkspaceDirectory():Promise<string>;
+
+export function SetCompression(arg1:domain.CompressionMode):Promise<void>;
+
+export function SetPartitioning(arg1:domain.PartitionGranularity):Promise<void>;
+
+export function SetRetentionPolicies(arg1:Array<domain.RetentionPolicy>):Promise<void>;
+
+export function TopAnomalies(arg1:number):Promise<Array<domain.Anomaly>>;
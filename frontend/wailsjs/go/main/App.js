@@ -2,10 +2,58 @@
 // Cynhyrchwyd y ffeil hon yn awtomatig. PEIDIWCH Â MODIWL
 // This file is automatically generated. DO NOT EDIT
 
+export function ApplyRetention(arg1) {
+  return window['go']['main']['App']['ApplyRetention'](arg1);
+}
+
 export function AutoImportFile(arg1) {
   return window['go']['main']['App']['AutoImportFile'](arg1);
 }
 
+export function AutoImportFileWithOptions(arg1, arg2) {
+  return window['go']['main']['App']['AutoImportFileWithOptions'](arg1, arg2);
+}
+
+export function ClearQueryHistory() {
+  return window['go']['main']['App']['ClearQueryHistory']();
+}
+
+export function CompactDatabase() {
+  return window['go']['main']['App']['CompactDatabase']();
+}
+
+export function Correlate(arg1, arg2) {
+  return window['go']['main']['App']['Correlate'](arg1, arg2);
+}
+
+export function CreateWorkspace(arg1, arg2, arg3) {
+  return window['go']['main']['App']['CreateWorkspace'](arg1, arg2, arg3);
+}
+
+export function CurrentWorkspace() {
+  return window['go']['main']['App']['CurrentWorkspace']();
+}
+
+export function DeleteByQuery(arg1, arg2) {
+  return window['go']['main']['App']['DeleteByQuery'](arg1, arg2);
+}
+
+export function DeleteSavedQuery(arg1) {
+  return window['go']['main']['App']['DeleteSavedQuery'](arg1);
+}
+
+export function DeleteWorkspace(arg1, arg2) {
+  return window['go']['main']['App']['DeleteWorkspace'](arg1, arg2);
+}
+
+export function DetectAnomalies(arg1) {
+  return window['go']['main']['App']['DetectAnomalies'](arg1);
+}
+
+export function DropPartitionsBefore(arg1, arg2) {
+  return window['go']['main']['App']['DropPartitionsBefore'](arg1, arg2);
+}
+
 export function ExplainQuery(arg1) {
   return window['go']['main']['App']['ExplainQuery'](arg1);
 }
@@ -14,10 +62,54 @@ export function ExportReport(arg1, arg2) {
   return window['go']['main']['App']['ExportReport'](arg1, arg2);
 }
 
+export function ExportSavedQueries() {
+  return window['go']['main']['App']['ExportSavedQueries']();
+}
+
+export function Facets(arg1, arg2, arg3) {
+  return window['go']['main']['App']['Facets'](arg1, arg2, arg3);
+}
+
+export function FormatQuery(arg1) {
+  return window['go']['main']['App']['FormatQuery'](arg1);
+}
+
+export function GetCompression() {
+  return window['go']['main']['App']['GetCompression']();
+}
+
+export function GetContext(arg1, arg2, arg3) {
+  return window['go']['main']['App']['GetContext'](arg1, arg2, arg3);
+}
+
+export function GetCorrelationFields() {
+  return window['go']['main']['App']['GetCorrelationFields']();
+}
+
+export function GetFieldCatalog(arg1) {
+  return window['go']['main']['App']['GetFieldCatalog'](arg1);
+}
+
+export function GetPartitioning() {
+  return window['go']['main']['App']['GetPartitioning']();
+}
+
+export function GetPatterns(arg1, arg2) {
+  return window['go']['main']['App']['GetPatterns'](arg1, arg2);
+}
+
+export function GetQueryHistory(arg1) {
+  return window['go']['main']['App']['GetQueryHistory'](arg1);
+}
+
 export function GetRecord(arg1) {
   return window['go']['main']['App']['GetRecord'](arg1);
 }
 
+export function GetRetentionPolicies() {
+  return window['go']['main']['App']['GetRetentionPolicies']();
+}
+
 export function GetStats() {
   return window['go']['main']['App']['GetStats']();
 }
@@ -30,14 +122,90 @@ export function GetTimeline(arg1) {
   return window['go']['main']['App']['GetTimeline'](arg1);
 }
 
+export function GetTimelineSeries(arg1) {
+  return window['go']['main']['App']['GetTimelineSeries'](arg1);
+}
+
+export function GetTrace(arg1) {
+  return window['go']['main']['App']['GetTrace'](arg1);
+}
+
 export function ImportFile(arg1, arg2) {
   return window['go']['main']['App']['ImportFile'](arg1, arg2);
 }
 
+export function ImportFileWithOptions(arg1, arg2, arg3) {
+  return window['go']['main']['App']['ImportFileWithOptions'](arg1, arg2, arg3);
+}
+
+export function ImportSavedQueries() {
+  return window['go']['main']['App']['ImportSavedQueries']();
+}
+
+export function ListPartitions() {
+  return window['go']['main']['App']['ListPartitions']();
+}
+
+export function ListSavedQueries(arg1) {
+  return window['go']['main']['App']['ListSavedQueries'](arg1);
+}
+
+export function ListTraces(arg1, arg2) {
+  return window['go']['main']['App']['ListTraces'](arg1, arg2);
+}
+
+export function ListWorkspaces() {
+  return window['go']['main']['App']['ListWorkspaces']();
+}
+
+export function OpenWorkspace(arg1) {
+  return window['go']['main']['App']['OpenWorkspace'](arg1);
+}
+
+export function ParseQuery(arg1) {
+  return window['go']['main']['App']['ParseQuery'](arg1);
+}
+
 export function Query(arg1) {
   return window['go']['main']['App']['Query'](arg1);
 }
 
+export function QueryPattern(arg1, arg2) {
+  return window['go']['main']['App']['QueryPattern'](arg1, arg2);
+}
+
+export function RenameSavedQuery(arg1, arg2) {
+  return window['go']['main']['App']['RenameSavedQuery'](arg1, arg2);
+}
+
+export function RenameWorkspace(arg1, arg2) {
+  return window['go']['main']['App']['RenameWorkspace'](arg1, arg2);
+}
+
+export function SaveQuery(arg1) {
+  return window['go']['main']['App']['SaveQuery'](arg1);
+}
+
 export function SelectLogFile() {
   return window['go']['main']['App']['SelectLogFile']();
 }
+
+export function SelectWorkspaceDirectory() {
+  return window['go']['main']['App']['SelectWorkspaceDirectory']();
+}
+
+export function SetCompression(arg1) {
+  return window['go']['main']['App']['SetCompression'](arg1);
+}
+
+export function SetPartitioning(arg1) {
+  return window['go']['main']['App']['SetPartitioning'](arg1);
+}
+
+export function SetRetentionPolicies(arg1) {
+  return window['go']['main']['App']['SetRetentionPolicies'](arg1);
+}
+
+export function TopAnomalies(arg1) {
+  return window['go']['main']['App']['TopAnomalies'](arg1);
+}
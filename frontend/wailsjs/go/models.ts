@@ -4,6 +4,7 @@ export namespace app {
 	    totalRecords: number;
 	    levelCounts: {[key: string]: number};
 	    lastUpdated: number;
+	    compression?: domain.CompressionStats;
 	
 	    static createFrom(source: any = {}) {
 	        return new Stats(source);
@@ -14,6 +15,47 @@ export namespace app {
 	        this.totalRecords = source["totalRecords"];
 	        this.levelCounts = source["levelCounts"];
 	        this.lastUpdated = source["lastUpdated"];
+	        this.compression = this.convertValues(source["compression"], domain.CompressionStats);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class Workspace {
+	    name: string;
+	    path: string;
+	    description?: string;
+	    createdAt: number;
+	    lastOpened?: number;
+	    sizeBytes: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Workspace(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.path = source["path"];
+	        this.description = source["description"];
+	        this.createdAt = source["createdAt"];
+	        this.lastOpened = source["lastOpened"];
+	        this.sizeBytes = source["sizeBytes"];
 	    }
 	}
 
@@ -25,6 +67,9 @@ export namespace domain {
 	    function: string;
 	    field?: string;
 	    alias?: string;
+	    percentile?: number;
+	    interval?: number;
+	    logBase?: number;
 	
 	    static createFrom(source: any = {}) {
 	        return new Aggregation(source);
@@ -35,110 +80,169 @@ export namespace domain {
 	        this.function = source["function"];
 	        this.field = source["field"];
 	        this.alias = source["alias"];
+	        this.percentile = source["percentile"];
+	        this.interval = source["interval"];
+	        this.logBase = source["logBase"];
 	    }
 	}
-	export class FilterCondition {
-	    type: string;
-	    field: string;
-	    value: any;
-	    operator?: string;
+	export class Anomaly {
+	    field?: string;
+	    series: string;
+	    metric: string;
+	    start: number;
+	    end: number;
+	    peak: number;
+	    observed: number;
+	    expected: number;
+	    score: number;
+	    spike: boolean;
+	    severity: string;
 	
 	    static createFrom(source: any = {}) {
-	        return new FilterCondition(source);
+	        return new Anomaly(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.type = source["type"];
 	        this.field = source["field"];
-	        this.value = source["value"];
-	        this.operator = source["operator"];
+	        this.series = source["series"];
+	        this.metric = source["metric"];
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.peak = source["peak"];
+	        this.observed = source["observed"];
+	        this.expected = source["expected"];
+	        this.score = source["score"];
+	        this.spike = source["spike"];
+	        this.severity = source["severity"];
 	    }
 	}
-	export class ImportResult {
-	    totalRecords: number;
-	    processed: number;
-	    errors?: string[];
-	    duration: number;
+	export class TimelineMetric {
+	    function: string;
+	    field?: string;
+	    alias?: string;
+	    percentile?: number;
+	    interval?: number;
+	    logBase?: number;
+	    rate?: boolean;
 	
 	    static createFrom(source: any = {}) {
-	        return new ImportResult(source);
+	        return new TimelineMetric(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.totalRecords = source["totalRecords"];
-	        this.processed = source["processed"];
-	        this.errors = source["errors"];
-	        this.duration = source["duration"];
+	        this.function = source["function"];
+	        this.field = source["field"];
+	        this.alias = source["alias"];
+	        this.percentile = source["percentile"];
+	        this.interval = source["interval"];
+	        this.logBase = source["logBase"];
+	        this.rate = source["rate"];
 	    }
 	}
-	export class LogRecord {
-	    id: string;
-	    timestamp: number;
-	    level: string;
-	    message: string;
-	    service?: string;
-	    fields?: {[key: string]: any};
-	    raw: string;
+	export class FilterCondition {
+	    type: string;
+	    field: string;
+	    value: any;
+	    operator?: string;
+	    children?: FilterCondition[];
 	
 	    static createFrom(source: any = {}) {
-	        return new LogRecord(source);
+	        return new FilterCondition(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.id = source["id"];
-	        this.timestamp = source["timestamp"];
-	        this.level = source["level"];
-	        this.message = source["message"];
-	        this.service = source["service"];
-	        this.fields = source["fields"];
-	        this.raw = source["raw"];
+	        this.type = source["type"];
+	        this.field = source["field"];
+	        this.value = source["value"];
+	        this.operator = source["operator"];
+	        this.children = this.convertValues(source["children"], FilterCondition);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class ParserConfig {
-	    type: string;
-	    pattern?: string;
-	    fields?: {[key: string]: string};
-	    timeFormat?: string;
-	    idPrefix?: string;
+	export class TimelineRequest {
+	    filters: FilterCondition[];
+	    bucketMs: number;
+	    interval?: string;
+	    points?: number;
+	    timezone?: string;
+	    splitBy?: string;
+	    topK?: number;
+	    from?: number;
+	    to?: number;
+	    metrics?: TimelineMetric[];
 	
 	    static createFrom(source: any = {}) {
-	        return new ParserConfig(source);
+	        return new TimelineRequest(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.type = source["type"];
-	        this.pattern = source["pattern"];
-	        this.fields = source["fields"];
-	        this.timeFormat = source["timeFormat"];
-	        this.idPrefix = source["idPrefix"];
+	        this.filters = this.convertValues(source["filters"], FilterCondition);
+	        this.bucketMs = source["bucketMs"];
+	        this.interval = source["interval"];
+	        this.points = source["points"];
+	        this.timezone = source["timezone"];
+	        this.splitBy = source["splitBy"];
+	        this.topK = source["topK"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.metrics = this.convertValues(source["metrics"], TimelineMetric);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
-	export class Query {
-	    filters: FilterCondition[];
-	    groupBy?: string[];
-	    aggregations?: Aggregation[];
-	    sortBy?: string;
-	    sortDesc?: boolean;
+	export class AnomalyRequest {
+	    timeline: TimelineRequest;
+	    threshold?: number;
+	    season?: number;
 	    limit?: number;
-	    offset?: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new Query(source);
+	        return new AnomalyRequest(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.filters = this.convertValues(source["filters"], FilterCondition);
-	        this.groupBy = source["groupBy"];
-	        this.aggregations = this.convertValues(source["aggregations"], Aggregation);
-	        this.sortBy = source["sortBy"];
-	        this.sortDesc = source["sortDesc"];
+	        this.timeline = this.convertValues(source["timeline"], TimelineRequest);
+	        this.threshold = source["threshold"];
+	        this.season = source["season"];
 	        this.limit = source["limit"];
-	        this.offset = source["offset"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -159,23 +263,121 @@ export namespace domain {
 		    return a;
 		}
 	}
-	export class QueryResult {
-	    records: LogRecord[];
-	    aggregations?: {[key: string]: any};
-	    total: number;
+	export class CompactResult {
+	    sizeBefore: number;
+	    sizeAfter: number;
+	    reclaimedBytes: number;
 	    took: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new QueryResult(source);
+	        return new CompactResult(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.records = this.convertValues(source["records"], LogRecord);
-	        this.aggregations = source["aggregations"];
-	        this.total = source["total"];
+	        this.sizeBefore = source["sizeBefore"];
+	        this.sizeAfter = source["sizeAfter"];
+	        this.reclaimedBytes = source["reclaimedBytes"];
 	        this.took = source["took"];
 	    }
+	}
+	export class CompressionStats {
+	    mode: string;
+	    storedBytes: number;
+	    originalBytes: number;
+	    ratio: number;
+	    compressedRows: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CompressionStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.mode = source["mode"];
+	        this.storedBytes = source["storedBytes"];
+	        this.originalBytes = source["originalBytes"];
+	        this.ratio = source["ratio"];
+	        this.compressedRows = source["compressedRows"];
+	    }
+	}
+	export class ServiceSpan {
+	    service: string;
+	    firstSeen: number;
+	    lastSeen: number;
+	    durationMs: number;
+	    records: number;
+	    errors: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ServiceSpan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.service = source["service"];
+	        this.firstSeen = source["firstSeen"];
+	        this.lastSeen = source["lastSeen"];
+	        this.durationMs = source["durationMs"];
+	        this.records = source["records"];
+	        this.errors = source["errors"];
+	    }
+	}
+	export class LogRecord {
+	    id: string;
+	    timestamp: number;
+	    level: string;
+	    message: string;
+	    service?: string;
+	    fields?: {[key: string]: any};
+	    raw: string;
+	    source?: string;
+	    line?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new LogRecord(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.timestamp = source["timestamp"];
+	        this.level = source["level"];
+	        this.message = source["message"];
+	        this.service = source["service"];
+	        this.fields = source["fields"];
+	        this.raw = source["raw"];
+	        this.source = source["source"];
+	        this.line = source["line"];
+	    }
+	}
+	export class Correlation {
+	    field: string;
+	    value: any;
+	    start: number;
+	    end: number;
+	    durationMs: number;
+	    errors: number;
+	    records: LogRecord[];
+	    services: ServiceSpan[];
+	    truncated?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Correlation(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.field = source["field"];
+	        this.value = source["value"];
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.durationMs = source["durationMs"];
+	        this.errors = source["errors"];
+	        this.records = this.convertValues(source["records"], LogRecord);
+	        this.services = this.convertValues(source["services"], ServiceSpan);
+	        this.truncated = source["truncated"];
+	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
 		    if (!a) {
@@ -195,32 +397,54 @@ export namespace domain {
 		    return a;
 		}
 	}
-	export class TimelinePoint {
-	    bucketStart: number;
+	export class DeleteResult {
+	    matched: number;
+	    deleted: number;
+	    dryRun: boolean;
+	    took: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new DeleteResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.matched = source["matched"];
+	        this.deleted = source["deleted"];
+	        this.dryRun = source["dryRun"];
+	        this.took = source["took"];
+	    }
+	}
+	export class FacetValue {
+	    value: any;
 	    count: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new TimelinePoint(source);
+	        return new FacetValue(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.bucketStart = source["bucketStart"];
+	        this.value = source["value"];
 	        this.count = source["count"];
 	    }
 	}
-	export class TimelineRequest {
-	    filters: FilterCondition[];
-	    bucketMs: number;
+	export class Facet {
+	    field: string;
+	    values: FacetValue[];
+	    other: number;
+	    missing: number;
 	
 	    static createFrom(source: any = {}) {
-	        return new TimelineRequest(source);
+	        return new Facet(source);
 	    }
 	
 	    constructor(source: any = {}) {
 	        if ('string' === typeof source) source = JSON.parse(source);
-	        this.filters = this.convertValues(source["filters"], FilterCondition);
-	        this.bucketMs = source["bucketMs"];
+	        this.field = source["field"];
+	        this.values = this.convertValues(source["values"], FacetValue);
+	        this.other = source["other"];
+	        this.missing = source["missing"];
 	    }
 	
 		convertValues(a: any, classs: any, asMap: boolean = false): any {
@@ -241,6 +465,728 @@ export namespace domain {
 		    return a;
 		}
 	}
+	
+	export class FieldInfo {
+	    path: string;
+	    types: {[key: string]: number};
+	    samples?: string[];
+	    seen: number;
+	    nulls: number;
+	    nullRatio: number;
+	    distinct: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new FieldInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.path = source["path"];
+	        this.types = source["types"];
+	        this.samples = source["samples"];
+	        this.seen = source["seen"];
+	        this.nulls = source["nulls"];
+	        this.nullRatio = source["nullRatio"];
+	        this.distinct = source["distinct"];
+	    }
+	}
+	
+	export class GroupBucket {
+	    key: {[key: string]: any};
+	    values: {[key: string]: any};
+	
+	    static createFrom(source: any = {}) {
+	        return new GroupBucket(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.key = source["key"];
+	        this.values = source["values"];
+	    }
+	}
+	export class ImportOptions {
+	    idMode?: string;
+	    dedup?: string;
+	    bulk?: boolean;
+	    deferIndexes?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportOptions(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.idMode = source["idMode"];
+	        this.dedup = source["dedup"];
+	        this.bulk = source["bulk"];
+	        this.deferIndexes = source["deferIndexes"];
+	    }
+	}
+	export class RecordError {
+	    id: string;
+	    source?: string;
+	    line?: number;
+	    reason: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new RecordError(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.source = source["source"];
+	        this.line = source["line"];
+	        this.reason = source["reason"];
+	    }
+	}
+	export class ImportResult {
+	    totalRecords: number;
+	    processed: number;
+	    duplicates: number;
+	    failed: number;
+	    errors?: string[];
+	    recordErrors?: RecordError[];
+	    partial?: boolean;
+	    duration: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ImportResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.totalRecords = source["totalRecords"];
+	        this.processed = source["processed"];
+	        this.duplicates = source["duplicates"];
+	        this.failed = source["failed"];
+	        this.errors = source["errors"];
+	        this.recordErrors = this.convertValues(source["recordErrors"], RecordError);
+	        this.partial = source["partial"];
+	        this.duration = source["duration"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class LogGroup {
+	    id: string;
+	    pattern: string;
+	    count: number;
+	    firstSeen: number;
+	    lastSeen: number;
+	    sample: LogRecord;
+	    level: string;
+	    service?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new LogGroup(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.pattern = source["pattern"];
+	        this.count = source["count"];
+	        this.firstSeen = source["firstSeen"];
+	        this.lastSeen = source["lastSeen"];
+	        this.sample = this.convertValues(source["sample"], LogRecord);
+	        this.level = source["level"];
+	        this.service = source["service"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class ParserConfig {
+	    type: string;
+	    pattern?: string;
+	    fields?: {[key: string]: string};
+	    timeFormat?: string;
+	    idPrefix?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new ParserConfig(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.type = source["type"];
+	        this.pattern = source["pattern"];
+	        this.fields = source["fields"];
+	        this.timeFormat = source["timeFormat"];
+	        this.idPrefix = source["idPrefix"];
+	    }
+	}
+	export class PartitionInfo {
+	    name: string;
+	    start: number;
+	    end: number;
+	    rows: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new PartitionInfo(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.rows = source["rows"];
+	    }
+	}
+	export class Query {
+	    filters: FilterCondition[];
+	    groupBy?: string[];
+	    bucketMs?: number;
+	    groupSort?: string;
+	    groupSortDesc?: boolean;
+	    groupLimit?: number;
+	    aggregations?: Aggregation[];
+	    sortBy?: string;
+	    sortDesc?: boolean;
+	    limit?: number;
+	    offset?: number;
+	    cursor?: string;
+	    countMode?: string;
+	    text?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Query(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.filters = this.convertValues(source["filters"], FilterCondition);
+	        this.groupBy = source["groupBy"];
+	        this.bucketMs = source["bucketMs"];
+	        this.groupSort = source["groupSort"];
+	        this.groupSortDesc = source["groupSortDesc"];
+	        this.groupLimit = source["groupLimit"];
+	        this.aggregations = this.convertValues(source["aggregations"], Aggregation);
+	        this.sortBy = source["sortBy"];
+	        this.sortDesc = source["sortDesc"];
+	        this.limit = source["limit"];
+	        this.offset = source["offset"];
+	        this.cursor = source["cursor"];
+	        this.countMode = source["countMode"];
+	        this.text = source["text"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class QueryHistoryEntry {
+	    id: number;
+	    query: Query;
+	    executedAt: number;
+	    durationMs: number;
+	    resultCount: number;
+	    error?: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new QueryHistoryEntry(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.query = this.convertValues(source["query"], Query);
+	        this.executedAt = source["executedAt"];
+	        this.durationMs = source["durationMs"];
+	        this.resultCount = source["resultCount"];
+	        this.error = source["error"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class QueryResult {
+	    records: LogRecord[];
+	    aggregations?: {[key: string]: any};
+	    groups?: GroupBucket[];
+	    total: number;
+	    totalUnknown?: boolean;
+	    totalLowerBound?: boolean;
+	    nextCursor?: string;
+	    prevCursor?: string;
+	    took: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new QueryResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.records = this.convertValues(source["records"], LogRecord);
+	        this.aggregations = source["aggregations"];
+	        this.groups = this.convertValues(source["groups"], GroupBucket);
+	        this.total = source["total"];
+	        this.totalUnknown = source["totalUnknown"];
+	        this.totalLowerBound = source["totalLowerBound"];
+	        this.nextCursor = source["nextCursor"];
+	        this.prevCursor = source["prevCursor"];
+	        this.took = source["took"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class RecordContext {
+	    record: LogRecord;
+	    before: LogRecord[];
+	    after: LogRecord[];
+	    moreBefore: boolean;
+	    moreAfter: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new RecordContext(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.record = this.convertValues(source["record"], LogRecord);
+	        this.before = this.convertValues(source["before"], LogRecord);
+	        this.after = this.convertValues(source["after"], LogRecord);
+	        this.moreBefore = source["moreBefore"];
+	        this.moreAfter = source["moreAfter"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class RetentionPolicy {
+	    source: string;
+	    maxAgeMs?: number;
+	    maxRows?: number;
+	    maxBytes?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RetentionPolicy(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.source = source["source"];
+	        this.maxAgeMs = source["maxAgeMs"];
+	        this.maxRows = source["maxRows"];
+	        this.maxBytes = source["maxBytes"];
+	    }
+	}
+	export class RetentionOutcome {
+	    policy: RetentionPolicy;
+	    matched: number;
+	    deleted: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RetentionOutcome(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.policy = this.convertValues(source["policy"], RetentionPolicy);
+	        this.matched = source["matched"];
+	        this.deleted = source["deleted"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class RetentionResult {
+	    dryRun: boolean;
+	    outcomes: RetentionOutcome[];
+	    matched: number;
+	    deleted: number;
+	    took: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RetentionResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.dryRun = source["dryRun"];
+	        this.outcomes = this.convertValues(source["outcomes"], RetentionOutcome);
+	        this.matched = source["matched"];
+	        this.deleted = source["deleted"];
+	        this.took = source["took"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class SavedQuery {
+	    id?: number;
+	    name: string;
+	    description?: string;
+	    tags: string[];
+	    query: Query;
+	    createdAt?: number;
+	    updatedAt?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SavedQuery(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.id = source["id"];
+	        this.name = source["name"];
+	        this.description = source["description"];
+	        this.tags = source["tags"];
+	        this.query = this.convertValues(source["query"], Query);
+	        this.createdAt = source["createdAt"];
+	        this.updatedAt = source["updatedAt"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	export class TimelinePoint {
+	    bucketStart: number;
+	    count: number;
+	    value?: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TimelinePoint(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.bucketStart = source["bucketStart"];
+	        this.count = source["count"];
+	        this.value = source["value"];
+	    }
+	}
+	
+	export class TimelineSeries {
+	    name: string;
+	    metric: string;
+	    value?: any;
+	    other?: boolean;
+	    total: number;
+	    points: TimelinePoint[];
+	
+	    static createFrom(source: any = {}) {
+	        return new TimelineSeries(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.metric = source["metric"];
+	        this.value = source["value"];
+	        this.other = source["other"];
+	        this.total = source["total"];
+	        this.points = this.convertValues(source["points"], TimelinePoint);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class TimelineResult {
+	    bucketMs: number;
+	    interval: string;
+	    timezone: string;
+	    from: number;
+	    to: number;
+	    series: TimelineSeries[];
+	
+	    static createFrom(source: any = {}) {
+	        return new TimelineResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.bucketMs = source["bucketMs"];
+	        this.interval = source["interval"];
+	        this.timezone = source["timezone"];
+	        this.from = source["from"];
+	        this.to = source["to"];
+	        this.series = this.convertValues(source["series"], TimelineSeries);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class TraceSpan {
+	    spanId: string;
+	    parentId?: string;
+	    name: string;
+	    service?: string;
+	    depth: number;
+	    start: number;
+	    offsetMs: number;
+	    durationMs: number;
+	    selfTimeMs: number;
+	    critical?: boolean;
+	    slow?: boolean;
+	    error?: boolean;
+	    orphan?: boolean;
+	    recordIds: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new TraceSpan(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.spanId = source["spanId"];
+	        this.parentId = source["parentId"];
+	        this.name = source["name"];
+	        this.service = source["service"];
+	        this.depth = source["depth"];
+	        this.start = source["start"];
+	        this.offsetMs = source["offsetMs"];
+	        this.durationMs = source["durationMs"];
+	        this.selfTimeMs = source["selfTimeMs"];
+	        this.critical = source["critical"];
+	        this.slow = source["slow"];
+	        this.error = source["error"];
+	        this.orphan = source["orphan"];
+	        this.recordIds = source["recordIds"];
+	    }
+	}
+	export class Trace {
+	    traceId: string;
+	    start: number;
+	    end: number;
+	    durationMs: number;
+	    spans: TraceSpan[];
+	    errors: number;
+	    orphans: number;
+	    truncated?: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Trace(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.traceId = source["traceId"];
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.durationMs = source["durationMs"];
+	        this.spans = this.convertValues(source["spans"], TraceSpan);
+	        this.errors = source["errors"];
+	        this.orphans = source["orphans"];
+	        this.truncated = source["truncated"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	export class TraceSummary {
+	    traceId: string;
+	    start: number;
+	    end: number;
+	    records: number;
+	    spans: number;
+	    errorRatio: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new TraceSummary(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.traceId = source["traceId"];
+	        this.start = source["start"];
+	        this.end = source["end"];
+	        this.records = source["records"];
+	        this.spans = source["spans"];
+	        this.errorRatio = source["errorRatio"];
+	    }
+	}
 
 }
 
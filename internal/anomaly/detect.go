@@ -0,0 +1,189 @@
+// Package anomaly flags timeline buckets that deviate from the expected
+// volume. Deviations are scored with a robust z-score, using the median and
+// the median absolute deviation so that the spikes being looked for do not
+// inflate the baseline.
+package anomaly
+
+import (
+	"math"
+	"sort"
+
+	"LogLens/internal/domain"
+)
+
+const (
+	DefaultThreshold = 3.5
+	// MinPoints is the shortest series with a meaningful baseline.
+	MinPoints = 8
+	// madScale makes the MAD a consistent estimator of the standard
+	// deviation for normally distributed data.
+	madScale = 1.4826
+	// meanADScale does the same for the mean absolute deviation, the
+	// fallback when more than half the values equal the median.
+	meanADScale = 1.2533
+)
+
+type Options struct {
+	Threshold float64
+	// Season is the number of buckets per period; 0 disables seasonality.
+	Season int
+}
+
+// Detect returns the anomalies in series, whose last bucket ends at end.
+// Points are scored by their metric value, or their count for plain count
+// series; points without a value are skipped.
+func Detect(series domain.TimelineSeries, end int64, opts Options) []domain.Anomaly {
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultThreshold
+	}
+	values := make([]float64, len(series.Points))
+	valid := make([]bool, len(series.Points))
+	n := 0
+	for i, p := range series.Points {
+		switch {
+		case p.Value != nil:
+			values[i], valid[i] = *p.Value, true
+		case series.Metric == "" || series.Metric == "count":
+			values[i], valid[i] = float64(p.Count), true
+		}
+		if valid[i] {
+			n++
+		}
+	}
+	if n < MinPoints {
+		return nil
+	}
+
+	expected := baseline(values, valid, opts.Season)
+	residuals := make([]float64, 0, n)
+	for i := range values {
+		if valid[i] {
+			residuals = append(residuals, values[i]-expected[i])
+		}
+	}
+	scale := spread(residuals)
+	if scale == 0 {
+		return nil
+	}
+
+	var anomalies []domain.Anomaly
+	var current *domain.Anomaly
+	for i, p := range series.Points {
+		score := 0.0
+		if valid[i] {
+			score = (values[i] - expected[i]) / scale
+		}
+		if math.Abs(score) < opts.Threshold {
+			current = nil
+			continue
+		}
+		spike := score > 0
+		if current == nil || current.Spike != spike {
+			anomalies = append(anomalies, domain.Anomaly{
+				Series: series.Name,
+				Metric: series.Metric,
+				Start:  p.BucketStart,
+				Spike:  spike,
+			})
+			current = &anomalies[len(anomalies)-1]
+		}
+		current.End = end
+		if i+1 < len(series.Points) {
+			current.End = series.Points[i+1].BucketStart
+		}
+		if math.Abs(score) > math.Abs(current.Score) {
+			current.Peak = p.BucketStart
+			current.Observed = values[i]
+			current.Expected = expected[i]
+			current.Score = score
+		}
+	}
+	for i := range anomalies {
+		anomalies[i].Severity = Severity(anomalies[i].Score, opts.Threshold)
+	}
+	return anomalies
+}
+
+// Severity grades a score: high from four times the threshold, medium from
+// twice.
+func Severity(score, threshold float64) domain.AnomalySeverity {
+	switch s := math.Abs(score); {
+	case s >= 4*threshold:
+		return domain.SeverityHigh
+	case s >= 2*threshold:
+		return domain.SeverityMedium
+	default:
+		return domain.SeverityLow
+	}
+}
+
+// baseline returns the expected value of each bucket: the median of the
+// same phase in the other periods when seasonal, or else of the whole
+// series. Seasonality needs at least two full periods.
+func baseline(values []float64, valid []bool, season int) []float64 {
+	expected := make([]float64, len(values))
+	if season <= 1 || len(values) < 2*season {
+		var all []float64
+		for i, v := range values {
+			if valid[i] {
+				all = append(all, v)
+			}
+		}
+		m := median(all)
+		for i := range expected {
+			expected[i] = m
+		}
+		return expected
+	}
+
+	for i := range values {
+		var same []float64
+		for j := i % season; j < len(values); j += season {
+			if j != i && valid[j] {
+				same = append(same, values[j])
+			}
+		}
+		expected[i] = median(same)
+	}
+	return expected
+}
+
+// spread is a robust estimate of the standard deviation of residuals.
+func spread(residuals []float64) float64 {
+	m := median(residuals)
+	deviations := make([]float64, len(residuals))
+	sum := 0.0
+	for i, r := range residuals {
+		deviations[i] = math.Abs(r - m)
+		sum += deviations[i]
+	}
+	if mad := median(deviations); mad > 0 {
+		return madScale * mad
+	}
+	return meanADScale * sum / float64(len(residuals))
+}
+
+func median(values []float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		return sorted[mid]
+	}
+	return (sorted[mid-1] + sorted[mid]) / 2
+}
+
+// Rank sorts anomalies by descending absolute score and keeps the first
+// limit, or all if limit <= 0.
+func Rank(anomalies []domain.Anomaly, limit int) []domain.Anomaly {
+	sort.SliceStable(anomalies, func(i, j int) bool {
+		return math.Abs(anomalies[i].Score) > math.Abs(anomalies[j].Score)
+	})
+	if limit > 0 && len(anomalies) > limit {
+		anomalies = anomalies[:limit]
+	}
+	return anomalies
+}
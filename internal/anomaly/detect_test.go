@@ -0,0 +1,88 @@
+package anomaly
+
+import (
+	"testing"
+
+	"LogLens/internal/domain"
+)
+
+func countSeries(counts ...int64) domain.TimelineSeries {
+	series := domain.TimelineSeries{Name: "count", Metric: "count"}
+	for i, c := range counts {
+		series.Points = append(series.Points, domain.TimelinePoint{BucketStart: int64(i) * 1000, Count: c})
+	}
+	return series
+}
+
+func TestDetect_SpikeWindow(t *testing.T) {
+	series := countSeries(10, 11, 9, 10, 12, 10, 80, 95, 10, 9, 11, 10)
+
+	anomalies := Detect(series, 12000, Options{})
+	if len(anomalies) != 1 {
+		t.Fatalf("expected one anomaly, got %+v", anomalies)
+	}
+	a := anomalies[0]
+	if !a.Spike || a.Start != 6000 || a.End != 8000 || a.Peak != 7000 {
+		t.Errorf("expected a spike over [6000, 8000) peaking at 7000, got %+v", a)
+	}
+	if a.Observed != 95 || a.Expected != 10 || a.Severity != domain.SeverityHigh {
+		t.Errorf("expected 95 against 10 with high severity, got %+v", a)
+	}
+}
+
+func TestDetect_DropAndFlatSeries(t *testing.T) {
+	drop := Detect(countSeries(50, 52, 49, 51, 50, 48, 50, 0, 51, 50), 10000, Options{})
+	if len(drop) != 1 || drop[0].Spike || drop[0].Peak != 7000 {
+		t.Errorf("expected a drop at 7000, got %+v", drop)
+	}
+
+	if flat := Detect(countSeries(5, 5, 5, 5, 5, 5, 5, 5, 5, 5), 10000, Options{}); len(flat) != 0 {
+		t.Errorf("expected no anomalies in a flat series, got %+v", flat)
+	}
+	if short := Detect(countSeries(1, 1, 100), 3000, Options{}); len(short) != 0 {
+		t.Errorf("expected no anomalies in a short series, got %+v", short)
+	}
+}
+
+func TestDetect_SeasonalBaseline(t *testing.T) {
+	// A busy bucket every fourth bucket is normal; the last period's quiet
+	// bucket is not.
+	counts := []int64{100, 10, 10, 10, 102, 11, 9, 10, 98, 10, 11, 10, 101, 10, 60, 10}
+
+	plain := Detect(countSeries(counts...), 16000, Options{})
+	if len(plain) == 0 || plain[0].Peak != 0 {
+		t.Fatalf("expected the busy buckets to stand out without seasonality, got %+v", plain)
+	}
+
+	seasonal := Detect(countSeries(counts...), 16000, Options{Season: 4})
+	if len(seasonal) != 1 || seasonal[0].Peak != 14000 || seasonal[0].Expected != 10 {
+		t.Errorf("expected only the bucket at 14000 with a seasonal baseline, got %+v", seasonal)
+	}
+}
+
+func TestDetect_MetricValues(t *testing.T) {
+	series := domain.TimelineSeries{Name: "avg_latency", Metric: "avg_latency"}
+	for i := 0; i < 10; i++ {
+		p := domain.TimelinePoint{BucketStart: int64(i), Count: 1}
+		v := 20.0 + float64(i%2)
+		if i == 5 {
+			v = 400
+		}
+		if i != 3 {
+			p.Value = &v
+		}
+		series.Points = append(series.Points, p)
+	}
+
+	anomalies := Detect(series, 10, Options{})
+	if len(anomalies) != 1 || anomalies[0].Peak != 5 || anomalies[0].Observed != 400 {
+		t.Errorf("expected the 400 latency at 5, got %+v", anomalies)
+	}
+}
+
+func TestRank(t *testing.T) {
+	ranked := Rank([]domain.Anomaly{{Score: 4}, {Score: -9}, {Score: 6}}, 2)
+	if len(ranked) != 2 || ranked[0].Score != -9 || ranked[1].Score != 6 {
+		t.Errorf("unexpected ranking: %+v", ranked)
+	}
+}
@@ -0,0 +1,169 @@
+package app
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"LogLens/internal/domain"
+	"LogLens/internal/query/lang"
+	"LogLens/internal/storage"
+)
+
+// historyQueueSize bounds the history entries waiting to be written; when
+// the library falls behind further entries are dropped.
+const historyQueueSize = 256
+
+// savedQueryVersion is the version of the saved query export format.
+const savedQueryVersion = 1
+
+// savedQueryLibrary is the JSON document saved queries are shared as.
+type savedQueryLibrary struct {
+	Version int                 `json:"version"`
+	Queries []domain.SavedQuery `json:"queries"`
+}
+
+// QueryLibrary holds the saved queries and query history shared by every
+// workspace. History is written in the background, so recording a query
+// never delays it.
+type QueryLibrary struct {
+	store   *storage.SavedQueryStore
+	history chan domain.QueryHistoryEntry
+	done    chan struct{}
+	mu      sync.RWMutex
+	closed  bool
+}
+
+func NewQueryLibrary(dbPath string) (*QueryLibrary, error) {
+	store, err := storage.NewSavedQueryStore(dbPath)
+	if err != nil {
+		return nil, err
+	}
+	l := &QueryLibrary{
+		store:   store,
+		history: make(chan domain.QueryHistoryEntry, historyQueueSize),
+		done:    make(chan struct{}),
+	}
+	go l.writeHistory()
+	return l, nil
+}
+
+func (l *QueryLibrary) writeHistory() {
+	defer close(l.done)
+	for entry := range l.history {
+		if err := l.store.RecordQuery(context.Background(), entry); err != nil {
+			log.Printf("Failed to record query history: %v", err)
+		}
+	}
+}
+
+// RecordQuery queues query, executed from start with result or err, for the
+// history. Facets, timelines and patterns report no result, so their
+// entries have no result count. Later pages of a result, fetched by cursor
+// or offset, are not recorded.
+func (l *QueryLibrary) RecordQuery(query domain.Query, start time.Time, result *domain.QueryResult, err error) {
+	if query.Cursor != "" || query.Offset > 0 {
+		return
+	}
+	entry := domain.QueryHistoryEntry{
+		Query:      query,
+		ExecutedAt: start.UnixMilli(),
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		entry.Error = err.Error()
+	} else if result != nil && !result.TotalUnknown {
+		entry.ResultCount = result.Total
+	}
+
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		return
+	}
+	select {
+	case l.history <- entry:
+	default:
+		log.Printf("Query history queue full, dropping entry")
+	}
+}
+
+// Close writes the queued history entries and closes the library.
+func (l *QueryLibrary) Close() error {
+	l.mu.Lock()
+	if l.closed {
+		l.mu.Unlock()
+		return nil
+	}
+	l.closed = true
+	close(l.history)
+	l.mu.Unlock()
+
+	<-l.done
+	return l.store.Close()
+}
+
+func (l *QueryLibrary) GetQueryHistory(ctx context.Context, limit int) ([]domain.QueryHistoryEntry, error) {
+	return l.store.ListQueryHistory(ctx, limit)
+}
+
+func (l *QueryLibrary) ClearQueryHistory(ctx context.Context) error {
+	return l.store.ClearQueryHistory(ctx)
+}
+
+// SaveQuery stores q under its name, overwriting a saved query of the same
+// name.
+func (l *QueryLibrary) SaveQuery(ctx context.Context, q domain.SavedQuery) (*domain.SavedQuery, error) {
+	return l.store.SaveQuery(ctx, q)
+}
+
+// ListSavedQueries returns the saved queries whose name, description or tags
+// contain search, or all of them if it is empty.
+func (l *QueryLibrary) ListSavedQueries(ctx context.Context, search string) ([]domain.SavedQuery, error) {
+	return l.store.ListSavedQueries(ctx, search)
+}
+
+func (l *QueryLibrary) RenameSavedQuery(ctx context.Context, id int64, name string) error {
+	return l.store.RenameSavedQuery(ctx, id, name)
+}
+
+func (l *QueryLibrary) DeleteSavedQuery(ctx context.Context, id int64) error {
+	return l.store.DeleteSavedQuery(ctx, id)
+}
+
+// ExportSavedQueries encodes every saved query as a JSON library. IDs and
+// timestamps are local to a library and left out.
+func (l *QueryLibrary) ExportSavedQueries(ctx context.Context) ([]byte, error) {
+	queries, err := l.store.ListSavedQueries(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range queries {
+		queries[i].ID, queries[i].CreatedAt, queries[i].UpdatedAt = 0, 0, 0
+	}
+	return json.MarshalIndent(savedQueryLibrary{Version: savedQueryVersion, Queries: queries}, "", "  ")
+}
+
+// ImportSavedQueries saves every query of a JSON library, overwriting saved
+// queries of the same name, and returns how many were imported.
+func (l *QueryLibrary) ImportSavedQueries(ctx context.Context, data []byte) (int, error) {
+	var library savedQueryLibrary
+	if err := json.Unmarshal(data, &library); err != nil {
+		return 0, fmt.Errorf("invalid saved query library: %w", err)
+	}
+	if library.Version == 0 || library.Version > savedQueryVersion {
+		return 0, fmt.Errorf("unsupported saved query library version %d", library.Version)
+	}
+	for _, q := range library.Queries {
+		if q.Query.Text == "" {
+			continue
+		}
+		if _, err := lang.Parse(q.Query.Text); err != nil {
+			return 0, fmt.Errorf("invalid saved query %q: %w", q.Name, err)
+		}
+	}
+	return l.store.ImportSavedQueries(ctx, library.Queries)
+}
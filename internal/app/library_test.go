@@ -0,0 +1,102 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"LogLens/internal/domain"
+)
+
+func newTestLibrary(t *testing.T) *QueryLibrary {
+	t.Helper()
+	library, err := NewQueryLibrary(filepath.Join(t.TempDir(), "library.db"))
+	if err != nil {
+		t.Fatalf("failed to open query library: %v", err)
+	}
+	t.Cleanup(func() { library.Close() })
+	return library
+}
+
+func TestQueryLibrary_RecordsFirstPagesOnly(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "library.db")
+	library, err := NewQueryLibrary(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open query library: %v", err)
+	}
+	start := time.Now()
+
+	library.RecordQuery(domain.Query{Text: "level:ERROR"}, start, &domain.QueryResult{Total: 1}, nil)
+	library.RecordQuery(domain.Query{Text: "level:ERROR", Cursor: "abc"}, start, &domain.QueryResult{Total: 1}, nil)
+	library.RecordQuery(domain.Query{Text: "level:ERROR", Offset: 50}, start, &domain.QueryResult{Total: 1}, nil)
+	library.RecordQuery(domain.Query{Text: "level:ERROR ("}, start, nil, errors.New("parse error"))
+
+	// Close flushes the queued entries; recording afterwards is a no-op.
+	if err := library.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	library.RecordQuery(domain.Query{Text: "late"}, start, nil, nil)
+	if err := library.Close(); err != nil {
+		t.Fatalf("second Close failed: %v", err)
+	}
+
+	library, err = NewQueryLibrary(dbPath)
+	if err != nil {
+		t.Fatalf("failed to reopen query library: %v", err)
+	}
+	defer library.Close()
+
+	history, err := library.GetQueryHistory(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("GetQueryHistory failed: %v", err)
+	}
+	if len(history) != 2 {
+		t.Fatalf("expected 2 history entries, got %+v", history)
+	}
+	if history[0].Error == "" || history[1].Error != "" || history[1].ResultCount != 1 || history[1].Query.Text != "level:ERROR" {
+		t.Errorf("unexpected history: %+v", history)
+	}
+}
+
+func TestSavedQueries_ExportImport(t *testing.T) {
+	library := newTestLibrary(t)
+	ctx := context.Background()
+
+	if _, err := library.SaveQuery(ctx, domain.SavedQuery{Name: "errors", Description: "All errors", Tags: []string{"triage"}, Query: domain.Query{Text: "level:ERROR", SortDesc: true}}); err != nil {
+		t.Fatalf("SaveQuery failed: %v", err)
+	}
+	data, err := library.ExportSavedQueries(ctx)
+	if err != nil {
+		t.Fatalf("ExportSavedQueries failed: %v", err)
+	}
+	if strings.Contains(string(data), `"id"`) || !strings.Contains(string(data), `"version": 1`) {
+		t.Errorf("expected a versioned export without local IDs, got %s", data)
+	}
+
+	other := newTestLibrary(t)
+	n, err := other.ImportSavedQueries(ctx, data)
+	if err != nil || n != 1 {
+		t.Fatalf("expected 1 imported, got %d: %v", n, err)
+	}
+	queries, err := other.ListSavedQueries(ctx, "triage")
+	if err != nil {
+		t.Fatalf("ListSavedQueries failed: %v", err)
+	}
+	if len(queries) != 1 || queries[0].Description != "All errors" || !queries[0].Query.SortDesc {
+		t.Errorf("unexpected imported queries: %+v", queries)
+	}
+
+	bad := []string{
+		`not json`,
+		`{"version": 99, "queries": []}`,
+		`{"version": 1, "queries": [{"name": "broken", "query": {"text": "level:ERROR ("}}]}`,
+	}
+	for _, b := range bad {
+		if _, err := other.ImportSavedQueries(ctx, []byte(b)); err == nil {
+			t.Errorf("expected an error importing %s", b)
+		}
+	}
+}
@@ -5,14 +5,21 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"sync"
 	"time"
 
+	"LogLens/internal/anomaly"
+	"LogLens/internal/correlation"
 	"LogLens/internal/domain"
 	"LogLens/internal/parser"
+	"LogLens/internal/patterns"
 	"LogLens/internal/query"
+	"LogLens/internal/query/lang"
 	"LogLens/internal/storage"
+	"LogLens/internal/trace"
 )
 
 type LogLens struct {
@@ -20,12 +27,18 @@ type LogLens struct {
 	queryEngine  domain.QueryEngine
 	filterEngine domain.FilterEngine
 	parserFactory *parser.ParserFactory
+	// cancel stops the background work tracked by background.
+	cancel        context.CancelFunc
+	background    sync.WaitGroup
+	closeOnce     sync.Once
+	closeErr      error
 }
 
 
 
 type Config struct {
-	DatabasePath string `json:"databasePath"`
+	DatabasePath      string        `json:"databasePath"`
+	RetentionInterval time.Duration `json:"retentionInterval,omitempty"`
 }
 
 func NewLogLens(config Config) (*LogLens, error) {
@@ -44,15 +57,72 @@ func NewLogLens(config Config) (*LogLens, error) {
 	
 	parserFactory := parser.NewParserFactory()
 	
-	return &LogLens{
+	ctx, cancel := context.WithCancel(context.Background())
+	ll := &LogLens{
 		storage:       storage,
 		queryEngine:   queryEngine,
 		filterEngine:  filterEngine,
 		parserFactory: parserFactory,
-	}, nil
+		cancel:        cancel,
+	}
+
+	ll.background.Add(1)
+	go func() {
+		defer ll.background.Done()
+		ll.retentionLoop(ctx, config.RetentionInterval)
+	}()
+
+	return ll, nil
+}
+
+type retentionProvider interface {
+	GetRetentionPolicies(context.Context) ([]domain.RetentionPolicy, error)
+	SetRetentionPolicies(context.Context, []domain.RetentionPolicy) error
+	ApplyRetention(context.Context, bool) (*domain.RetentionResult, error)
+}
+
+// retentionLoop enforces retention once on startup and then every interval
+// until ctx is cancelled. A zero interval means startup only.
+func (ll *LogLens) retentionLoop(ctx context.Context, interval time.Duration) {
+	provider, ok := ll.storage.(retentionProvider)
+	if !ok {
+		return
+	}
+
+	apply := func() {
+		if _, err := provider.ApplyRetention(ctx, false); err != nil && ctx.Err() == nil {
+			log.Printf("Retention failed: %v", err)
+		}
+	}
+
+	apply()
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			apply()
+		}
+	}
 }
 
 func (ll *LogLens) ImportFile(ctx context.Context, filePath string, parserConfig domain.ParserConfig, reporter domain.ProgressReporter) (*domain.ImportResult, error) {
+	return ll.ImportFileWithOptions(ctx, filePath, parserConfig, domain.ImportOptions{}, reporter)
+}
+
+func (ll *LogLens) ImportFileWithOptions(ctx context.Context, filePath string, parserConfig domain.ParserConfig, opts domain.ImportOptions, reporter domain.ProgressReporter) (*domain.ImportResult, error) {
+	switch opts.IDMode {
+	case "", domain.IDModeSequential, domain.IDModeContent:
+	default:
+		return nil, fmt.Errorf("unsupported ID mode: %s", opts.IDMode)
+	}
+
 	if parserConfig.IDPrefix == "" {
 		h := sha256.Sum256([]byte(fmt.Sprintf("%s-%d", filePath, time.Now().UnixNano())))
 		parserConfig.IDPrefix = hex.EncodeToString(h[:8])
@@ -74,13 +144,23 @@ func (ll *LogLens) ImportFile(ctx context.Context, filePath string, parserConfig
 		return nil, fmt.Errorf("failed to parse file: %w", err)
 	}
 
-	tracked := ll.trackProgress(ctx, records, reporter)
+	source, err := filepath.Abs(filePath)
+	if err != nil {
+		source = filePath
+	}
+
+	tracked := ll.trackProgress(ctx, ll.prepareRecords(ctx, records, source, opts.IDMode), reporter)
 
-	result, err := ll.storage.Store(ctx, tracked)
+	result, err := ll.storage.StoreWithOptions(ctx, tracked, domain.StoreOptions{Dedup: opts.Dedup, Bulk: opts.Bulk, DeferIndexes: opts.DeferIndexes})
 	if err != nil {
-		reporter.ReportError(err)
+		if reporter != nil {
+			reporter.ReportError(err)
+		}
 		return nil, fmt.Errorf("failed to store records: %w", err)
 	}
+	if err := ll.indexCorrelationFields(ctx); err != nil {
+		log.Printf("Failed to index correlation fields: %v", err)
+	}
 
 	if reporter != nil {
 		reporter.ReportProgress(result.Processed, result.Processed, "Import complete")
@@ -121,7 +201,54 @@ func (ll *LogLens) trackProgress(ctx context.Context, in <-chan domain.LogRecord
 	return out
 }
 
+// prepareRecords stamps each record with its source and, in content ID mode,
+// replaces the parser's ID with a hash of source, byte offset and raw text so
+// that re-importing the same file yields the same IDs while different content
+// at the same path does not.
+func (ll *LogLens) prepareRecords(ctx context.Context, in <-chan domain.LogRecord, source string, idMode domain.IDMode) <-chan domain.LogRecord {
+	out := make(chan domain.LogRecord, 100)
+	go func() {
+		defer close(out)
+		for record := range in {
+			if record.Source == "" {
+				record.Source = source
+			}
+			if idMode == domain.IDModeContent {
+				record.ID = contentID(record.Source, record.Offset, record.Raw)
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case out <- record:
+			}
+		}
+	}()
+	return out
+}
+
+func contentID(source string, offset int64, raw string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%d\x00", source, offset)
+	h.Write([]byte(raw))
+	return hex.EncodeToString(h.Sum(nil)[:16])
+}
+
 func (ll *LogLens) AutoImportFile(ctx context.Context, filePath string, reporter domain.ProgressReporter) (*domain.ImportResult, error) {
+	return ll.AutoImportFileWithOptions(ctx, filePath, domain.ImportOptions{}, reporter)
+}
+
+// AutoImportFileWithOptions detects the parser from the start of the file.
+// Unless opts say otherwise, records get content IDs and duplicates are
+// skipped, so re-importing a file adds only new lines and a different file
+// at the same path never overwrites earlier records.
+func (ll *LogLens) AutoImportFileWithOptions(ctx context.Context, filePath string, opts domain.ImportOptions, reporter domain.ProgressReporter) (*domain.ImportResult, error) {
+	if opts.IDMode == "" {
+		opts.IDMode = domain.IDModeContent
+	}
+	if opts.Dedup == "" {
+		opts.Dedup = domain.DedupSkip
+	}
+
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to open file: %w", err)
@@ -147,7 +274,7 @@ func (ll *LogLens) AutoImportFile(ctx context.Context, filePath string, reporter
 		IDPrefix: hex.EncodeToString(h[:8]),
 	}
 
-	return ll.ImportFile(ctx, filePath, parserConfig, reporter)
+	return ll.ImportFileWithOptions(ctx, filePath, parserConfig, opts, reporter)
 }
 
 func (ll *LogLens) Query(ctx context.Context, query domain.Query) (*domain.QueryResult, error) {
@@ -158,10 +285,31 @@ func (ll *LogLens) ExplainQuery(query domain.Query) (string, error) {
 	return ll.queryEngine.Explain(query)
 }
 
+// ParseQuery compiles search bar text into filter conditions.
+func (ll *LogLens) ParseQuery(text string) ([]domain.FilterCondition, error) {
+	return lang.ParseFilters(text, time.Now())
+}
+
+// FormatQuery renders filter conditions as search bar text.
+func (ll *LogLens) FormatQuery(filters []domain.FilterCondition) (string, error) {
+	return lang.FormatFilters(filters)
+}
+
 func (ll *LogLens) GetRecord(ctx context.Context, id string) (*domain.LogRecord, error) {
 	return ll.storage.GetRecord(ctx, id)
 }
 
+// GetContext returns the lines around record id in its source file.
+func (ll *LogLens) GetContext(ctx context.Context, id string, before, after int) (*domain.RecordContext, error) {
+	provider, ok := ll.storage.(interface {
+		GetContext(context.Context, string, int, int) (*domain.RecordContext, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("record context not supported by storage")
+	}
+	return provider.GetContext(ctx, id, before, after)
+}
+
 func (ll *LogLens) GetTimeline(ctx context.Context, req domain.TimelineRequest) ([]domain.TimelinePoint, error) {
 	provider, ok := ll.storage.(interface{ Timeline(context.Context, []domain.FilterCondition, int64) ([]domain.TimelinePoint, error) })
 	if !ok {
@@ -170,6 +318,28 @@ func (ll *LogLens) GetTimeline(ctx context.Context, req domain.TimelineRequest)
 	return provider.Timeline(ctx, req.Filters, req.BucketMs)
 }
 
+// GetTimelineSeries returns zero-filled timeline series, split by
+// req.SplitBy when set.
+func (ll *LogLens) GetTimelineSeries(ctx context.Context, req domain.TimelineRequest) (*domain.TimelineResult, error) {
+	provider, ok := ll.storage.(interface {
+		TimelineSeries(context.Context, domain.TimelineRequest) (*domain.TimelineResult, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("timeline series not supported by storage")
+	}
+	return provider.TimelineSeries(ctx, req)
+}
+
+func (ll *LogLens) GetFieldCatalog(ctx context.Context, source string) ([]domain.FieldInfo, error) {
+	provider, ok := ll.storage.(interface {
+		GetFieldCatalog(context.Context, string) ([]domain.FieldInfo, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("field catalog not supported by storage")
+	}
+	return provider.GetFieldCatalog(ctx, source)
+}
+
 func (ll *LogLens) GetSupportedParserTypes() []domain.ParserType {
 	return ll.parserFactory.GetSupportedTypes()
 }
@@ -178,8 +348,403 @@ func (ll *LogLens) CreateParser(config domain.ParserConfig) (domain.Parser, erro
 	return ll.parserFactory.CreateParser(config)
 }
 
+func (ll *LogLens) DeleteByQuery(ctx context.Context, filters []domain.FilterCondition, dryRun bool) (*domain.DeleteResult, error) {
+	provider, ok := ll.storage.(interface {
+		DeleteByQuery(context.Context, []domain.FilterCondition, bool) (*domain.DeleteResult, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("delete not supported by storage")
+	}
+	return provider.DeleteByQuery(ctx, filters, dryRun)
+}
+
+func (ll *LogLens) GetRetentionPolicies(ctx context.Context) ([]domain.RetentionPolicy, error) {
+	provider, ok := ll.storage.(retentionProvider)
+	if !ok {
+		return nil, fmt.Errorf("retention not supported by storage")
+	}
+	return provider.GetRetentionPolicies(ctx)
+}
+
+func (ll *LogLens) SetRetentionPolicies(ctx context.Context, policies []domain.RetentionPolicy) error {
+	provider, ok := ll.storage.(retentionProvider)
+	if !ok {
+		return fmt.Errorf("retention not supported by storage")
+	}
+	return provider.SetRetentionPolicies(ctx, policies)
+}
+
+func (ll *LogLens) ApplyRetention(ctx context.Context, dryRun bool) (*domain.RetentionResult, error) {
+	provider, ok := ll.storage.(retentionProvider)
+	if !ok {
+		return nil, fmt.Errorf("retention not supported by storage")
+	}
+	return provider.ApplyRetention(ctx, dryRun)
+}
+
+func (ll *LogLens) Compact(ctx context.Context) (*domain.CompactResult, error) {
+	provider, ok := ll.storage.(interface {
+		Compact(context.Context) (*domain.CompactResult, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("compaction not supported by storage")
+	}
+	return provider.Compact(ctx)
+}
+
+type partitionProvider interface {
+	Partitioning(context.Context) (domain.PartitionGranularity, error)
+	SetPartitioning(context.Context, domain.PartitionGranularity) error
+	ListPartitions(context.Context) ([]domain.PartitionInfo, error)
+	DropPartitionsBefore(context.Context, int64, bool) (*domain.DeleteResult, error)
+}
+
+func (ll *LogLens) GetPartitioning(ctx context.Context) (domain.PartitionGranularity, error) {
+	provider, ok := ll.storage.(partitionProvider)
+	if !ok {
+		return domain.PartitionNone, nil
+	}
+	return provider.Partitioning(ctx)
+}
+
+func (ll *LogLens) SetPartitioning(ctx context.Context, granularity domain.PartitionGranularity) error {
+	provider, ok := ll.storage.(partitionProvider)
+	if !ok {
+		return fmt.Errorf("partitioning not supported by storage")
+	}
+	return provider.SetPartitioning(ctx, granularity)
+}
+
+func (ll *LogLens) ListPartitions(ctx context.Context) ([]domain.PartitionInfo, error) {
+	provider, ok := ll.storage.(partitionProvider)
+	if !ok {
+		return nil, fmt.Errorf("partitioning not supported by storage")
+	}
+	return provider.ListPartitions(ctx)
+}
+
+func (ll *LogLens) DropPartitionsBefore(ctx context.Context, beforeMs int64, dryRun bool) (*domain.DeleteResult, error) {
+	provider, ok := ll.storage.(partitionProvider)
+	if !ok {
+		return nil, fmt.Errorf("partitioning not supported by storage")
+	}
+	return provider.DropPartitionsBefore(ctx, beforeMs, dryRun)
+}
+
+// Facets returns the top values of fields for the records matching query.
+func (ll *LogLens) Facets(ctx context.Context, q domain.Query, fields []string, topN int) ([]domain.Facet, error) {
+	provider, ok := ll.storage.(interface {
+		Facets(context.Context, domain.Query, []string, int) ([]domain.Facet, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("facets not supported by storage")
+	}
+	q, err := lang.CompileQuery(q, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	return provider.Facets(ctx, q, fields, topN)
+}
+
+// GetPatterns mines message templates from the records matching q and
+// returns up to limit groups, largest first.
+func (ll *LogLens) GetPatterns(ctx context.Context, q domain.Query, limit int) ([]domain.LogGroup, error) {
+	scanner, ok := ll.storage.(interface {
+		Scan(context.Context, []domain.FilterCondition, func(domain.LogRecord) error) error
+	})
+	if !ok {
+		return nil, fmt.Errorf("pattern mining not supported by storage")
+	}
+	q, err := lang.CompileQuery(q, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+
+	miner := patterns.NewMiner(patterns.Options{})
+	err = scanner.Scan(ctx, q.Filters, func(record domain.LogRecord) error {
+		miner.Add(record)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to mine patterns: %w", err)
+	}
+
+	groups := miner.Groups()
+	if limit > 0 && len(groups) > limit {
+		groups = groups[:limit]
+	}
+	return groups, nil
+}
+
+// QueryPattern runs q restricted to the messages matching pattern, a
+// template returned by GetPatterns. The match is on the template's regexp,
+// not on the mined clusters, so the result is a superset of the pattern's
+// group: records mined into another group whose messages also fit the
+// template are included too.
+func (ll *LogLens) QueryPattern(ctx context.Context, q domain.Query, pattern string) (*domain.QueryResult, error) {
+	return ll.Query(ctx, PatternQuery(q, pattern))
+}
+
+// PatternQuery is the query QueryPattern runs.
+func PatternQuery(q domain.Query, pattern string) domain.Query {
+	q.Filters = append(append([]domain.FilterCondition(nil), q.Filters...), domain.FilterCondition{
+		Type:  domain.FilterRegexp,
+		Field: "message",
+		Value: patterns.Regexp(pattern),
+	})
+	return q
+}
+
+// TimelineQuery is the query selecting the records req counts.
+func TimelineQuery(req domain.TimelineRequest) domain.Query {
+	q := domain.Query{Filters: append([]domain.FilterCondition(nil), req.Filters...)}
+	if req.From != 0 {
+		q.Filters = append(q.Filters, domain.FilterCondition{Type: domain.FilterRange, Field: "timestamp", Operator: "gte", Value: req.From})
+	}
+	if req.To != 0 {
+		q.Filters = append(q.Filters, domain.FilterCondition{Type: domain.FilterRange, Field: "timestamp", Operator: "lt", Value: req.To})
+	}
+	return q
+}
+
+const maxCorrelatedRecords = 10000
+
+// GetCorrelationFields lists the ID fields found in the imported records,
+// most preferred first.
+func (ll *LogLens) GetCorrelationFields(ctx context.Context) ([]string, error) {
+	catalog, err := ll.GetFieldCatalog(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+	return correlation.DetectFields(catalog), nil
+}
+
+// indexCorrelationFields indexes the detected ID fields so that Correlate
+// and GetTrace look records up instead of scanning. Fields already indexed
+// are skipped, so only an import bringing a new ID field pays for a build.
+func (ll *LogLens) indexCorrelationFields(ctx context.Context) error {
+	indexer, ok := ll.storage.(interface {
+		IndexFields(context.Context, []string) error
+	})
+	if !ok {
+		return nil
+	}
+	fields, err := ll.GetCorrelationFields(ctx)
+	if err != nil || len(fields) == 0 {
+		return err
+	}
+	return indexer.IndexFields(ctx, fields)
+}
+
+// aliasFilter matches records holding value in any of fields.
+func aliasFilter(fields []string, value interface{}) domain.FilterCondition {
+	if len(fields) == 1 {
+		return domain.FilterCondition{Type: domain.FilterEquality, Field: fields[0], Value: value}
+	}
+	filter := domain.FilterCondition{Type: domain.FilterOr}
+	for _, field := range fields {
+		filter.Children = append(filter.Children, domain.FilterCondition{Type: domain.FilterEquality, Field: field, Value: value})
+	}
+	return filter
+}
+
+// Correlate returns the records of every source sharing record id's value
+// of field, or of its most preferred ID field when field is empty. Sources
+// spelling the field differently, such as requestId and request_id, are
+// matched too.
+func (ll *LogLens) Correlate(ctx context.Context, id string, field string) (*domain.Correlation, error) {
+	record, err := ll.storage.GetRecord(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	var fields []string
+	if field != "" {
+		fields = []string{field}
+	}
+	field, value, ok := correlation.Value(*record, fields)
+	if !ok {
+		return nil, fmt.Errorf("record %s has no correlation ID", id)
+	}
+	detected, err := ll.GetCorrelationFields(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := ll.storage.Query(ctx, domain.Query{
+		Filters: []domain.FilterCondition{aliasFilter(correlation.Aliases(field, detected), value)},
+		SortBy:  "timestamp",
+		Limit:   maxCorrelatedRecords,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query correlated records: %w", err)
+	}
+	c := correlation.Build(field, value, result.Records)
+	c.Truncated = result.Total > int64(len(result.Records))
+	return c, nil
+}
+
+const (
+	defaultTraceLimit = 50
+	maxTraceRecords   = 10000
+)
+
+func (ll *LogLens) traceFields(ctx context.Context) (trace.Fields, []string, error) {
+	catalog, err := ll.GetFieldCatalog(ctx, "")
+	if err != nil {
+		return trace.Fields{}, nil, err
+	}
+	fields, err := trace.DetectFields(catalog)
+	return fields, correlation.DetectFields(catalog), err
+}
+
+// ListTraces lists the traces among the records matching q, most recent
+// first.
+func (ll *LogLens) ListTraces(ctx context.Context, q domain.Query, limit int) ([]domain.TraceSummary, error) {
+	grouper, ok := ll.storage.(interface {
+		AggregateGroups(context.Context, domain.Query) ([]domain.GroupBucket, error)
+	})
+	if !ok {
+		return nil, fmt.Errorf("traces not supported by storage")
+	}
+	fields, _, err := ll.traceFields(ctx)
+	if err != nil {
+		return nil, err
+	}
+	q, err = lang.CompileQuery(q, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
+	if limit <= 0 {
+		limit = defaultTraceLimit
+	}
+
+	// One extra group leaves room for the records without a trace ID.
+	groups, err := grouper.AggregateGroups(ctx, domain.Query{
+		Filters: q.Filters,
+		GroupBy: []string{fields.Trace},
+		Aggregations: []domain.Aggregation{
+			{Function: "count", Alias: "records"},
+			{Function: "min", Field: "timestamp", Alias: "start"},
+			{Function: "max", Field: "timestamp", Alias: "end"},
+			{Function: "count", Field: fields.Span, Alias: "spans"},
+			{Function: "error_ratio", Alias: "errorRatio"},
+		},
+		GroupSort:     "start",
+		GroupSortDesc: true,
+		GroupLimit:    limit + 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list traces: %w", err)
+	}
+
+	traces := make([]domain.TraceSummary, 0, len(groups))
+	for _, g := range groups {
+		id := g.Key[fields.Trace]
+		if id == nil || id == "" || len(traces) == limit {
+			continue
+		}
+		summary := domain.TraceSummary{TraceID: fmt.Sprint(id)}
+		summary.Records, _ = g.Values["records"].(int64)
+		summary.Start, _ = g.Values["start"].(int64)
+		summary.End, _ = g.Values["end"].(int64)
+		summary.Spans, _ = g.Values["spans"].(int64)
+		summary.ErrorRatio, _ = g.Values["errorRatio"].(float64)
+		traces = append(traces, summary)
+	}
+	return traces, nil
+}
+
+// GetTrace assembles the span tree of traceID, matching the trace ID field
+// in any of its spellings as Correlate does.
+func (ll *LogLens) GetTrace(ctx context.Context, traceID string) (*domain.Trace, error) {
+	fields, detected, err := ll.traceFields(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result, err := ll.storage.Query(ctx, domain.Query{
+		Filters: []domain.FilterCondition{aliasFilter(correlation.Aliases(fields.Trace, detected), traceID)},
+		SortBy:  "timestamp",
+		Limit:   maxTraceRecords,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query trace records: %w", err)
+	}
+	if len(result.Records) == 0 {
+		return nil, fmt.Errorf("trace %s not found", traceID)
+	}
+	t := trace.Assemble(traceID, result.Records, fields)
+	t.Truncated = result.Total > int64(len(result.Records))
+	return t, nil
+}
+
+// DetectAnomalies scans each series of req.Timeline for anomalies, ranked
+// by score.
+func (ll *LogLens) DetectAnomalies(ctx context.Context, req domain.AnomalyRequest) ([]domain.Anomaly, error) {
+	timeline, err := ll.GetTimelineSeries(ctx, req.Timeline)
+	if err != nil {
+		return nil, err
+	}
+	opts := anomaly.Options{Threshold: req.Threshold, Season: req.Season}
+	var anomalies []domain.Anomaly
+	for _, series := range timeline.Series {
+		for _, a := range anomaly.Detect(series, timeline.To, opts) {
+			a.Field = req.Timeline.SplitBy
+			anomalies = append(anomalies, a)
+		}
+	}
+	return anomaly.Rank(anomalies, req.Limit), nil
+}
+
+// topAnomalySplits are the timelines scanned by TopAnomalies: total volume,
+// then volume per level and per service.
+var topAnomalySplits = []string{"", "level", "service"}
+
+// TopAnomalies lists the strongest anomalies in the whole dataset, over
+// automatically sized buckets.
+func (ll *LogLens) TopAnomalies(ctx context.Context, limit int) ([]domain.Anomaly, error) {
+	var anomalies []domain.Anomaly
+	for _, split := range topAnomalySplits {
+		found, err := ll.DetectAnomalies(ctx, domain.AnomalyRequest{
+			Timeline: domain.TimelineRequest{Interval: "auto", SplitBy: split},
+		})
+		if err != nil {
+			return nil, err
+		}
+		anomalies = append(anomalies, found...)
+	}
+	return anomaly.Rank(anomalies, limit), nil
+}
+
+type compressionProvider interface {
+	Compression(context.Context) (domain.CompressionMode, error)
+	SetCompression(context.Context, domain.CompressionMode) error
+	CompressionStats(context.Context) (*domain.CompressionStats, error)
+}
+
+func (ll *LogLens) GetCompression(ctx context.Context) (domain.CompressionMode, error) {
+	provider, ok := ll.storage.(compressionProvider)
+	if !ok {
+		return domain.CompressionNone, nil
+	}
+	return provider.Compression(ctx)
+}
+
+func (ll *LogLens) SetCompression(ctx context.Context, mode domain.CompressionMode) error {
+	provider, ok := ll.storage.(compressionProvider)
+	if !ok {
+		return fmt.Errorf("compression not supported by storage")
+	}
+	return provider.SetCompression(ctx, mode)
+}
+
+// Close cancels background work, waits for it to return and then closes the
+// storage. Calling it again is a no-op.
 func (ll *LogLens) Close() error {
-	return ll.storage.Close()
+	ll.closeOnce.Do(func() {
+		ll.cancel()
+		ll.background.Wait()
+		ll.closeErr = ll.storage.Close()
+	})
+	return ll.closeErr
 }
 
 func (ll *LogLens) GetStats(ctx context.Context) (*Stats, error) {
@@ -193,15 +758,26 @@ func (ll *LogLens) GetStats(ctx context.Context) (*Stats, error) {
 		return nil, fmt.Errorf("failed to get level counts: %w", err)
 	}
 
-	return &Stats{
+	stats := &Stats{
 		TotalRecords: total,
 		LastUpdated:  time.Now().UnixMilli(),
 		LevelCounts:  levelCounts,
-	}, nil
+	}
+
+	if provider, ok := ll.storage.(compressionProvider); ok {
+		compression, err := provider.CompressionStats(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get compression stats: %w", err)
+		}
+		stats.Compression = compression
+	}
+
+	return stats, nil
 }
 
 type Stats struct {
 	TotalRecords int64            `json:"totalRecords"`
 	LevelCounts  map[string]int64 `json:"levelCounts"`
 	LastUpdated  int64            `json:"lastUpdated"`
+	Compression  *domain.CompressionStats `json:"compression,omitempty"`
 }
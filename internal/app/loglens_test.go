@@ -2,10 +2,13 @@ package app
 
 import (
 	"context"
+	"database/sql"
+	"fmt"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"LogLens/internal/domain"
 )
@@ -55,6 +58,33 @@ type noopReporter struct{}
 func (r *noopReporter) ReportProgress(current, total int64, message string) {}
 func (r *noopReporter) ReportError(err error)                              {}
 
+func TestGetContext_ImportedContinuationLines(t *testing.T) {
+	ll, cleanup := newTestLogLens(t)
+	defer cleanup()
+
+	importPlain(t, ll, `2024-01-15 10:30:44 [INFO] [api] starting
+2024-01-15 10:30:45 [ERROR] [api] request failed
+	at handler.serve(handler.go:42)
+	at main.run(main.go:7)
+2024-01-15 10:30:46 [INFO] [api] recovered
+2024-01-15 10:30:47 [INFO] [api] done
+`)
+
+	result, err := ll.GetContext(context.Background(), "test_line_5", 2, 1)
+	if err != nil {
+		t.Fatalf("GetContext failed: %v", err)
+	}
+	if len(result.Before) != 1 || result.Before[0].ID != "test_line_2" || !result.MoreBefore {
+		t.Errorf("expected 2 lines before to reach into the multi-line record, got %+v (more=%v)", result.Before, result.MoreBefore)
+	}
+	if len(result.After) != 1 || result.After[0].ID != "test_line_6" || result.MoreAfter {
+		t.Errorf("expected the last record after, got %+v (more=%v)", result.After, result.MoreAfter)
+	}
+	if total := totalRecords(t, ll); total != 4 {
+		t.Errorf("expected continuation lines to be part of their record, got %d records", total)
+	}
+}
+
 func TestGetStats_SQLAggregation(t *testing.T) {
 	ll, cleanup := newTestLogLens(t)
 	defer cleanup()
@@ -230,3 +260,497 @@ func TestQueryEngine_RegexpFilterInSQL(t *testing.T) {
 		}
 	}
 }
+
+func TestQuery_TextCombinedWithFilters(t *testing.T) {
+	ll, cleanup := newTestLogLens(t)
+	defer cleanup()
+
+	content := `2024-01-15 10:30:45 [ERROR] Connection refused to 10.0.0.1
+2024-01-15 10:30:46 [FATAL] Disk full
+2024-01-15 10:30:47 [ERROR] Connection timed out to 192.168.1.1
+2024-01-15 10:30:48 [INFO] Connection established`
+
+	importPlain(t, ll, content)
+
+	result, err := ll.Query(context.Background(), domain.Query{
+		Text:    `(level:ERROR OR level:FATAL) -/refused/`,
+		Filters: []domain.FilterCondition{{Type: domain.FilterRange, Field: "line", Operator: "gte", Value: float64(2)}},
+		Limit:   100,
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if result.Total != 2 {
+		t.Errorf("expected 2 records, got %d", result.Total)
+	}
+
+	_, err = ll.Query(context.Background(), domain.Query{Text: `level:ERROR OR`, Limit: 10})
+	if err == nil || !strings.Contains(err.Error(), "column 15") {
+		t.Errorf("expected syntax error at column 15, got %v", err)
+	}
+}
+
+func TestQuery_GroupByLevel(t *testing.T) {
+	ll, cleanup := newTestLogLens(t)
+	defer cleanup()
+
+	content := `2024-01-15 10:30:45 [ERROR] Connection refused
+2024-01-15 10:30:46 [INFO] Request completed
+2024-01-15 10:30:47 [ERROR] Connection timed out
+2024-01-15 10:30:48 [WARN] Slow request`
+
+	importPlain(t, ll, content)
+
+	result, err := ll.Query(context.Background(), domain.Query{
+		Text:          `Connection OR Slow`,
+		GroupBy:       []string{"level"},
+		Aggregations:  []domain.Aggregation{{Function: "count"}},
+		GroupSort:     "count",
+		GroupSortDesc: true,
+		Limit:         10,
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(result.Groups) != 2 {
+		t.Fatalf("expected 2 groups, got %+v", result.Groups)
+	}
+	if result.Groups[0].Key["level"] != "ERROR" || result.Groups[0].Values["count"] != int64(2) {
+		t.Errorf("expected ERROR=2 first, got %+v", result.Groups[0])
+	}
+	if result.Aggregations["count"] != int64(3) {
+		t.Errorf("expected overall count 3, got %v", result.Aggregations["count"])
+	}
+}
+
+func TestFacets_UseQueryText(t *testing.T) {
+	ll, cleanup := newTestLogLens(t)
+	defer cleanup()
+
+	content := `2024-01-15 10:30:45 [ERROR] Connection refused
+2024-01-15 10:30:46 [INFO] Request completed
+2024-01-15 10:30:47 [ERROR] Connection timed out
+2024-01-15 10:30:48 [WARN] Connection slow`
+
+	importPlain(t, ll, content)
+
+	facets, err := ll.Facets(context.Background(), domain.Query{Text: "Connection"}, []string{"level"}, 1)
+	if err != nil {
+		t.Fatalf("Facets failed: %v", err)
+	}
+	level := facets[0]
+	if len(level.Values) != 1 || level.Values[0].Value != "ERROR" || level.Values[0].Count != 2 || level.Other != 1 {
+		t.Errorf("unexpected level facet: %+v", level)
+	}
+}
+
+func TestGetPatterns_DrillDown(t *testing.T) {
+	ll, cleanup := newTestLogLens(t)
+	defer cleanup()
+
+	content := `2024-01-15 10:30:45 [ERROR] Connection refused to 10.0.0.1
+2024-01-15 10:30:46 [INFO] Request 17 completed in 12ms
+2024-01-15 10:30:47 [ERROR] Connection refused to 192.168.1.1
+2024-01-15 10:30:48 [INFO] Request 18 completed in 40ms
+2024-01-15 10:30:49 [ERROR] Connection refused to 10.0.0.7`
+
+	importPlain(t, ll, content)
+
+	groups, err := ll.GetPatterns(context.Background(), domain.Query{}, 10)
+	if err != nil {
+		t.Fatalf("GetPatterns failed: %v", err)
+	}
+	if len(groups) != 2 || groups[0].Count != 3 || groups[0].Level != "ERROR" {
+		t.Fatalf("unexpected groups: %+v", groups)
+	}
+
+	result, err := ll.QueryPattern(context.Background(), domain.Query{Limit: 10}, groups[0].Pattern)
+	if err != nil {
+		t.Fatalf("QueryPattern failed: %v", err)
+	}
+	if result.Total != 3 {
+		t.Errorf("expected 3 records for %q, got %d", groups[0].Pattern, result.Total)
+	}
+
+	infoOnly, err := ll.GetPatterns(context.Background(), domain.Query{Text: "level:INFO"}, 10)
+	if err != nil {
+		t.Fatalf("GetPatterns failed: %v", err)
+	}
+	if len(infoOnly) != 1 || infoOnly[0].Count != 2 {
+		t.Errorf("expected one INFO pattern with 2 records, got %+v", infoOnly)
+	}
+}
+
+func TestTimelineQuery_SelectsTheCountedRecords(t *testing.T) {
+	ll, cleanup := newTestLogLens(t)
+	defer cleanup()
+
+	importPlain(t, ll, `2024-01-15 10:30:00 [ERROR] boom
+2024-01-15 10:31:00 [INFO] ok
+2024-01-15 10:32:00 [ERROR] boom again
+2024-01-15 10:33:00 [ERROR] late
+`)
+	from := time.Date(2024, 1, 15, 10, 30, 0, 0, time.UTC).UnixMilli()
+	req := domain.TimelineRequest{
+		Filters:  []domain.FilterCondition{{Type: domain.FilterEquality, Field: "level", Value: "ERROR"}},
+		Interval: "1m", From: from, To: from + 3*60*1000,
+	}
+
+	timeline, err := ll.GetTimelineSeries(context.Background(), req)
+	if err != nil {
+		t.Fatalf("GetTimelineSeries failed: %v", err)
+	}
+	var counted int64
+	for _, p := range timeline.Series[0].Points {
+		counted += p.Count
+	}
+	result, err := ll.Query(context.Background(), TimelineQuery(req))
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if counted != 2 || result.Total != counted {
+		t.Errorf("expected the timeline query to select the %d counted records, got %d", counted, result.Total)
+	}
+}
+
+func TestGetFieldCatalog_TaggedBySource(t *testing.T) {
+	ll, cleanup := newTestLogLens(t)
+	defer cleanup()
+
+	tmpDir := t.TempDir()
+	filePath := filepath.Join(tmpDir, "app.json")
+	content := `{"timestamp":"2024-01-15T10:30:00Z","level":"ERROR","message":"boom","status":500,"host":"a"}
+{"timestamp":"2024-01-15T10:31:00Z","level":"INFO","message":"ok","status":200}`
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+
+	if _, err := ll.ImportFile(context.Background(), filePath, domain.ParserConfig{Type: domain.ParserJSON}, &noopReporter{}); err != nil {
+		t.Fatalf("ImportFile failed: %v", err)
+	}
+
+	catalog, err := ll.GetFieldCatalog(context.Background(), filePath)
+	if err != nil {
+		t.Fatalf("GetFieldCatalog failed: %v", err)
+	}
+	if len(catalog) != 2 {
+		t.Fatalf("expected 2 fields (host, status), got %d", len(catalog))
+	}
+	if catalog[0].Path != "host" || catalog[0].NullRatio != 0.5 {
+		t.Errorf("unexpected host entry: %+v", catalog[0])
+	}
+	if catalog[1].Path != "status" || catalog[1].Types["number"] != 2 {
+		t.Errorf("unexpected status entry: %+v", catalog[1])
+	}
+}
+
+func importFileWithOptions(t *testing.T, ll *LogLens, filePath, content string, config domain.ParserConfig, opts domain.ImportOptions) *domain.ImportResult {
+	t.Helper()
+	if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+		t.Fatalf("failed to write test file: %v", err)
+	}
+	result, err := ll.ImportFileWithOptions(context.Background(), filePath, config, opts, &noopReporter{})
+	if err != nil {
+		t.Fatalf("ImportFileWithOptions failed: %v", err)
+	}
+	return result
+}
+
+func totalRecords(t *testing.T, ll *LogLens) int64 {
+	t.Helper()
+	stats, err := ll.GetStats(context.Background())
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	return stats.TotalRecords
+}
+
+func TestImport_ContentIDsAreIdempotent(t *testing.T) {
+	ll, cleanup := newTestLogLens(t)
+	defer cleanup()
+
+	filePath := filepath.Join(t.TempDir(), "app.log")
+	content := `2024-01-15 10:30:45 [ERROR] boom
+2024-01-15 10:30:46 [INFO] ok
+2024-01-15 10:30:46 [INFO] ok`
+	opts := domain.ImportOptions{IDMode: domain.IDModeContent, Dedup: domain.DedupSkip}
+	config := domain.ParserConfig{Type: domain.ParserPlain}
+
+	first := importFileWithOptions(t, ll, filePath, content, config, opts)
+	if first.Duplicates != 0 {
+		t.Errorf("first import: expected 0 duplicates, got %d", first.Duplicates)
+	}
+	second := importFileWithOptions(t, ll, filePath, content, config, opts)
+	if second.Duplicates != 3 {
+		t.Errorf("second import: expected 3 duplicates, got %d", second.Duplicates)
+	}
+	if total := totalRecords(t, ll); total != 3 {
+		t.Errorf("expected 3 records after re-import, got %d", total)
+	}
+
+	keep := importFileWithOptions(t, ll, filePath, content, config,
+		domain.ImportOptions{IDMode: domain.IDModeContent, Dedup: domain.DedupKeepBoth})
+	if keep.Duplicates != 3 {
+		t.Errorf("keep-both import: expected 3 duplicates, got %d", keep.Duplicates)
+	}
+	if total := totalRecords(t, ll); total != 6 {
+		t.Errorf("expected 6 records after keep-both import, got %d", total)
+	}
+}
+
+func TestImport_ContentIDsSamePathDifferentContent(t *testing.T) {
+	ll, cleanup := newTestLogLens(t)
+	defer cleanup()
+
+	filePath := filepath.Join(t.TempDir(), "rotated.log")
+	opts := domain.ImportOptions{IDMode: domain.IDModeContent}
+	config := domain.ParserConfig{Type: domain.ParserPlain}
+
+	importFileWithOptions(t, ll, filePath, "2024-01-15 10:30:45 [ERROR] first file", config, opts)
+	second := importFileWithOptions(t, ll, filePath, "2024-01-16 10:30:45 [ERROR] second file", config, opts)
+
+	if second.Duplicates != 0 {
+		t.Errorf("expected no duplicates for different content, got %d", second.Duplicates)
+	}
+	if total := totalRecords(t, ll); total != 2 {
+		t.Errorf("expected both files kept, got %d records", total)
+	}
+}
+
+func TestImport_ContentIDsIgnoreJSONIDs(t *testing.T) {
+	ll, cleanup := newTestLogLens(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	opts := domain.ImportOptions{IDMode: domain.IDModeContent}
+	config := domain.ParserConfig{Type: domain.ParserJSON}
+
+	importFileWithOptions(t, ll, filepath.Join(dir, "a.json"), `{"id":"1","level":"INFO","message":"from a"}`, config, opts)
+	importFileWithOptions(t, ll, filepath.Join(dir, "b.json"), `{"id":"1","level":"INFO","message":"from b"}`, config, opts)
+
+	if total := totalRecords(t, ll); total != 2 {
+		t.Errorf("expected JSON id collision to be avoided, got %d records", total)
+	}
+}
+
+func TestAutoImport_SamePathKeepsEarlierRecords(t *testing.T) {
+	ll, cleanup := newTestLogLens(t)
+	defer cleanup()
+
+	filePath := filepath.Join(t.TempDir(), "app.log")
+	autoImport := func(content string) *domain.ImportResult {
+		t.Helper()
+		if err := os.WriteFile(filePath, []byte(content), 0644); err != nil {
+			t.Fatalf("failed to write test file: %v", err)
+		}
+		result, err := ll.AutoImportFile(context.Background(), filePath, nil)
+		if err != nil {
+			t.Fatalf("AutoImportFile failed: %v", err)
+		}
+		return result
+	}
+
+	autoImport("2024-01-15 10:30:45 [ERROR] boom\n2024-01-15 10:30:46 [INFO] ok\n")
+	appended := autoImport("2024-01-15 10:30:45 [ERROR] boom\n2024-01-15 10:30:46 [INFO] ok\n2024-01-15 10:30:47 [INFO] ok\n")
+	if appended.Duplicates != 2 {
+		t.Errorf("expected the first two lines skipped as duplicates, got %d", appended.Duplicates)
+	}
+	// A different file written to the same path adds its lines.
+	autoImport("2024-01-16 08:00:00 [WARN] rotated\n")
+	if total := totalRecords(t, ll); total != 4 {
+		t.Errorf("expected 4 records, got %d", total)
+	}
+}
+
+func TestImport_ReplaceReportsDuplicates(t *testing.T) {
+	ll, cleanup := newTestLogLens(t)
+	defer cleanup()
+
+	importPlainWithPrefix(t, ll, "2024-01-15 10:30:45 [ERROR] boom", "same")
+	result := importPlainWithPrefix(t, ll, "2024-01-15 10:30:45 [ERROR] boom", "same")
+
+	if result.Duplicates != 1 {
+		t.Errorf("expected 1 replaced duplicate, got %d", result.Duplicates)
+	}
+	if total := totalRecords(t, ll); total != 1 {
+		t.Errorf("expected 1 record, got %d", total)
+	}
+}
+
+func TestTopAnomalies_ErrorBurst(t *testing.T) {
+	ll, cleanup := newTestLogLens(t)
+	defer cleanup()
+
+	var b strings.Builder
+	for minute := 0; minute < 30; minute++ {
+		for _, second := range []int{0, 20, 40} {
+			fmt.Fprintf(&b, "2024-01-15 10:%02d:%02d [INFO] Request completed\n", minute, second)
+		}
+		if minute == 20 {
+			for i := 0; i < 40; i++ {
+				fmt.Fprintf(&b, "2024-01-15 10:20:05 [ERROR] Connection refused\n")
+			}
+		}
+	}
+	importPlain(t, ll, b.String())
+
+	errors, err := ll.Query(context.Background(), domain.Query{Text: "level:ERROR", Limit: 1})
+	if err != nil || len(errors.Records) != 1 {
+		t.Fatalf("Query failed: %v", err)
+	}
+	burst := errors.Records[0].Timestamp
+
+	anomalies, err := ll.TopAnomalies(context.Background(), 5)
+	if err != nil {
+		t.Fatalf("TopAnomalies failed: %v", err)
+	}
+	var found bool
+	for _, a := range anomalies {
+		if a.Start > burst || a.End <= burst || !a.Spike {
+			t.Errorf("expected only spikes around the burst, got %+v", a)
+		}
+		found = found || (a.Field == "level" && a.Series == "ERROR")
+	}
+	if !found {
+		t.Errorf("expected an ERROR anomaly, got %+v", anomalies)
+	}
+}
+
+// indexedFields lists the fields with an expression index in the database
+// at dbPath.
+func indexedFields(t *testing.T, dbPath string) []string {
+	t.Helper()
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	rows, err := db.Query("SELECT field FROM field_indexes ORDER BY field")
+	if err != nil {
+		t.Fatalf("failed to read field indexes: %v", err)
+	}
+	defer rows.Close()
+	var fields []string
+	for rows.Next() {
+		var field string
+		if err := rows.Scan(&field); err != nil {
+			t.Fatalf("failed to read field indexes: %v", err)
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+func TestCorrelate_AcrossSources(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	ll, err := NewLogLens(Config{DatabasePath: dbPath})
+	if err != nil {
+		t.Fatalf("failed to create LogLens: %v", err)
+	}
+	defer ll.Close()
+
+	dir := t.TempDir()
+	config := domain.ParserConfig{Type: domain.ParserJSON}
+	importFileWithOptions(t, ll, filepath.Join(dir, "api.json"), `{"timestamp":"2024-01-15T10:30:00Z","level":"INFO","service":"api","message":"request received","request_id":"r-1"}
+{"timestamp":"2024-01-15T10:30:01Z","level":"INFO","service":"api","message":"request received","request_id":"r-2"}
+{"timestamp":"2024-01-15T10:30:03Z","level":"INFO","service":"api","message":"request done","request_id":"r-1"}`, config, domain.ImportOptions{})
+	importFileWithOptions(t, ll, filepath.Join(dir, "db.json"), `{"timestamp":"2024-01-15T10:30:01Z","level":"ERROR","service":"db","message":"deadlock","request_id":"r-1"}
+{"timestamp":"2024-01-15T10:30:02Z","level":"INFO","service":"db","message":"query ok","request_id":"r-2"}`, config, domain.ImportOptions{})
+	importFileWithOptions(t, ll, filepath.Join(dir, "worker.json"), `{"timestamp":"2024-01-15T10:30:04Z","level":"INFO","service":"worker","message":"job sent","requestId":"r-1"}
+{"timestamp":"2024-01-15T10:30:05Z","level":"INFO","service":"worker","message":"job sent","requestId":"r-2"}`, config, domain.ImportOptions{})
+
+	fields, err := ll.GetCorrelationFields(context.Background())
+	if err != nil || len(fields) != 2 || fields[0] != "request_id" || fields[1] != "requestId" {
+		t.Fatalf("expected request_id and requestId to be detected, got %v (%v)", fields, err)
+	}
+	if indexed := indexedFields(t, dbPath); fmt.Sprint(indexed) != "[requestId request_id]" {
+		t.Errorf("expected the imports to index both spellings, got %v", indexed)
+	}
+
+	deadlock, err := ll.Query(context.Background(), domain.Query{Text: "deadlock", Limit: 1})
+	if err != nil || len(deadlock.Records) != 1 {
+		t.Fatalf("Query failed: %v", err)
+	}
+	c, err := ll.Correlate(context.Background(), deadlock.Records[0].ID, "")
+	if err != nil {
+		t.Fatalf("Correlate failed: %v", err)
+	}
+	if c.Field != "request_id" || c.Value != "r-1" || len(c.Records) != 4 || c.DurationMs != 4000 || c.Errors != 1 {
+		t.Fatalf("unexpected correlation: %+v", c)
+	}
+	if c.Records[1].Message != "deadlock" || len(c.Services) != 3 || c.Services[1].Service != "db" || c.Services[2].Service != "worker" {
+		t.Errorf("expected api, db then worker in time order, got %+v", c.Services)
+	}
+
+	// Starting from the differently named field finds the same records.
+	job, err := ll.Query(context.Background(), domain.Query{Text: `service:worker`, Limit: 1, SortBy: "timestamp"})
+	if err != nil || len(job.Records) != 1 {
+		t.Fatalf("Query failed: %v", err)
+	}
+	c, err = ll.Correlate(context.Background(), job.Records[0].ID, "")
+	if err != nil {
+		t.Fatalf("Correlate failed: %v", err)
+	}
+	if c.Field != "requestId" || len(c.Records) != 4 {
+		t.Errorf("expected the 4 records of r-1 via requestId, got %+v", c)
+	}
+}
+
+func TestTraces_ListAndAssemble(t *testing.T) {
+	ll, cleanup := newTestLogLens(t)
+	defer cleanup()
+
+	dir := t.TempDir()
+	importFileWithOptions(t, ll, filepath.Join(dir, "spans.json"), `{"timestamp":"2024-01-15T10:30:00.120Z","level":"INFO","service":"api","message":"GET /orders","trace_id":"t1","span_id":"a","duration_ms":120}
+{"timestamp":"2024-01-15T10:30:00.100Z","level":"ERROR","service":"db","message":"select orders","trace_id":"t1","span_id":"b","parent_span_id":"a","duration_ms":90}
+{"timestamp":"2024-01-15T10:31:00.005Z","level":"INFO","service":"api","message":"GET /health","trace_id":"t2","span_id":"c","duration_ms":5}
+{"timestamp":"2024-01-15T10:30:00.050Z","level":"INFO","service":"cache","message":"get orders","traceId":"t1","spanId":"d","parentSpanId":"a","duration_ms":20}
+{"timestamp":"2024-01-15T10:32:00Z","level":"INFO","service":"api","message":"no trace"}`, domain.ParserConfig{Type: domain.ParserJSON}, domain.ImportOptions{})
+
+	traces, err := ll.ListTraces(context.Background(), domain.Query{}, 10)
+	if err != nil {
+		t.Fatalf("ListTraces failed: %v", err)
+	}
+	if len(traces) != 2 || traces[0].TraceID != "t2" || traces[1].TraceID != "t1" {
+		t.Fatalf("expected t2 then t1, got %+v", traces)
+	}
+	if t1 := traces[1]; t1.Records != 2 || t1.Spans != 2 || t1.ErrorRatio != 0.5 {
+		t.Errorf("unexpected t1 summary: %+v", t1)
+	}
+
+	tr, err := ll.GetTrace(context.Background(), "t1")
+	if err != nil {
+		t.Fatalf("GetTrace failed: %v", err)
+	}
+	if tr.DurationMs != 120 || len(tr.Spans) != 3 || tr.Spans[1].Depth != 1 || !tr.Spans[1].Error || !tr.Spans[1].Critical {
+		t.Errorf("unexpected trace: %+v", tr)
+	}
+	if cache := tr.Spans[2]; cache.SpanID != "d" || cache.ParentID != "a" || cache.Depth != 1 {
+		t.Errorf("expected the span spelled spanId under a, got %+v", cache)
+	}
+
+	if _, err := ll.GetTrace(context.Background(), "missing"); err == nil {
+		t.Error("expected an error for an unknown trace")
+	}
+}
+
+func TestClose_StopsRetentionAndIsIdempotent(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "test.db")
+	ll, err := NewLogLens(Config{DatabasePath: dbPath, RetentionInterval: time.Millisecond})
+	if err != nil {
+		t.Fatalf("failed to create LogLens: %v", err)
+	}
+	importPlain(t, ll, "2024-01-15 10:30:00 ERROR boom\n")
+	if err := ll.SetRetentionPolicies(context.Background(), []domain.RetentionPolicy{{MaxRows: 1000}}); err != nil {
+		t.Fatalf("SetRetentionPolicies failed: %v", err)
+	}
+	// Let a few retention passes run before closing under them.
+	time.Sleep(20 * time.Millisecond)
+
+	if err := ll.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	if err := ll.Close(); err != nil {
+		t.Errorf("second Close failed: %v", err)
+	}
+}
@@ -0,0 +1,352 @@
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	DefaultWorkspace      = "default"
+	workspaceRegistryFile = "workspaces.json"
+)
+
+var workspaceSlugRe = regexp.MustCompile(`[^a-z0-9_-]+`)
+
+type Workspace struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Description string `json:"description,omitempty"`
+	CreatedAt   int64  `json:"createdAt"`
+	LastOpened  int64  `json:"lastOpened,omitempty"`
+	SizeBytes   int64  `json:"sizeBytes"`
+}
+
+type workspaceRegistry struct {
+	Current    string      `json:"current"`
+	Workspaces []Workspace `json:"workspaces"`
+}
+
+// WorkspaceManager keeps the list of named workspaces in a small JSON registry
+// under root. Each workspace is an independent SQLite file that may live
+// anywhere on disk.
+type WorkspaceManager struct {
+	mu   sync.Mutex
+	root string
+}
+
+func NewWorkspaceManager(root string) (*WorkspaceManager, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create workspace directory: %w", err)
+	}
+
+	m := &WorkspaceManager{root: root}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reg, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	if len(reg.Workspaces) == 0 {
+		reg.Workspaces = append(reg.Workspaces, Workspace{
+			Name:      DefaultWorkspace,
+			Path:      filepath.Join(root, "loglens.db"),
+			CreatedAt: time.Now().UnixMilli(),
+		})
+		reg.Current = DefaultWorkspace
+		if err := m.save(reg); err != nil {
+			return nil, err
+		}
+	}
+
+	return m, nil
+}
+
+func (m *WorkspaceManager) List() ([]Workspace, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reg, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	workspaces := make([]Workspace, len(reg.Workspaces))
+	for i, ws := range reg.Workspaces {
+		ws.SizeBytes = databaseSize(ws.Path)
+		workspaces[i] = ws
+	}
+	sort.Slice(workspaces, func(i, j int) bool {
+		return workspaces[i].Name < workspaces[j].Name
+	})
+	return workspaces, nil
+}
+
+func (m *WorkspaceManager) Current() (*Workspace, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reg, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	ws := reg.find(reg.Current)
+	if ws == nil && len(reg.Workspaces) > 0 {
+		ws = &reg.Workspaces[0]
+	}
+	if ws == nil {
+		return nil, fmt.Errorf("no workspaces configured")
+	}
+	out := *ws
+	out.SizeBytes = databaseSize(out.Path)
+	return &out, nil
+}
+
+func (m *WorkspaceManager) Get(name string) (*Workspace, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reg, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	ws := reg.find(name)
+	if ws == nil {
+		return nil, fmt.Errorf("workspace not found: %s", name)
+	}
+	out := *ws
+	out.SizeBytes = databaseSize(out.Path)
+	return &out, nil
+}
+
+// Create registers a new workspace. The database file is placed in dir, or in
+// the manager root when dir is empty; an existing database at that location
+// is reused as-is.
+func (m *WorkspaceManager) Create(name, dir, description string) (*Workspace, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("workspace name cannot be empty")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reg, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	if reg.find(name) != nil {
+		return nil, fmt.Errorf("workspace already exists: %s", name)
+	}
+
+	if dir == "" {
+		dir = filepath.Join(m.root, "workspaces")
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create workspace directory: %w", err)
+	}
+	path, err := filepath.Abs(filepath.Join(dir, workspaceFileName(name)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve workspace path: %w", err)
+	}
+	for _, ws := range reg.Workspaces {
+		if ws.Path == path {
+			return nil, fmt.Errorf("database %s is already used by workspace %s", path, ws.Name)
+		}
+	}
+
+	ws := Workspace{
+		Name:        name,
+		Path:        path,
+		Description: description,
+		CreatedAt:   time.Now().UnixMilli(),
+	}
+	reg.Workspaces = append(reg.Workspaces, ws)
+	if err := m.save(reg); err != nil {
+		return nil, err
+	}
+	return &ws, nil
+}
+
+// Rename changes only the display name; the database file stays where it is.
+func (m *WorkspaceManager) Rename(oldName, newName string) error {
+	newName = strings.TrimSpace(newName)
+	if newName == "" {
+		return fmt.Errorf("workspace name cannot be empty")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reg, err := m.load()
+	if err != nil {
+		return err
+	}
+	ws := reg.find(oldName)
+	if ws == nil {
+		return fmt.Errorf("workspace not found: %s", oldName)
+	}
+	if oldName != newName && reg.find(newName) != nil {
+		return fmt.Errorf("workspace already exists: %s", newName)
+	}
+
+	ws.Name = newName
+	if reg.Current == oldName {
+		reg.Current = newName
+	}
+	return m.save(reg)
+}
+
+// Delete unregisters a workspace and, when removeFiles is set, deletes its
+// database together with the WAL and shared-memory files and the backups
+// taken before migrations.
+func (m *WorkspaceManager) Delete(name string, removeFiles bool) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reg, err := m.load()
+	if err != nil {
+		return err
+	}
+	if reg.Current == name {
+		return fmt.Errorf("cannot delete the open workspace %s", name)
+	}
+
+	idx := -1
+	for i, ws := range reg.Workspaces {
+		if ws.Name == name {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return fmt.Errorf("workspace not found: %s", name)
+	}
+	path := reg.Workspaces[idx].Path
+
+	reg.Workspaces = append(reg.Workspaces[:idx], reg.Workspaces[idx+1:]...)
+	if err := m.save(reg); err != nil {
+		return err
+	}
+
+	if removeFiles {
+		files := []string{path, path + "-wal", path + "-shm"}
+		backups, err := migrationBackups(path)
+		if err != nil {
+			return err
+		}
+		for _, p := range append(files, backups...) {
+			if err := os.Remove(p); err != nil && !os.IsNotExist(err) {
+				return fmt.Errorf("failed to remove %s: %w", p, err)
+			}
+		}
+	}
+	return nil
+}
+
+// MarkOpened records name as the current workspace so it is reopened on the
+// next start.
+func (m *WorkspaceManager) MarkOpened(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	reg, err := m.load()
+	if err != nil {
+		return err
+	}
+	ws := reg.find(name)
+	if ws == nil {
+		return fmt.Errorf("workspace not found: %s", name)
+	}
+	ws.LastOpened = time.Now().UnixMilli()
+	reg.Current = name
+	return m.save(reg)
+}
+
+func (m *WorkspaceManager) load() (*workspaceRegistry, error) {
+	reg := &workspaceRegistry{}
+	data, err := os.ReadFile(filepath.Join(m.root, workspaceRegistryFile))
+	if os.IsNotExist(err) {
+		return reg, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read workspace registry: %w", err)
+	}
+	if err := json.Unmarshal(data, reg); err != nil {
+		return nil, fmt.Errorf("failed to parse workspace registry: %w", err)
+	}
+	return reg, nil
+}
+
+func (m *WorkspaceManager) save(reg *workspaceRegistry) error {
+	data, err := json.MarshalIndent(reg, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode workspace registry: %w", err)
+	}
+
+	path := filepath.Join(m.root, workspaceRegistryFile)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write workspace registry: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to write workspace registry: %w", err)
+	}
+	return nil
+}
+
+func (r *workspaceRegistry) find(name string) *Workspace {
+	for i := range r.Workspaces {
+		if r.Workspaces[i].Name == name {
+			return &r.Workspaces[i]
+		}
+	}
+	return nil
+}
+
+func workspaceFileName(name string) string {
+	slug := workspaceSlugRe.ReplaceAllString(strings.ToLower(name), "-")
+	slug = strings.Trim(slug, "-")
+	if slug == "" {
+		slug = "workspace"
+	}
+	return slug + ".db"
+}
+
+// migrationBackups lists the copies storage keeps of the database at path
+// before migrating it, named "<file>.v<version>-<time>.bak".
+func migrationBackups(path string) ([]string, error) {
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to list workspace directory: %w", err)
+	}
+	prefix := filepath.Base(path) + ".v"
+	var backups []string
+	for _, e := range entries {
+		if !e.IsDir() && strings.HasPrefix(e.Name(), prefix) && strings.HasSuffix(e.Name(), ".bak") {
+			backups = append(backups, filepath.Join(filepath.Dir(path), e.Name()))
+		}
+	}
+	return backups, nil
+}
+
+func databaseSize(path string) int64 {
+	var total int64
+	for _, p := range []string{path, path + "-wal"} {
+		if info, err := os.Stat(p); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
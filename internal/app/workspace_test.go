@@ -0,0 +1,147 @@
+package app
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWorkspaceManager_DefaultWorkspace(t *testing.T) {
+	root := t.TempDir()
+	m, err := NewWorkspaceManager(root)
+	if err != nil {
+		t.Fatalf("NewWorkspaceManager failed: %v", err)
+	}
+
+	ws, err := m.Current()
+	if err != nil {
+		t.Fatalf("Current failed: %v", err)
+	}
+	if ws.Name != DefaultWorkspace {
+		t.Errorf("expected default workspace, got %s", ws.Name)
+	}
+	if ws.Path != filepath.Join(root, "loglens.db") {
+		t.Errorf("default workspace should keep the legacy database path, got %s", ws.Path)
+	}
+}
+
+func TestWorkspaceManager_Lifecycle(t *testing.T) {
+	root := t.TempDir()
+	m, err := NewWorkspaceManager(root)
+	if err != nil {
+		t.Fatalf("NewWorkspaceManager failed: %v", err)
+	}
+
+	ticketDir := filepath.Join(t.TempDir(), "INC-1234")
+	ws, err := m.Create("INC-1234 Payment outage", ticketDir, "checkout errors")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	if filepath.Dir(ws.Path) != ticketDir {
+		t.Errorf("expected database next to ticket folder, got %s", ws.Path)
+	}
+	if _, err := m.Create("INC-1234 Payment outage", "", ""); err == nil {
+		t.Error("expected error for duplicate workspace name")
+	}
+
+	ll, err := NewLogLens(Config{DatabasePath: ws.Path})
+	if err != nil {
+		t.Fatalf("failed to open workspace database: %v", err)
+	}
+	importPlain(t, ll, "2024-01-15 10:30:45 [ERROR] boom")
+	ll.Close()
+
+	if err := m.MarkOpened(ws.Name); err != nil {
+		t.Fatalf("MarkOpened failed: %v", err)
+	}
+	if err := m.Rename(ws.Name, "INC-1234"); err != nil {
+		t.Fatalf("Rename failed: %v", err)
+	}
+
+	current, err := m.Current()
+	if err != nil {
+		t.Fatalf("Current failed: %v", err)
+	}
+	if current.Name != "INC-1234" || current.Path != ws.Path {
+		t.Errorf("rename should follow the open workspace, got %+v", current)
+	}
+	if current.SizeBytes == 0 {
+		t.Error("expected non-zero workspace size")
+	}
+
+	reopened, err := NewWorkspaceManager(root)
+	if err != nil {
+		t.Fatalf("NewWorkspaceManager failed: %v", err)
+	}
+	list, err := reopened.List()
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 workspaces after reload, got %d", len(list))
+	}
+
+	if err := reopened.Delete("INC-1234", true); err == nil {
+		t.Error("expected error deleting the open workspace")
+	}
+	if err := reopened.MarkOpened(DefaultWorkspace); err != nil {
+		t.Fatalf("MarkOpened failed: %v", err)
+	}
+	backup := ws.Path + ".v3-20240115-103045.bak"
+	other := filepath.Join(ticketDir, "other.db.v3-20240115-103045.bak")
+	for _, p := range []string{backup, other} {
+		if err := os.WriteFile(p, []byte("backup"), 0644); err != nil {
+			t.Fatalf("failed to write backup: %v", err)
+		}
+	}
+	if err := reopened.Delete("INC-1234", true); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, err := os.Stat(ws.Path); !os.IsNotExist(err) {
+		t.Errorf("expected workspace database to be removed, stat err=%v", err)
+	}
+	if _, err := os.Stat(backup); !os.IsNotExist(err) {
+		t.Errorf("expected migration backup to be removed, stat err=%v", err)
+	}
+	if _, err := os.Stat(other); err != nil {
+		t.Errorf("expected another database's backup to be kept, stat err=%v", err)
+	}
+}
+
+func TestWorkspaceManager_SeparateDatabases(t *testing.T) {
+	m, err := NewWorkspaceManager(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewWorkspaceManager failed: %v", err)
+	}
+
+	a, err := m.Create("alpha", "", "")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+	b, err := m.Create("beta", "", "")
+	if err != nil {
+		t.Fatalf("Create failed: %v", err)
+	}
+
+	llA, err := NewLogLens(Config{DatabasePath: a.Path})
+	if err != nil {
+		t.Fatalf("NewLogLens failed: %v", err)
+	}
+	defer llA.Close()
+	llB, err := NewLogLens(Config{DatabasePath: b.Path})
+	if err != nil {
+		t.Fatalf("NewLogLens failed: %v", err)
+	}
+	defer llB.Close()
+
+	importPlain(t, llA, "2024-01-15 10:30:45 [ERROR] only in alpha")
+
+	statsB, err := llB.GetStats(context.Background())
+	if err != nil {
+		t.Fatalf("GetStats failed: %v", err)
+	}
+	if statsB.TotalRecords != 0 {
+		t.Errorf("expected empty beta workspace, got %d records", statsB.TotalRecords)
+	}
+}
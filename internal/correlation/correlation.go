@@ -0,0 +1,183 @@
+// Package correlation links records that share a trace, request or
+// correlation ID, across sources and services.
+package correlation
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"LogLens/internal/domain"
+)
+
+// idFieldNames are the recognised ID fields, normalized by NormalizeName,
+// in order of preference.
+var idFieldNames = []string{
+	"traceid",
+	"requestid",
+	"xrequestid",
+	"reqid",
+	"correlationid",
+	"xcorrelationid",
+	"transactionid",
+}
+
+// NormalizeName lowercases name and drops separators, so trace_id, traceId
+// and trace-id compare equal.
+func NormalizeName(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(unicode.ToLower(r))
+		}
+	}
+	return b.String()
+}
+
+func idRank(name string) int {
+	n := NormalizeName(name)
+	for i, id := range idFieldNames {
+		if n == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// IsIDField reports whether field is a recognised ID field.
+func IsIDField(field string) bool {
+	return idRank(field) >= 0
+}
+
+// DetectFields returns the top-level catalog fields that hold IDs, most
+// preferred first. Objects and arrays are skipped.
+func DetectFields(catalog []domain.FieldInfo) []string {
+	type candidate struct {
+		path string
+		rank int
+		seen int64
+	}
+	var found []candidate
+	for _, f := range catalog {
+		rank := idRank(f.Path)
+		if rank < 0 || strings.Contains(f.Path, ".") || f.Types["string"]+f.Types["number"] == 0 {
+			continue
+		}
+		found = append(found, candidate{f.Path, rank, f.Seen})
+	}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].rank != found[j].rank {
+			return found[i].rank < found[j].rank
+		}
+		if found[i].seen != found[j].seen {
+			return found[i].seen > found[j].seen
+		}
+		return found[i].path < found[j].path
+	})
+
+	fields := make([]string, len(found))
+	for i, c := range found {
+		fields[i] = c.path
+	}
+	return fields
+}
+
+// Aliases returns field followed by the fields of detected that spell the
+// same ID differently, such as requestId for request_id.
+func Aliases(field string, detected []string) []string {
+	aliases := []string{field}
+	name := NormalizeName(field)
+	for _, f := range detected {
+		if f != field && NormalizeName(f) == name {
+			aliases = append(aliases, f)
+		}
+	}
+	return aliases
+}
+
+// recordFields returns the ID fields of record, most preferred first.
+func recordFields(record domain.LogRecord) []string {
+	var fields []string
+	for field := range record.Fields {
+		if IsIDField(field) {
+			fields = append(fields, field)
+		}
+	}
+	sort.Slice(fields, func(i, j int) bool {
+		ri, rj := idRank(fields[i]), idRank(fields[j])
+		if ri != rj {
+			return ri < rj
+		}
+		return fields[i] < fields[j]
+	})
+	return fields
+}
+
+// Value returns the first of fields that record has a non-empty ID in. With
+// no fields, the record's own ID fields are tried in order of preference.
+func Value(record domain.LogRecord, fields []string) (string, interface{}, bool) {
+	if len(fields) == 0 {
+		fields = recordFields(record)
+	}
+	for _, field := range fields {
+		switch v := record.Fields[field].(type) {
+		case string:
+			if v != "" {
+				return field, v, true
+			}
+		case float64, int64, int:
+			return field, v, true
+		}
+	}
+	return "", nil, false
+}
+
+// Build assembles the correlation of records sharing value in field. The
+// records are sorted by time, then by source and line.
+func Build(field string, value interface{}, records []domain.LogRecord) *domain.Correlation {
+	sort.SliceStable(records, func(i, j int) bool {
+		a, b := records[i], records[j]
+		if a.Timestamp != b.Timestamp {
+			return a.Timestamp < b.Timestamp
+		}
+		if a.Source != b.Source {
+			return a.Source < b.Source
+		}
+		return a.Line < b.Line
+	})
+
+	c := &domain.Correlation{
+		Field:    field,
+		Value:    value,
+		Records:  records,
+		Services: make([]domain.ServiceSpan, 0),
+	}
+	if len(records) == 0 {
+		return c
+	}
+	c.Start, c.End = records[0].Timestamp, records[len(records)-1].Timestamp
+	c.DurationMs = c.End - c.Start
+
+	spans := make(map[string]*domain.ServiceSpan)
+	var order []string
+	for _, r := range records {
+		span, ok := spans[r.Service]
+		if !ok {
+			span = &domain.ServiceSpan{Service: r.Service, FirstSeen: r.Timestamp}
+			spans[r.Service] = span
+			order = append(order, r.Service)
+		}
+		span.LastSeen = r.Timestamp
+		span.Records++
+		if domain.IsErrorLevel(r.Level) {
+			span.Errors++
+			c.Errors++
+		}
+	}
+	for _, service := range order {
+		span := spans[service]
+		span.DurationMs = span.LastSeen - span.FirstSeen
+		c.Services = append(c.Services, *span)
+	}
+	return c
+}
@@ -0,0 +1,86 @@
+package correlation
+
+import (
+	"strings"
+	"testing"
+
+	"LogLens/internal/domain"
+)
+
+func TestDetectFields(t *testing.T) {
+	catalog := []domain.FieldInfo{
+		{Path: "status", Types: map[string]int64{"number": 10}, Seen: 10},
+		{Path: "requestId", Types: map[string]int64{"string": 4}, Seen: 4},
+		{Path: "request_id", Types: map[string]int64{"string": 8}, Seen: 8},
+		{Path: "trace-id", Types: map[string]int64{"string": 2}, Seen: 2},
+		{Path: "http.request_id", Types: map[string]int64{"string": 9}, Seen: 9},
+		{Path: "correlation_id", Types: map[string]int64{"object": 3}, Seen: 3},
+	}
+
+	got := DetectFields(catalog)
+	want := []string{"trace-id", "request_id", "requestId"}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("expected %v, got %v", want, got)
+			break
+		}
+	}
+}
+
+func TestAliases(t *testing.T) {
+	got := Aliases("request_id", []string{"trace-id", "request_id", "requestId", "x_request_id", "Request-ID"})
+	want := []string{"request_id", "requestId", "Request-ID"}
+	if strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("expected %v, got %v", want, got)
+	}
+}
+
+func TestValue(t *testing.T) {
+	record := domain.LogRecord{Fields: map[string]interface{}{
+		"correlation_id": "c-1",
+		"traceId":        "",
+		"request_id":     float64(42),
+	}}
+
+	if field, value, ok := Value(record, nil); !ok || field != "request_id" || value != float64(42) {
+		t.Errorf("expected request_id 42, got %s %v", field, value)
+	}
+	if field, value, ok := Value(record, []string{"correlation_id"}); !ok || field != "correlation_id" || value != "c-1" {
+		t.Errorf("expected correlation_id c-1, got %s %v", field, value)
+	}
+	if _, _, ok := Value(record, []string{"traceId"}); ok {
+		t.Error("expected an empty ID to be skipped")
+	}
+}
+
+func TestBuild_ServiceSpans(t *testing.T) {
+	records := []domain.LogRecord{
+		{ID: "4", Timestamp: 400, Service: "api", Level: "INFO", Source: "api.log", Line: 2},
+		{ID: "2", Timestamp: 150, Service: "db", Level: "ERROR", Source: "db.log", Line: 7},
+		{ID: "1", Timestamp: 100, Service: "api", Level: "INFO", Source: "api.log", Line: 1},
+		{ID: "3", Timestamp: 150, Service: "db", Level: "INFO", Source: "db.log", Line: 8},
+	}
+
+	c := Build("trace_id", "t-1", records)
+	if c.Start != 100 || c.End != 400 || c.DurationMs != 300 || c.Errors != 1 {
+		t.Errorf("unexpected request span: %+v", c)
+	}
+	for i, id := range []string{"1", "2", "3", "4"} {
+		if c.Records[i].ID != id {
+			t.Fatalf("expected records in time and line order, got %+v", c.Records)
+		}
+	}
+	if len(c.Services) != 2 {
+		t.Fatalf("expected 2 services, got %+v", c.Services)
+	}
+	api, db := c.Services[0], c.Services[1]
+	if api.Service != "api" || api.DurationMs != 300 || api.Records != 2 || api.Errors != 0 {
+		t.Errorf("unexpected api span: %+v", api)
+	}
+	if db.Service != "db" || db.FirstSeen != 150 || db.DurationMs != 0 || db.Errors != 1 {
+		t.Errorf("unexpected db span: %+v", db)
+	}
+}
@@ -12,6 +12,7 @@ type Parser interface {
 
 type Storage interface {
 	Store(ctx context.Context, records <-chan LogRecord) (*ImportResult, error)
+	StoreWithOptions(ctx context.Context, records <-chan LogRecord, opts StoreOptions) (*ImportResult, error)
 	Query(ctx context.Context, query Query) (*QueryResult, error)
 	GetRecord(ctx context.Context, id string) (*LogRecord, error)
 	GetTotalCount(ctx context.Context) (int64, error)
@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"time"
 )
 
@@ -12,6 +13,20 @@ type LogRecord struct {
 	Service   string                 `json:"service,omitempty"`
 	Fields    map[string]interface{} `json:"fields,omitempty"`
 	Raw       string                 `json:"raw"`
+	Source    string                 `json:"source,omitempty"`
+	Line      int64                  `json:"line,omitempty"`
+	// Offset is the byte offset of the record in its source as read by the
+	// parser. It only feeds content IDs and is not stored.
+	Offset    int64                  `json:"-"`
+}
+
+// IsErrorLevel reports whether level marks a failure.
+func IsErrorLevel(level string) bool {
+	switch strings.ToUpper(level) {
+	case "ERROR", "FATAL", "PANIC":
+		return true
+	}
+	return false
 }
 
 func (r *LogRecord) SetTimestamp(t time.Time) {
@@ -41,6 +56,11 @@ const (
 	FilterContains   FilterType = "contains"
 	FilterRegexp     FilterType = "regexp"
 	FilterRange      FilterType = "range"
+
+	// Group nodes combine their Children instead of testing a field.
+	FilterAnd FilterType = "and"
+	FilterOr  FilterType = "or"
+	FilterNot FilterType = "not"
 )
 
 type FilterCondition struct {
@@ -48,31 +68,90 @@ type FilterCondition struct {
 	Field    string     `json:"field"`
 	Value    interface{} `json:"value"`
 	Operator string     `json:"operator,omitempty"`
+	Children []FilterCondition `json:"children,omitempty"`
+}
+
+// IsGroup reports whether c is an and/or/not node rather than a leaf.
+func (c FilterCondition) IsGroup() bool {
+	switch c.Type {
+	case FilterAnd, FilterOr, FilterNot:
+		return true
+	}
+	return false
 }
 
 type Query struct {
 	Filters    []FilterCondition `json:"filters"`
 	GroupBy    []string          `json:"groupBy,omitempty"`
+	// BucketMs groups timestamps into buckets of this width; "timestamp" is
+	// added to GroupBy if missing.
+	BucketMs   int64             `json:"bucketMs,omitempty"`
+	GroupSort  string            `json:"groupSort,omitempty"`
+	GroupSortDesc bool           `json:"groupSortDesc,omitempty"`
+	GroupLimit int               `json:"groupLimit,omitempty"`
 	Aggregations []Aggregation   `json:"aggregations,omitempty"`
 	SortBy     string            `json:"sortBy,omitempty"`
 	SortDesc   bool              `json:"sortDesc,omitempty"`
 	Limit      int               `json:"limit,omitempty"`
 	Offset     int               `json:"offset,omitempty"`
+	Cursor     string            `json:"cursor,omitempty"`
+	CountMode  CountMode         `json:"countMode,omitempty"`
+	// Text is a search bar query; its filters are ANDed with Filters.
+	Text       string            `json:"text,omitempty"`
 }
 
+// CountMode controls how Total is computed for pages after the first one.
+// CountSkip leaves it out and sets TotalUnknown; CountCapped stops counting
+// at a fixed number of matches and sets TotalLowerBound when it is reached.
+type CountMode string
+
+const (
+	CountExact  CountMode = "exact"
+	CountSkip   CountMode = "skip"
+	CountCapped CountMode = "capped"
+)
+
 type Aggregation struct {
 	Function string `json:"function"`
 	Field    string `json:"field,omitempty"`
 	Alias    string `json:"alias,omitempty"`
+	// Percentile is the rank for the "percentile" function, in (0, 100].
+	Percentile float64 `json:"percentile,omitempty"`
+	// Interval sets fixed-width "histogram" buckets; LogBase > 1 uses
+	// buckets between successive powers of LogBase instead.
+	Interval float64 `json:"interval,omitempty"`
+	LogBase  float64 `json:"logBase,omitempty"`
+}
+
+// HistogramBucket counts values in [Lower, Upper).
+type HistogramBucket struct {
+	Lower float64 `json:"lower"`
+	Upper float64 `json:"upper"`
+	Count int64   `json:"count"`
 }
 
 type QueryResult struct {
 	Records      []LogRecord            `json:"records"`
 	Aggregations map[string]interface{} `json:"aggregations,omitempty"`
+	Groups       []GroupBucket          `json:"groups,omitempty"`
 	Total        int64                  `json:"total"`
+	// TotalUnknown is set when Total was not counted; Total is then zero.
+	TotalUnknown bool `json:"totalUnknown,omitempty"`
+	// TotalLowerBound is set when counting stopped at a cap, so there are at
+	// least Total matches.
+	TotalLowerBound bool `json:"totalLowerBound,omitempty"`
+	NextCursor   string                 `json:"nextCursor,omitempty"`
+	PrevCursor   string                 `json:"prevCursor,omitempty"`
 	Took         int64                  `json:"took"`
 }
 
+// GroupBucket is one group of a grouped aggregation. Key maps each GroupBy
+// field to its value and Values maps each aggregation alias to its result.
+type GroupBucket struct {
+	Key    map[string]interface{} `json:"key"`
+	Values map[string]interface{} `json:"values"`
+}
+
 type LogGroup struct {
 	ID        string `json:"id"`
 	Pattern   string `json:"pattern"`
@@ -87,10 +166,114 @@ type LogGroup struct {
 type ImportResult struct {
 	TotalRecords int64  `json:"totalRecords"`
 	Processed    int64  `json:"processed"`
+	Duplicates   int64  `json:"duplicates"`
+	Failed       int64  `json:"failed"`
 	Errors       []string `json:"errors,omitempty"`
+	RecordErrors []RecordError `json:"recordErrors,omitempty"`
+	Partial      bool   `json:"partial,omitempty"`
 	Duration     int64  `json:"duration"`
 }
 
+// RecordError describes a single record that could not be stored. Source and
+// Line locate it in the imported file when the parser provided them.
+type RecordError struct {
+	ID     string `json:"id"`
+	Source string `json:"source,omitempty"`
+	Line   int64  `json:"line,omitempty"`
+	Reason string `json:"reason"`
+}
+
+type IDMode string
+
+const (
+	IDModeSequential IDMode = "sequential"
+	IDModeContent    IDMode = "content"
+)
+
+type DedupPolicy string
+
+const (
+	DedupReplace  DedupPolicy = "replace"
+	DedupSkip     DedupPolicy = "skip"
+	DedupKeepBoth DedupPolicy = "keep-both"
+)
+
+type ImportOptions struct {
+	IDMode       IDMode      `json:"idMode,omitempty"`
+	Dedup        DedupPolicy `json:"dedup,omitempty"`
+	Bulk         bool        `json:"bulk,omitempty"`
+	DeferIndexes bool        `json:"deferIndexes,omitempty"`
+}
+
+// StoreOptions tune a single Store call. Bulk trades durability for speed
+// while the import runs; DeferIndexes additionally drops secondary indexes
+// until the load finishes, so filtered queries are slow in the meantime.
+type StoreOptions struct {
+	Dedup        DedupPolicy `json:"dedup,omitempty"`
+	Bulk         bool        `json:"bulk,omitempty"`
+	DeferIndexes bool        `json:"deferIndexes,omitempty"`
+}
+
+type FieldInfo struct {
+	Path      string           `json:"path"`
+	Types     map[string]int64 `json:"types"`
+	Samples   []string         `json:"samples,omitempty"`
+	Seen      int64            `json:"seen"`
+	Nulls     int64            `json:"nulls"`
+	NullRatio float64          `json:"nullRatio"`
+	Distinct  int64            `json:"distinct"`
+}
+
+// Facet is the value distribution of one field over the records matching a
+// query. Other counts records with values outside the top values and
+// Missing those without the field or with an empty value.
+type Facet struct {
+	Field   string       `json:"field"`
+	Values  []FacetValue `json:"values"`
+	Other   int64        `json:"other"`
+	Missing int64        `json:"missing"`
+}
+
+type FacetValue struct {
+	Value interface{} `json:"value"`
+	Count int64       `json:"count"`
+}
+
+type DeleteResult struct {
+	Matched int64 `json:"matched"`
+	Deleted int64 `json:"deleted"`
+	DryRun  bool  `json:"dryRun"`
+	Took    int64 `json:"took"`
+}
+
+type RetentionPolicy struct {
+	Source   string `json:"source"`
+	MaxAgeMs int64  `json:"maxAgeMs,omitempty"`
+	MaxRows  int64  `json:"maxRows,omitempty"`
+	MaxBytes int64  `json:"maxBytes,omitempty"`
+}
+
+type RetentionOutcome struct {
+	Policy  RetentionPolicy `json:"policy"`
+	Matched int64           `json:"matched"`
+	Deleted int64           `json:"deleted"`
+}
+
+type RetentionResult struct {
+	DryRun   bool               `json:"dryRun"`
+	Outcomes []RetentionOutcome `json:"outcomes"`
+	Matched  int64              `json:"matched"`
+	Deleted  int64              `json:"deleted"`
+	Took     int64              `json:"took"`
+}
+
+type CompactResult struct {
+	SizeBefore     int64 `json:"sizeBefore"`
+	SizeAfter      int64 `json:"sizeAfter"`
+	ReclaimedBytes int64 `json:"reclaimedBytes"`
+	Took           int64 `json:"took"`
+}
+
 type ParserType string
 
 const (
@@ -125,11 +308,204 @@ type IndexConfig struct {
 type TimelineRequest struct {
 	Filters  []FilterCondition `json:"filters"`
 	BucketMs int64             `json:"bucketMs"`
+	// Interval overrides BucketMs with a width such as "30s", "5m", "1h",
+	// "1d", "1w" or "1M". Hours and longer align to calendar boundaries in
+	// Timezone, an IANA name defaulting to UTC. "auto", or no interval and
+	// no BucketMs, picks one giving about Points buckets over the range.
+	Interval string            `json:"interval,omitempty"`
+	Points   int               `json:"points,omitempty"`
+	Timezone string            `json:"timezone,omitempty"`
+	// SplitBy returns one series per value of this field; the TopK values
+	// by volume get their own series and the rest are summed into "other".
+	SplitBy  string            `json:"splitBy,omitempty"`
+	TopK     int               `json:"topK,omitempty"`
+	// From and To limit the timeline to [From, To) and set the range that
+	// empty buckets are zero-filled over. Zero means unbounded.
+	From     int64             `json:"from,omitempty"`
+	To       int64             `json:"to,omitempty"`
+	// Metrics are computed per bucket and series; the default is a count.
+	Metrics  []TimelineMetric  `json:"metrics,omitempty"`
+}
+
+// TimelineMetric is an aggregation computed per timeline bucket.
+type TimelineMetric struct {
+	Aggregation
+	// Rate divides the value by the bucket width in seconds.
+	Rate bool `json:"rate,omitempty"`
 }
 
+// TimelinePoint counts the records in a bucket. Value is the metric of a
+// metric series; it is nil for buckets where the metric is undefined, such
+// as an average over no records.
 type TimelinePoint struct {
-	BucketStart int64 `json:"bucketStart"`
-	Count       int64 `json:"count"`
+	BucketStart int64    `json:"bucketStart"`
+	Count       int64    `json:"count"`
+	Value       *float64 `json:"value,omitempty"`
+}
+
+// TimelineSeries is one series of a timeline: a metric for one value of the
+// split field. Name is the value, or the metric when the timeline is not
+// split. Other marks the series holding values outside the top K, including
+// missing ones.
+type TimelineSeries struct {
+	Name   string          `json:"name"`
+	Metric string          `json:"metric"`
+	Value  interface{}     `json:"value,omitempty"`
+	Other  bool            `json:"other,omitempty"`
+	Total  int64           `json:"total"`
+	Points []TimelinePoint `json:"points"`
+}
+
+// TimelineResult holds series sharing the same buckets, which cover
+// [From, To) with no gaps. Interval and Timezone are the effective bucket;
+// BucketMs is its nominal width, as calendar months and days vary.
+type TimelineResult struct {
+	BucketMs int64            `json:"bucketMs"`
+	Interval string           `json:"interval"`
+	Timezone string           `json:"timezone"`
+	From     int64            `json:"from"`
+	To       int64            `json:"to"`
+	Series   []TimelineSeries `json:"series"`
+}
+
+// RecordContext is a record with its neighbours from the same source in
+// file order. MoreBefore and MoreAfter report whether the source continues
+// past Before[0] and After[len(After)-1].
+type RecordContext struct {
+	Record     LogRecord   `json:"record"`
+	Before     []LogRecord `json:"before"`
+	After      []LogRecord `json:"after"`
+	MoreBefore bool        `json:"moreBefore"`
+	MoreAfter  bool        `json:"moreAfter"`
+}
+
+// Correlation is every record sharing an ID field value, in time order,
+// with the span of the request per service. Truncated is set when Records
+// holds only the earliest of more matching records.
+type Correlation struct {
+	Field      string        `json:"field"`
+	Value      interface{}   `json:"value"`
+	Start      int64         `json:"start"`
+	End        int64         `json:"end"`
+	DurationMs int64         `json:"durationMs"`
+	Errors     int           `json:"errors"`
+	Records    []LogRecord   `json:"records"`
+	Services   []ServiceSpan `json:"services"`
+	Truncated  bool          `json:"truncated,omitempty"`
+}
+
+// ServiceSpan is the part of a correlated request seen in one service.
+type ServiceSpan struct {
+	Service    string `json:"service"`
+	FirstSeen  int64  `json:"firstSeen"`
+	LastSeen   int64  `json:"lastSeen"`
+	DurationMs int64  `json:"durationMs"`
+	Records    int    `json:"records"`
+	Errors     int    `json:"errors"`
+}
+
+// TraceSummary describes one trace found in the records.
+type TraceSummary struct {
+	TraceID    string  `json:"traceId"`
+	Start      int64   `json:"start"`
+	End        int64   `json:"end"`
+	Records    int64   `json:"records"`
+	Spans      int64   `json:"spans"`
+	ErrorRatio float64 `json:"errorRatio"`
+}
+
+// Trace is a span tree assembled from the records of one trace. Spans are
+// in waterfall order: depth first, children by start time, with orphaned
+// spans, whose parent was not logged, as extra roots.
+type Trace struct {
+	TraceID    string      `json:"traceId"`
+	Start      int64       `json:"start"`
+	End        int64       `json:"end"`
+	DurationMs int64       `json:"durationMs"`
+	Spans      []TraceSpan `json:"spans"`
+	Errors     int         `json:"errors"`
+	Orphans    int         `json:"orphans"`
+	Truncated  bool        `json:"truncated,omitempty"`
+}
+
+// TraceSpan is one span of a Trace. OffsetMs is its start relative to the
+// trace and SelfTimeMs the part of its duration not covered by children.
+// Critical marks spans on the critical path and Slow the slowest spans by
+// self time.
+type TraceSpan struct {
+	SpanID     string   `json:"spanId"`
+	ParentID   string   `json:"parentId,omitempty"`
+	Name       string   `json:"name"`
+	Service    string   `json:"service,omitempty"`
+	Depth      int      `json:"depth"`
+	Start      int64    `json:"start"`
+	OffsetMs   int64    `json:"offsetMs"`
+	DurationMs int64    `json:"durationMs"`
+	SelfTimeMs int64    `json:"selfTimeMs"`
+	Critical   bool     `json:"critical,omitempty"`
+	Slow       bool     `json:"slow,omitempty"`
+	Error      bool     `json:"error,omitempty"`
+	Orphan     bool     `json:"orphan,omitempty"`
+	RecordIDs  []string `json:"recordIds"`
+}
+
+// QueryHistoryEntry is one executed query. Error is set when it failed.
+type QueryHistoryEntry struct {
+	ID          int64  `json:"id"`
+	Query       Query  `json:"query"`
+	ExecutedAt  int64  `json:"executedAt"`
+	DurationMs  int64  `json:"durationMs"`
+	ResultCount int64  `json:"resultCount"`
+	Error       string `json:"error,omitempty"`
+}
+
+// SavedQuery is a named query kept for reuse. Names are unique.
+type SavedQuery struct {
+	ID          int64    `json:"id,omitempty"`
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags"`
+	Query       Query    `json:"query"`
+	CreatedAt   int64    `json:"createdAt,omitempty"`
+	UpdatedAt   int64    `json:"updatedAt,omitempty"`
+}
+
+// AnomalyRequest scans the series of Timeline for buckets deviating from
+// the expected volume or metric value.
+type AnomalyRequest struct {
+	Timeline  TimelineRequest `json:"timeline"`
+	// Threshold is the robust z-score a bucket must reach; default 3.5.
+	Threshold float64         `json:"threshold,omitempty"`
+	// Season is the number of buckets per seasonal period, such as 24 for
+	// hourly buckets with a daily pattern. Buckets are then compared with
+	// the same phase of other periods instead of the whole series.
+	Season    int             `json:"season,omitempty"`
+	Limit     int             `json:"limit,omitempty"`
+}
+
+type AnomalySeverity string
+
+const (
+	SeverityLow    AnomalySeverity = "low"
+	SeverityMedium AnomalySeverity = "medium"
+	SeverityHigh   AnomalySeverity = "high"
+)
+
+// Anomaly is a run of consecutive deviating buckets in [Start, End) of one
+// timeline series. Peak is the bucket with the largest Score, the robust
+// z-score; Observed and Expected are its value and baseline.
+type Anomaly struct {
+	Field     string          `json:"field,omitempty"`
+	Series    string          `json:"series"`
+	Metric    string          `json:"metric"`
+	Start     int64           `json:"start"`
+	End       int64           `json:"end"`
+	Peak      int64           `json:"peak"`
+	Observed  float64         `json:"observed"`
+	Expected  float64         `json:"expected"`
+	Score     float64         `json:"score"`
+	Spike     bool            `json:"spike"`
+	Severity  AnomalySeverity `json:"severity"`
 }
 
 type Report struct {
@@ -141,3 +517,36 @@ type Report struct {
 	Took        int64          `json:"took"`
 	Records     []LogRecord    `json:"records"`
 }
+
+// PartitionGranularity selects how records are split into per-period tables.
+type PartitionGranularity string
+
+const (
+	PartitionNone PartitionGranularity = "none"
+	PartitionDay  PartitionGranularity = "day"
+	PartitionHour PartitionGranularity = "hour"
+)
+
+type PartitionInfo struct {
+	Name  string `json:"name"`
+	Start int64  `json:"start"`
+	End   int64  `json:"end"`
+	Rows  int64  `json:"rows"`
+}
+
+// CompressionMode selects which record columns are stored compressed.
+type CompressionMode string
+
+const (
+	CompressionNone      CompressionMode = "none"
+	CompressionRaw       CompressionMode = "raw"
+	CompressionRawFields CompressionMode = "raw+fields"
+)
+
+type CompressionStats struct {
+	Mode           CompressionMode `json:"mode"`
+	StoredBytes    int64           `json:"storedBytes"`
+	OriginalBytes  int64           `json:"originalBytes"`
+	Ratio          float64         `json:"ratio"`
+	CompressedRows int64           `json:"compressedRows"`
+}
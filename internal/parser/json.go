@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"bufio"
 	"context"
 	"encoding/json"
 	"fmt"
@@ -30,10 +29,7 @@ func (p *JSONParser) Parse(ctx context.Context, r io.Reader) (<-chan domain.LogR
 	
 	go func() {
 		defer close(records)
-		scanner := bufio.NewScanner(r)
-		
-		buf := make([]byte, 0, 64*1024)
-		scanner.Buffer(buf, 10*1024*1024)
+		scanner := newLineScanner(r)
 		
 		lineNum := 0
 		for scanner.Scan() {
@@ -52,6 +48,7 @@ func (p *JSONParser) Parse(ctx context.Context, r io.Reader) (<-chan domain.LogR
 					log.Printf("Error parsing line %d: %v", lineNum, err)
 					continue
 				}
+				record.Offset = scanner.Offset()
 				
 				select {
 				case records <- *record:
@@ -78,6 +75,7 @@ func (p *JSONParser) parseJSONLine(line string, lineNum int) (*domain.LogRecord,
 	record := &domain.LogRecord{
 		ID:     p.generateID(jsonData, lineNum),
 		Raw:    line,
+		Line:   int64(lineNum),
 		Fields: make(map[string]interface{}),
 	}
 	
@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"io"
@@ -64,15 +63,28 @@ func (p *PlainParser) Config() domain.ParserConfig {
 	return p.config
 }
 
+// Parse emits one record per line. An indented line, such as a stack frame,
+// continues the record above it and is appended to its Raw.
 func (p *PlainParser) Parse(ctx context.Context, r io.Reader) (<-chan domain.LogRecord, error) {
 	records := make(chan domain.LogRecord, 1000)
 	
 	go func() {
 		defer close(records)
-		scanner := bufio.NewScanner(r)
+		scanner := newLineScanner(r)
 		
-		buf := make([]byte, 0, 64*1024)
-		scanner.Buffer(buf, 10*1024*1024)
+		var pending *domain.LogRecord
+		flush := func() bool {
+			if pending == nil {
+				return true
+			}
+			select {
+			case records <- *pending:
+				pending = nil
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
 		
 		lineNum := 0
 		for scanner.Scan() {
@@ -83,26 +95,33 @@ func (p *PlainParser) Parse(ctx context.Context, r io.Reader) (<-chan domain.Log
 			default:
 				line := scanner.Text()
 				if strings.TrimSpace(line) == "" {
+					if !flush() {
+						return
+					}
 					continue
 				}
+				if pending != nil && (line[0] == ' ' || line[0] == '\t') {
+					pending.Raw += "\n" + line
+					continue
+				}
+				if !flush() {
+					return
+				}
 				
 				record, err := p.parseLine(line, lineNum)
 				if err != nil {
 					log.Printf("Error parsing line %d: %v", lineNum, err)
 					continue
 				}
-				
-				select {
-				case records <- *record:
-				case <-ctx.Done():
-					return
-				}
+				record.Offset = scanner.Offset()
+				pending = record
 			}
 		}
 		
 		if err := scanner.Err(); err != nil {
 			log.Printf("Scanner error: %v", err)
 		}
+		flush()
 	}()
 	
 	return records, nil
@@ -116,6 +135,7 @@ func (p *PlainParser) parseLine(line string, lineNum int) (*domain.LogRecord, er
 	record := &domain.LogRecord{
 		ID:      fmt.Sprintf("%s_line_%d", idPrefix, lineNum),
 		Raw:     line,
+		Line:    int64(lineNum),
 		Fields:  make(map[string]interface{}),
 	}
 	
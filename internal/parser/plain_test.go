@@ -149,3 +149,34 @@ func TestPlainParser_ContextCancel(t *testing.T) {
 		t.Errorf("expected 0 records after cancel, got %d", count)
 	}
 }
+
+func TestPlainParser_ContinuationLines(t *testing.T) {
+	input := "2024-01-15 10:30:45 [ERROR] [api] request failed\n" +
+		"\tat handler.serve(handler.go:42)\n" +
+		"    at main.run(main.go:7)\n" +
+		"2024-01-15 10:30:46 [INFO] [api] recovered\n"
+
+	parser := NewPlainParser(domain.ParserConfig{Type: domain.ParserPlain})
+	records, err := parser.Parse(context.Background(), strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var results []domain.LogRecord
+	for r := range records {
+		results = append(results, r)
+	}
+
+	if len(results) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(results))
+	}
+	if want := "2024-01-15 10:30:45 [ERROR] [api] request failed\n\tat handler.serve(handler.go:42)\n    at main.run(main.go:7)"; results[0].Raw != want {
+		t.Errorf("expected continuation lines in Raw, got %q", results[0].Raw)
+	}
+	if results[0].Level != "ERROR" || results[0].Line != 1 {
+		t.Errorf("expected the first line's level and number, got %s on line %d", results[0].Level, results[0].Line)
+	}
+	if results[1].Line != 4 || results[1].Offset != int64(strings.Index(input, "2024-01-15 10:30:46")) {
+		t.Errorf("expected the next record on line 4, got line %d offset %d", results[1].Line, results[1].Offset)
+	}
+}
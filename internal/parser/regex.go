@@ -1,7 +1,6 @@
 package parser
 
 import (
-	"bufio"
 	"context"
 	"fmt"
 	"io"
@@ -43,10 +42,7 @@ func (p *RegexParser) Parse(ctx context.Context, r io.Reader) (<-chan domain.Log
 	
 	go func() {
 		defer close(records)
-		scanner := bufio.NewScanner(r)
-		
-		buf := make([]byte, 0, 64*1024)
-		scanner.Buffer(buf, 10*1024*1024)
+		scanner := newLineScanner(r)
 		
 		lineNum := 0
 		for scanner.Scan() {
@@ -65,6 +61,7 @@ func (p *RegexParser) Parse(ctx context.Context, r io.Reader) (<-chan domain.Log
 					log.Printf("Error parsing line %d: %v", lineNum, err)
 					continue
 				}
+				record.Offset = scanner.Offset()
 				
 				select {
 				case records <- *record:
@@ -91,6 +88,7 @@ func (p *RegexParser) parseLine(line string, lineNum int) (*domain.LogRecord, er
 	record := &domain.LogRecord{
 		ID:     fmt.Sprintf("%s_regex_%d", p.config.IDPrefix, lineNum),
 		Raw:    line,
+		Line:   int64(lineNum),
 		Fields: make(map[string]interface{}),
 	}
 	
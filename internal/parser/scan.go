@@ -0,0 +1,32 @@
+package parser
+
+import (
+	"bufio"
+	"io"
+)
+
+// lineScanner is a bufio.Scanner over lines that also reports where in the
+// input each line starts, so records can carry their byte offset.
+type lineScanner struct {
+	*bufio.Scanner
+	offset, next int64
+}
+
+func newLineScanner(r io.Reader) *lineScanner {
+	s := &lineScanner{Scanner: bufio.NewScanner(r)}
+	s.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	s.Split(func(data []byte, atEOF bool) (int, []byte, error) {
+		advance, token, err := bufio.ScanLines(data, atEOF)
+		if token != nil {
+			s.offset = s.next
+		}
+		s.next += int64(advance)
+		return advance, token, err
+	})
+	return s
+}
+
+// Offset returns the byte offset of the line last returned by Scan.
+func (s *lineScanner) Offset() int64 {
+	return s.offset
+}
@@ -0,0 +1,202 @@
+package patterns
+
+import (
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"LogLens/internal/domain"
+)
+
+// Drain defaults, following He et al., "Drain: An Online Log Parsing
+// Approach with Fixed Depth Tree".
+const (
+	DefaultDepth        = 4
+	DefaultSimilarity   = 0.4
+	DefaultMaxChildren  = 100
+	maxTokensPerMessage = 200
+)
+
+type Options struct {
+	// Depth is the parse tree depth including the root and length layers;
+	// Depth-2 leading tokens route a message to its clusters.
+	Depth int
+	// Similarity is the fraction of matching tokens needed to join a
+	// cluster.
+	Similarity float64
+	// MaxChildren caps the children of a routing node; further tokens share
+	// a wildcard child.
+	MaxChildren int
+}
+
+type node struct {
+	children map[string]*node
+	clusters []*cluster
+}
+
+type cluster struct {
+	tokens    []string
+	count     int
+	firstSeen int64
+	lastSeen  int64
+	sample    domain.LogRecord
+	levels    map[string]int
+	services  map[string]int
+}
+
+// Miner clusters messages online. It is not safe for concurrent use.
+type Miner struct {
+	opts     Options
+	root     map[int]*node
+	clusters []*cluster
+}
+
+func NewMiner(opts Options) *Miner {
+	if opts.Depth < 3 {
+		opts.Depth = DefaultDepth
+	}
+	if opts.Similarity <= 0 {
+		opts.Similarity = DefaultSimilarity
+	}
+	if opts.MaxChildren <= 0 {
+		opts.MaxChildren = DefaultMaxChildren
+	}
+	return &Miner{opts: opts, root: make(map[int]*node)}
+}
+
+// Add assigns record to a cluster by its message and returns the cluster's
+// current template.
+func (m *Miner) Add(record domain.LogRecord) string {
+	tokens := Tokenize(record.Message)
+	if len(tokens) > maxTokensPerMessage {
+		tokens = append(tokens[:maxTokensPerMessage-1], Wildcard)
+	}
+
+	leaf := m.route(tokens)
+	c := m.bestMatch(leaf.clusters, tokens)
+	if c == nil {
+		c = &cluster{
+			tokens:    tokens,
+			firstSeen: record.Timestamp,
+			lastSeen:  record.Timestamp,
+			sample:    record,
+			levels:    make(map[string]int),
+			services:  make(map[string]int),
+		}
+		leaf.clusters = append(leaf.clusters, c)
+		m.clusters = append(m.clusters, c)
+	} else {
+		for i, t := range tokens {
+			if c.tokens[i] != t {
+				c.tokens[i] = Wildcard
+			}
+		}
+	}
+
+	c.count++
+	c.firstSeen = min(c.firstSeen, record.Timestamp)
+	c.lastSeen = max(c.lastSeen, record.Timestamp)
+	c.levels[record.Level]++
+	c.services[record.Service]++
+	return strings.Join(c.tokens, " ")
+}
+
+// route walks the length layer and the leading tokens to a leaf, creating
+// nodes on the way. Tokens that are wildcards or contain digits share the
+// wildcard child, as do tokens beyond MaxChildren.
+func (m *Miner) route(tokens []string) *node {
+	n, ok := m.root[len(tokens)]
+	if !ok {
+		n = &node{children: make(map[string]*node)}
+		m.root[len(tokens)] = n
+	}
+
+	for i := 0; i < m.opts.Depth-2 && i < len(tokens); i++ {
+		key := tokens[i]
+		if strings.Contains(key, Wildcard) || strings.ContainsAny(key, "0123456789") {
+			key = Wildcard
+		}
+		child, ok := n.children[key]
+		if !ok {
+			if len(n.children) >= m.opts.MaxChildren {
+				key = Wildcard
+			}
+			if child, ok = n.children[key]; !ok {
+				child = &node{children: make(map[string]*node)}
+				n.children[key] = child
+			}
+		}
+		n = child
+	}
+	return n
+}
+
+func (m *Miner) bestMatch(clusters []*cluster, tokens []string) *cluster {
+	var best *cluster
+	bestSim, bestWildcards := -1.0, -1
+	for _, c := range clusters {
+		same, wildcards := 0, 0
+		for i, t := range c.tokens {
+			switch {
+			case t == Wildcard:
+				wildcards++
+			case t == tokens[i]:
+				same++
+			}
+		}
+		sim := 1.0
+		if len(tokens) > 0 {
+			sim = float64(same) / float64(len(tokens))
+		}
+		if sim > bestSim || (sim == bestSim && wildcards > bestWildcards) {
+			best, bestSim, bestWildcards = c, sim, wildcards
+		}
+	}
+	if best == nil || bestSim < m.opts.Similarity {
+		return nil
+	}
+	return best
+}
+
+// Groups returns the clusters as log groups, largest first.
+func (m *Miner) Groups() []domain.LogGroup {
+	groups := make([]domain.LogGroup, 0, len(m.clusters))
+	for _, c := range m.clusters {
+		pattern := strings.Join(c.tokens, " ")
+		groups = append(groups, domain.LogGroup{
+			ID:        PatternID(pattern),
+			Pattern:   pattern,
+			Count:     c.count,
+			FirstSeen: c.firstSeen,
+			LastSeen:  c.lastSeen,
+			Sample:    c.sample,
+			Level:     mostCommon(c.levels),
+			Service:   mostCommon(c.services),
+		})
+	}
+	sort.SliceStable(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].Pattern < groups[j].Pattern
+	})
+	return groups
+}
+
+// PatternID is a stable identifier for a template.
+func PatternID(pattern string) string {
+	h := fnv.New64a()
+	h.Write([]byte(pattern))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+func mostCommon(counts map[string]int) string {
+	best, bestCount := "", 0
+	for k, n := range counts {
+		if n > bestCount || (n == bestCount && k < best) {
+			best, bestCount = k, n
+		}
+	}
+	return best
+}
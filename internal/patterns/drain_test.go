@@ -0,0 +1,93 @@
+package patterns
+
+import (
+	"fmt"
+	"regexp"
+	"testing"
+
+	"LogLens/internal/domain"
+)
+
+func TestMiner_ClustersTemplates(t *testing.T) {
+	miner := NewMiner(Options{})
+	var records []domain.LogRecord
+	for i := 0; i < 300; i++ {
+		var r domain.LogRecord
+		switch i % 3 {
+		case 0:
+			r = domain.LogRecord{Level: "INFO", Service: "api", Message: fmt.Sprintf("GET /api/v1/orders/%d completed in %dms", i, i%97)}
+		case 1:
+			r = domain.LogRecord{Level: "ERROR", Service: "db", Message: fmt.Sprintf("Connection refused to 10.0.%d.1:5432", i%250)}
+		default:
+			user := []string{"alice", "bob", "carol"}[i%9/3]
+			r = domain.LogRecord{Level: "INFO", Service: "auth", Message: fmt.Sprintf("login succeeded for %s", user)}
+		}
+		r.ID = fmt.Sprintf("r%d", i)
+		r.Timestamp = int64(i) * 1000
+		records = append(records, r)
+		miner.Add(r)
+	}
+	miner.Add(domain.LogRecord{ID: "odd", Timestamp: 5, Level: "WARN", Message: "disk almost full"})
+
+	groups := miner.Groups()
+	if len(groups) != 4 {
+		for _, g := range groups {
+			t.Logf("%d %q", g.Count, g.Pattern)
+		}
+		t.Fatalf("expected 4 groups, got %d", len(groups))
+	}
+
+	want := map[string]struct {
+		count          int
+		level, service string
+		first, last    int64
+	}{
+		"GET <*> completed in <*>":  {100, "INFO", "api", 0, 297000},
+		"Connection refused to <*>": {100, "ERROR", "db", 1000, 298000},
+		"login succeeded for <*>":   {100, "INFO", "auth", 2000, 299000},
+		"disk almost full":          {1, "WARN", "", 5, 5},
+	}
+	for _, g := range groups {
+		w, ok := want[g.Pattern]
+		if !ok {
+			t.Errorf("unexpected pattern %q", g.Pattern)
+			continue
+		}
+		if g.Count != w.count || g.Level != w.level || g.Service != w.service || g.FirstSeen != w.first || g.LastSeen != w.last {
+			t.Errorf("%q: got %+v", g.Pattern, g)
+		}
+		if g.ID != PatternID(g.Pattern) || g.Sample.ID == "" {
+			t.Errorf("%q: missing id or sample", g.Pattern)
+		}
+	}
+	if groups[3].Pattern != "disk almost full" {
+		t.Errorf("expected groups sorted by count, got %q last", groups[3].Pattern)
+	}
+
+	// Every record matches the regexp of its own pattern.
+	for _, g := range groups[:3] {
+		re := regexp.MustCompile(Regexp(g.Pattern))
+		matched := 0
+		for _, r := range records {
+			if re.MatchString(r.Message) {
+				matched++
+			}
+		}
+		if matched != g.Count {
+			t.Errorf("%q: regexp matched %d records, want %d", g.Pattern, matched, g.Count)
+		}
+	}
+}
+
+func TestMiner_TruncatedMessagesMatchTheirRegexp(t *testing.T) {
+	miner := NewMiner(Options{})
+	message := "dump"
+	for i := 0; i < 2*maxTokensPerMessage; i++ {
+		message += fmt.Sprintf(" k%d", i)
+	}
+	pattern := miner.Add(domain.LogRecord{ID: "1", Message: message})
+
+	if !regexp.MustCompile(Regexp(pattern)).MatchString(message) {
+		t.Errorf("expected the template of a truncated message to match it")
+	}
+}
@@ -0,0 +1,78 @@
+// Package patterns groups log messages into templates with a Drain-style
+// parse tree. Variable parts of a message are masked before clustering and
+// show up as <*> in the resulting template.
+package patterns
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Wildcard marks a variable part of a template.
+const Wildcard = "<*>"
+
+// masks are applied in order, so the more specific shapes win over plain
+// numbers. A mask with a keep func leaves matches it accepts untouched.
+var masks = []struct {
+	re   *regexp.Regexp
+	keep func(string) bool
+}{
+	// UUIDs
+	{re: regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`)},
+	// IPv4 addresses with an optional port
+	{re: regexp.MustCompile(`\b\d{1,3}(?:\.\d{1,3}){3}(?::\d+)?\b`)},
+	// Paths, at least two segments deep
+	{re: regexp.MustCompile(`(?:[A-Za-z]:)?(?:/[\w.\-~%]+){2,}/?`)},
+	// 0x-prefixed hex, or hex strings of 8+ characters with a digit, so
+	// words such as "deadbeef" or "added" survive
+	{
+		re: regexp.MustCompile(`\b0[xX][0-9a-fA-F]+\b|\b[0-9a-fA-F]{8,}\b`),
+		keep: func(m string) bool {
+			return !strings.ContainsAny(m, "0123456789")
+		},
+	},
+	// Numbers, including decimals, signs and unit suffixes such as 250ms
+	{re: regexp.MustCompile(`[-+]?\b\d+(?:\.\d+)?(?:[a-zA-Z]{1,3})?\b`)},
+}
+
+// Mask replaces variable parts of message with Wildcard.
+func Mask(message string) string {
+	for _, m := range masks {
+		if m.keep == nil {
+			message = m.re.ReplaceAllString(message, Wildcard)
+			continue
+		}
+		message = m.re.ReplaceAllStringFunc(message, func(s string) string {
+			if m.keep(s) {
+				return s
+			}
+			return Wildcard
+		})
+	}
+	return message
+}
+
+// Tokenize masks message and splits it on whitespace.
+func Tokenize(message string) []string {
+	return strings.Fields(Mask(message))
+}
+
+// Regexp returns a regular expression matching the messages a template was
+// mined from: literal tokens must match exactly, wildcards match any
+// non-blank text. The wildcard that ends a template of a truncated message
+// matches the rest of the line.
+func Regexp(template string) string {
+	tokens := strings.Fields(template)
+	parts := make([]string, len(tokens))
+	for i, token := range tokens {
+		pieces := strings.Split(token, Wildcard)
+		for j, p := range pieces {
+			pieces[j] = regexp.QuoteMeta(p)
+		}
+		parts[i] = strings.Join(pieces, `\S+?`)
+	}
+	if len(tokens) == maxTokensPerMessage && tokens[len(tokens)-1] == Wildcard {
+		parts[len(parts)-1] = `.*`
+	}
+	return `^\s*` + strings.Join(parts, `\s+`) + `\s*$`
+}
@@ -0,0 +1,35 @@
+package patterns
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestMask(t *testing.T) {
+	cases := map[string]string{
+		"Connection refused to 10.0.0.1:5432":                     "Connection refused to <*>",
+		"request 550e8400-e29b-41d4-a716-446655440000 took 250ms": "request <*> took <*>",
+		"opened /var/log/app/current.log for reading":             "opened <*> for reading",
+		"ptr 0x7ffe3a and hash a3f9c2e1b7d4 but deadbeef stays":   "ptr <*> and hash <*> but deadbeef stays",
+		"retry 3 of 5, backoff -1.5s, user42 added":               "retry <*> of <*>, backoff <*>, user42 added",
+	}
+	for input, want := range cases {
+		if got := Mask(input); got != want {
+			t.Errorf("Mask(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestRegexp(t *testing.T) {
+	re := regexp.MustCompile(Regexp("user <*> logged in from <*> (id=<*>)"))
+	for msg, want := range map[string]bool{
+		"user 42 logged in from 10.0.0.1 (id=abc)":  true,
+		"  user bob logged in from host (id=7)":     true,
+		"user 42 logged out from 10.0.0.1 (id=abc)": false,
+		"user 42 logged in from 10.0.0.1":           false,
+	} {
+		if got := re.MatchString(msg); got != want {
+			t.Errorf("%q: expected match=%v", msg, want)
+		}
+	}
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"LogLens/internal/domain"
+	"LogLens/internal/query/lang"
 )
 
 type QueryEngine struct {
@@ -19,6 +21,10 @@ func NewQueryEngine(storage domain.Storage) *QueryEngine {
 }
 
 func (e *QueryEngine) Execute(ctx context.Context, query domain.Query) (*domain.QueryResult, error) {
+	query, err := lang.CompileQuery(query, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("invalid query: %w", err)
+	}
 	if err := e.validateQuery(query); err != nil {
 		return nil, fmt.Errorf("invalid query: %w", err)
 	}
@@ -36,6 +42,20 @@ func (e *QueryEngine) Execute(ctx context.Context, query domain.Query) (*domain.
 		result.Aggregations = aggregations
 	}
 
+	if len(query.GroupBy) > 0 || query.BucketMs > 0 {
+		grouper, ok := e.storage.(interface {
+			AggregateGroups(context.Context, domain.Query) ([]domain.GroupBucket, error)
+		})
+		if !ok {
+			return nil, fmt.Errorf("grouped aggregation not supported by storage")
+		}
+		groups, err := grouper.AggregateGroups(ctx, query)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute grouped aggregations: %w", err)
+		}
+		result.Groups = groups
+	}
+
 	return result, nil
 }
 
@@ -45,10 +65,21 @@ func (e *QueryEngine) Explain(query domain.Query) (string, error) {
 	explanation.WriteString("Query Execution Plan:\n")
 	explanation.WriteString("====================\n\n")
 	
+	if query.Text != "" {
+		node, err := lang.Parse(query.Text)
+		if err != nil {
+			return "", err
+		}
+		explanation.WriteString(fmt.Sprintf("Text: %s\n\n", lang.Format(node)))
+		if query, err = lang.CompileQuery(query, time.Now()); err != nil {
+			return "", err
+		}
+	}
+	
 	if len(query.Filters) > 0 {
 		explanation.WriteString("Filters:\n")
 		for i, filter := range query.Filters {
-			explanation.WriteString(fmt.Sprintf("  %d. %s %s %v\n", i+1, filter.Field, filter.Type, filter.Value))
+			explainFilter(&explanation, fmt.Sprintf("  %d. ", i+1), "     ", filter)
 		}
 		explanation.WriteString("\n")
 	}
@@ -66,6 +97,31 @@ func (e *QueryEngine) Explain(query domain.Query) (string, error) {
 		if query.Offset > 0 {
 			explanation.WriteString(fmt.Sprintf("Offset: %d\n", query.Offset))
 		}
+		if query.Cursor != "" {
+			explanation.WriteString("Cursor: keyset\n")
+		}
+		if query.CountMode != "" {
+			explanation.WriteString(fmt.Sprintf("Count: %s\n", query.CountMode))
+		}
+		explanation.WriteString("\n")
+	}
+	
+	if len(query.GroupBy) > 0 || query.BucketMs > 0 {
+		groups := append([]string(nil), query.GroupBy...)
+		if query.BucketMs > 0 {
+			groups = append(groups, fmt.Sprintf("timestamp bucket %dms", query.BucketMs))
+		}
+		explanation.WriteString(fmt.Sprintf("Group by: %s\n", strings.Join(groups, ", ")))
+		if query.GroupSort != "" {
+			direction := "ASC"
+			if query.GroupSortDesc {
+				direction = "DESC"
+			}
+			explanation.WriteString(fmt.Sprintf("Group sort: %s %s\n", query.GroupSort, direction))
+		}
+		if query.GroupLimit > 0 {
+			explanation.WriteString(fmt.Sprintf("Group limit: %d\n", query.GroupLimit))
+		}
 		explanation.WriteString("\n")
 	}
 	
@@ -88,8 +144,90 @@ func (e *QueryEngine) Explain(query domain.Query) (string, error) {
 	return explanation.String(), nil
 }
 
+// explainFilter writes filter on one line, with group children indented
+// beneath it.
+func explainFilter(b *strings.Builder, prefix, indent string, filter domain.FilterCondition) {
+	if !filter.IsGroup() {
+		b.WriteString(fmt.Sprintf("%s%s %s %v\n", prefix, filter.Field, filter.Type, filter.Value))
+		return
+	}
+	b.WriteString(fmt.Sprintf("%s%s\n", prefix, strings.ToUpper(string(filter.Type))))
+	for _, child := range filter.Children {
+		explainFilter(b, indent+"- ", indent+"  ", child)
+	}
+}
+
 func (e *QueryEngine) validateQuery(query domain.Query) error {
-	for _, filter := range query.Filters {
+	if err := validateFilters(query.Filters); err != nil {
+		return err
+	}
+	
+	for _, agg := range query.Aggregations {
+		validFunctions := []string{"count", "avg", "sum", "min", "max", "p50", "p90", "p95", "p99", "percentile", "stddev", "histogram", "cardinality", "error_ratio"}
+		valid := false
+		for _, fn := range validFunctions {
+			if agg.Function == fn {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid aggregation function: %s", agg.Function)
+		}
+		switch agg.Function {
+		case "percentile":
+			if agg.Percentile <= 0 || agg.Percentile > 100 {
+				return fmt.Errorf("percentile must be in (0, 100]")
+			}
+		case "histogram":
+			if agg.Interval <= 0 && agg.LogBase <= 1 {
+				return fmt.Errorf("histogram requires an interval > 0 or a log base > 1")
+			}
+		}
+	}
+	
+	if query.Limit < 0 {
+		return fmt.Errorf("limit cannot be negative")
+	}
+	if query.Offset < 0 {
+		return fmt.Errorf("offset cannot be negative")
+	}
+	for _, field := range query.GroupBy {
+		if field == "" {
+			return fmt.Errorf("group field cannot be empty")
+		}
+	}
+	if query.BucketMs < 0 {
+		return fmt.Errorf("bucketMs cannot be negative")
+	}
+	if query.GroupLimit < 0 {
+		return fmt.Errorf("group limit cannot be negative")
+	}
+	if query.Cursor != "" && query.Offset > 0 {
+		return fmt.Errorf("cursor and offset cannot be combined")
+	}
+	
+	switch query.CountMode {
+	case "", domain.CountExact, domain.CountSkip, domain.CountCapped:
+	default:
+		return fmt.Errorf("invalid count mode: %s", query.CountMode)
+	}
+	
+	return nil
+}
+
+func validateFilters(filters []domain.FilterCondition) error {
+	for _, filter := range filters {
+		if filter.IsGroup() {
+			if filter.Type == domain.FilterNot && len(filter.Children) != 1 {
+				return fmt.Errorf("not filter requires exactly one child")
+			}
+			if err := validateFilters(filter.Children); err != nil {
+				return err
+			}
+			continue
+		}
+		
 		if filter.Field == "" {
 			return fmt.Errorf("filter field cannot be empty")
 		}
@@ -113,26 +251,5 @@ func (e *QueryEngine) validateQuery(query domain.Query) error {
 		}
 	}
 	
-	for _, agg := range query.Aggregations {
-		validFunctions := []string{"count", "avg", "sum", "min", "max"}
-		valid := false
-		for _, fn := range validFunctions {
-			if agg.Function == fn {
-				valid = true
-				break
-			}
-		}
-		if !valid {
-			return fmt.Errorf("invalid aggregation function: %s", agg.Function)
-		}
-	}
-	
-	if query.Limit < 0 {
-		return fmt.Errorf("limit cannot be negative")
-	}
-	if query.Offset < 0 {
-		return fmt.Errorf("offset cannot be negative")
-	}
-	
 	return nil
 }
@@ -0,0 +1,37 @@
+package query
+
+import (
+	"strings"
+	"testing"
+
+	"LogLens/internal/domain"
+)
+
+func TestQueryEngine_ExplainText(t *testing.T) {
+	engine := NewQueryEngine(nil)
+	plan, err := engine.Explain(domain.Query{
+		Text:  `(level:ERROR OR level:FATAL)  AND NOT service:healthcheck`,
+		Limit: 50,
+	})
+	if err != nil {
+		t.Fatalf("Explain failed: %v", err)
+	}
+
+	for _, want := range []string{
+		"Text: (level:ERROR OR level:FATAL) -service:healthcheck\n",
+		"  1. OR\n     - level equality ERROR\n     - level equality FATAL\n",
+		"  2. NOT\n     - service equality healthcheck\n",
+	} {
+		if !strings.Contains(plan, want) {
+			t.Errorf("expected plan to contain %q, got:\n%s", want, plan)
+		}
+	}
+}
+
+func TestQueryEngine_ExplainTextSyntaxError(t *testing.T) {
+	engine := NewQueryEngine(nil)
+	_, err := engine.Explain(domain.Query{Text: `level:ERROR (service:api`})
+	if err == nil || !strings.Contains(err.Error(), "column 13") {
+		t.Errorf("expected syntax error at column 13, got %v", err)
+	}
+}
@@ -1,6 +1,7 @@
 package query
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
@@ -22,6 +23,15 @@ func (e *FilterEngine) BuildFilter(conditions []domain.FilterCondition) (domain.
 		return e.buildSingleFilter(conditions[0])
 	}
 	
+	filters, err := e.buildFilters(conditions)
+	if err != nil {
+		return nil, err
+	}
+	
+	return &AndFilter{filters: filters}, nil
+}
+
+func (e *FilterEngine) buildFilters(conditions []domain.FilterCondition) ([]domain.Filter, error) {
 	filters := make([]domain.Filter, len(conditions))
 	for i, condition := range conditions {
 		filter, err := e.buildSingleFilter(condition)
@@ -30,12 +40,31 @@ func (e *FilterEngine) BuildFilter(conditions []domain.FilterCondition) (domain.
 		}
 		filters[i] = filter
 	}
-	
-	return &AndFilter{filters: filters}, nil
+	return filters, nil
 }
 
 func (e *FilterEngine) buildSingleFilter(condition domain.FilterCondition) (domain.Filter, error) {
 	switch condition.Type {
+	case domain.FilterAnd, domain.FilterOr:
+		filters, err := e.buildFilters(condition.Children)
+		if err != nil {
+			return nil, err
+		}
+		if condition.Type == domain.FilterOr {
+			return &OrFilter{filters: filters}, nil
+		}
+		return &AndFilter{filters: filters}, nil
+		
+	case domain.FilterNot:
+		if len(condition.Children) != 1 {
+			return nil, fmt.Errorf("not filter requires exactly one child, got %d", len(condition.Children))
+		}
+		filter, err := e.buildSingleFilter(condition.Children[0])
+		if err != nil {
+			return nil, err
+		}
+		return &NotFilter{filter: filter}, nil
+		
 	case domain.FilterEquality:
 		return &EqualityFilter{
 			field: condition.Field,
@@ -108,6 +137,10 @@ func getFieldValue(field string, record domain.LogRecord) interface{} {
 		return record.Service
 	case "raw":
 		return record.Raw
+	case "source":
+		return record.Source
+	case "line":
+		return record.Line
 	default:
 		if value, exists := record.Fields[field]; exists {
 			return value
@@ -289,3 +322,11 @@ func (f *OrFilter) Match(record domain.LogRecord) bool {
 	}
 	return false
 }
+
+type NotFilter struct {
+	filter domain.Filter
+}
+
+func (f *NotFilter) Match(record domain.LogRecord) bool {
+	return !f.filter.Match(record)
+}
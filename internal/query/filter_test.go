@@ -187,3 +187,44 @@ func TestCompareValues(t *testing.T) {
 		})
 	}
 }
+
+func TestFilterEngine_BooleanTree(t *testing.T) {
+	engine := NewFilterEngine()
+	conditions := []domain.FilterCondition{
+		{Type: domain.FilterOr, Children: []domain.FilterCondition{
+			{Type: domain.FilterEquality, Field: "level", Value: "ERROR"},
+			{Type: domain.FilterEquality, Field: "level", Value: "FATAL"},
+		}},
+		{Type: domain.FilterNot, Children: []domain.FilterCondition{
+			{Type: domain.FilterEquality, Field: "service", Value: "healthcheck"},
+		}},
+	}
+	filter, err := engine.BuildFilter(conditions)
+	if err != nil {
+		t.Fatalf("BuildFilter failed: %v", err)
+	}
+
+	cases := []struct {
+		level, service string
+		want           bool
+	}{
+		{"ERROR", "api", true},
+		{"FATAL", "api", true},
+		{"ERROR", "healthcheck", false},
+		{"INFO", "api", false},
+	}
+	for _, c := range cases {
+		record := domain.LogRecord{Level: c.level, Service: c.service, Fields: make(map[string]interface{})}
+		if got := filter.Match(record); got != c.want {
+			t.Errorf("%s/%s: expected %v, got %v", c.level, c.service, c.want, got)
+		}
+	}
+}
+
+func TestFilterEngine_NotRequiresOneChild(t *testing.T) {
+	engine := NewFilterEngine()
+	_, err := engine.BuildFilter([]domain.FilterCondition{{Type: domain.FilterNot}})
+	if err == nil {
+		t.Error("expected error for not filter without a child")
+	}
+}
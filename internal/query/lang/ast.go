@@ -0,0 +1,106 @@
+// Package lang implements the LogLens search bar language:
+//
+//	level:ERROR service:api -message:"health" status>=500 @timestamp>now-1h /timeout|refused/
+//
+// Terms separated by whitespace (or AND) must all match; OR binds looser than
+// AND and parentheses group. A leading - or NOT negates a term or group.
+//
+//	field:value     equality; contains for message and raw
+//	field=value     equality
+//	field!=value    exclusion
+//	field~value     contains
+//	field:/re/      regular expression
+//	field>value     also >=, < and <=
+//	word, "phrase"  message contains
+//	/re/            message matches
+//
+// A leading @ on a field name is accepted and ignored. Timestamp values may be
+// epoch milliseconds, a date, an RFC 3339 time or now with an optional offset
+// such as now-15m (units s, m, h, d, w).
+package lang
+
+import "fmt"
+
+// Node is a parsed query expression: *And, *Or, *Not or *Term.
+type Node interface {
+	Pos() int
+	node()
+}
+
+type And struct {
+	Position int
+	Terms    []Node
+}
+
+type Or struct {
+	Position int
+	Terms    []Node
+}
+
+type Not struct {
+	Position int
+	Term     Node
+}
+
+// Term is a single comparison. Field is empty for free text and bare regular
+// expressions, which apply to the message.
+type Term struct {
+	Position int
+	Field    string
+	Op       Op
+	Value    Value
+}
+
+type Op string
+
+const (
+	OpMatch    Op = ":"
+	OpEq       Op = "="
+	OpNe       Op = "!="
+	OpContains Op = "~"
+	OpGt       Op = ">"
+	OpGte      Op = ">="
+	OpLt       Op = "<"
+	OpLte      Op = "<="
+)
+
+type ValueKind int
+
+const (
+	// Word is an unquoted value; numeric words compile to numbers.
+	Word ValueKind = iota
+	// Quoted is a double-quoted value and always compiles to a string.
+	Quoted
+	// Regexp is a /pattern/ value.
+	Regexp
+)
+
+type Value struct {
+	Position int
+	Kind     ValueKind
+	Text     string
+}
+
+func (n *And) Pos() int  { return n.Position }
+func (n *Or) Pos() int   { return n.Position }
+func (n *Not) Pos() int  { return n.Position }
+func (n *Term) Pos() int { return n.Position }
+
+func (*And) node()  {}
+func (*Or) node()   {}
+func (*Not) node()  {}
+func (*Term) node() {}
+
+// SyntaxError reports a problem at a byte offset of the query text.
+type SyntaxError struct {
+	Pos int
+	Msg string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("syntax error at column %d: %s", e.Pos+1, e.Msg)
+}
+
+func errorf(pos int, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
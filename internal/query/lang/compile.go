@@ -0,0 +1,184 @@
+package lang
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+
+	"LogLens/internal/domain"
+)
+
+// textColumns are the built-in string columns. Their values never compile to
+// numbers, and ':' on the free-text ones means contains.
+var textColumns = map[string]bool{
+	"id": true, "level": true, "message": true, "service": true, "raw": true, "source": true,
+}
+
+func isFreeText(field string) bool {
+	f := strings.ToLower(field)
+	return f == "message" || f == "raw"
+}
+
+// ParseFilters parses input and compiles it with now as the reference for
+// relative times.
+func ParseFilters(input string, now time.Time) ([]domain.FilterCondition, error) {
+	n, err := Parse(input)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(n, now)
+}
+
+// CompileQuery parses query.Text and appends its filters to query.Filters,
+// leaving Text empty.
+func CompileQuery(query domain.Query, now time.Time) (domain.Query, error) {
+	if query.Text == "" {
+		return query, nil
+	}
+	filters, err := ParseFilters(query.Text, now)
+	if err != nil {
+		return query, err
+	}
+	query.Filters = append(append([]domain.FilterCondition(nil), query.Filters...), filters...)
+	query.Text = ""
+	return query, nil
+}
+
+// Compile turns n into filter conditions. A top-level AND becomes separate
+// conditions so storage can still prune partitions on them.
+func Compile(n Node, now time.Time) ([]domain.FilterCondition, error) {
+	if n == nil {
+		return nil, nil
+	}
+	if and, ok := n.(*And); ok {
+		return compileAll(and.Terms, now)
+	}
+	c, err := compile(n, now)
+	if err != nil {
+		return nil, err
+	}
+	return []domain.FilterCondition{c}, nil
+}
+
+func compileAll(nodes []Node, now time.Time) ([]domain.FilterCondition, error) {
+	conditions := make([]domain.FilterCondition, len(nodes))
+	for i, n := range nodes {
+		c, err := compile(n, now)
+		if err != nil {
+			return nil, err
+		}
+		conditions[i] = c
+	}
+	return conditions, nil
+}
+
+func compile(n Node, now time.Time) (domain.FilterCondition, error) {
+	switch n := n.(type) {
+	case *And:
+		children, err := compileAll(n.Terms, now)
+		return domain.FilterCondition{Type: domain.FilterAnd, Children: children}, err
+	case *Or:
+		children, err := compileAll(n.Terms, now)
+		return domain.FilterCondition{Type: domain.FilterOr, Children: children}, err
+	case *Not:
+		child, err := compile(n.Term, now)
+		return domain.FilterCondition{Type: domain.FilterNot, Children: []domain.FilterCondition{child}}, err
+	default:
+		return compileTerm(n.(*Term), now)
+	}
+}
+
+func compileTerm(t *Term, now time.Time) (domain.FilterCondition, error) {
+	if t.Field == "" {
+		if t.Value.Kind == Regexp {
+			return domain.FilterCondition{Type: domain.FilterRegexp, Field: "message", Value: t.Value.Text}, nil
+		}
+		return domain.FilterCondition{Type: domain.FilterContains, Field: "message", Value: t.Value.Text}, nil
+	}
+
+	if t.Value.Kind == Regexp {
+		return domain.FilterCondition{Type: domain.FilterRegexp, Field: t.Field, Value: t.Value.Text}, nil
+	}
+	value, err := compileValue(t.Field, t.Value, now)
+	if err != nil {
+		return domain.FilterCondition{}, err
+	}
+
+	c := domain.FilterCondition{Field: t.Field, Value: value}
+	switch t.Op {
+	case OpMatch:
+		c.Type = domain.FilterEquality
+		if isFreeText(t.Field) {
+			c.Type = domain.FilterContains
+		}
+	case OpEq:
+		c.Type = domain.FilterEquality
+	case OpNe:
+		c.Type = domain.FilterExclusion
+	case OpContains:
+		c.Type = domain.FilterContains
+		c.Value = t.Value.Text
+	case OpGt, OpGte, OpLt, OpLte:
+		c.Type = domain.FilterRange
+		c.Operator = rangeOperators[t.Op]
+	default:
+		return domain.FilterCondition{}, errorf(t.Position, "unknown operator '%s'", t.Op)
+	}
+	return c, nil
+}
+
+var rangeOperators = map[Op]string{OpGt: "gt", OpGte: "gte", OpLt: "lt", OpLte: "lte"}
+
+func compileValue(field string, v Value, now time.Time) (interface{}, error) {
+	f := strings.ToLower(field)
+	switch {
+	case f == "timestamp":
+		ms, err := parseTime(v.Text, now)
+		if err != nil {
+			return nil, errorf(v.Position, "invalid time %q: %v", v.Text, err)
+		}
+		return ms, nil
+	case v.Kind == Quoted || textColumns[f]:
+		return v.Text, nil
+	}
+	if n, err := strconv.ParseFloat(v.Text, 64); err == nil {
+		return n, nil
+	}
+	return v.Text, nil
+}
+
+var timeUnits = map[byte]time.Duration{
+	's': time.Second, 'm': time.Minute, 'h': time.Hour, 'd': 24 * time.Hour, 'w': 7 * 24 * time.Hour,
+}
+
+// parseTime accepts epoch milliseconds, now[+-]N<unit>, a date or an RFC 3339
+// time and returns epoch milliseconds.
+func parseTime(s string, now time.Time) (int64, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil {
+		return ms, nil
+	}
+	if rest, ok := strings.CutPrefix(s, "now"); ok {
+		if rest == "" {
+			return now.UnixMilli(), nil
+		}
+		sign := rest[0]
+		unit, ok := timeUnits[rest[len(rest)-1]]
+		n, err := strconv.ParseInt(rest[1:max(1, len(rest)-1)], 10, 64)
+		if (sign != '-' && sign != '+') || !ok || err != nil || n < 0 {
+			return 0, errors.New("expected now, now-<n><unit> or now+<n><unit> with unit s, m, h, d or w")
+		}
+		offset := time.Duration(n) * unit
+		if sign == '-' {
+			offset = -offset
+		}
+		return now.Add(offset).UnixMilli(), nil
+	}
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t.UnixMilli(), nil
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.UnixMilli(), nil
+	}
+	return 0, errors.New("expected epoch milliseconds, now-<n><unit>, YYYY-MM-DD or an RFC 3339 time")
+}
@@ -0,0 +1,130 @@
+package lang
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"LogLens/internal/domain"
+)
+
+var testNow = time.Date(2024, 1, 15, 12, 0, 0, 0, time.UTC)
+
+func TestCompile_Example(t *testing.T) {
+	filters, err := ParseFilters(`level:ERROR service:api -message:"health" status>=500 @timestamp>now-1h /timeout|refused/`, testNow)
+	if err != nil {
+		t.Fatalf("ParseFilters failed: %v", err)
+	}
+
+	want := []domain.FilterCondition{
+		{Type: domain.FilterEquality, Field: "level", Value: "ERROR"},
+		{Type: domain.FilterEquality, Field: "service", Value: "api"},
+		{Type: domain.FilterNot, Children: []domain.FilterCondition{
+			{Type: domain.FilterContains, Field: "message", Value: "health"},
+		}},
+		{Type: domain.FilterRange, Field: "status", Operator: "gte", Value: float64(500)},
+		{Type: domain.FilterRange, Field: "timestamp", Operator: "gt", Value: testNow.Add(-time.Hour).UnixMilli()},
+		{Type: domain.FilterRegexp, Field: "message", Value: "timeout|refused"},
+	}
+	if !reflect.DeepEqual(filters, want) {
+		t.Errorf("got  %+v\nwant %+v", filters, want)
+	}
+}
+
+func TestCompileQuery_AppendsFilters(t *testing.T) {
+	existing := []domain.FilterCondition{{Type: domain.FilterEquality, Field: "source", Value: "a.log"}}
+	q, err := CompileQuery(domain.Query{Text: "level:ERROR", Filters: existing}, testNow)
+	if err != nil {
+		t.Fatalf("CompileQuery failed: %v", err)
+	}
+	if q.Text != "" || len(q.Filters) != 2 || q.Filters[1].Field != "level" {
+		t.Errorf("expected the text compiled after the existing filter, got %+v", q)
+	}
+	if _, err := CompileQuery(domain.Query{Text: "level:("}, testNow); err == nil {
+		t.Error("expected an error for invalid text")
+	}
+}
+
+func TestCompile_Values(t *testing.T) {
+	cases := []struct {
+		input string
+		want  domain.FilterCondition
+	}{
+		{`service:500`, domain.FilterCondition{Type: domain.FilterEquality, Field: "service", Value: "500"}},
+		{`code:"500"`, domain.FilterCondition{Type: domain.FilterEquality, Field: "code", Value: "500"}},
+		{`line<=42`, domain.FilterCondition{Type: domain.FilterRange, Field: "line", Operator: "lte", Value: float64(42)}},
+		{`raw=exact`, domain.FilterCondition{Type: domain.FilterEquality, Field: "raw", Value: "exact"}},
+		{`host~db`, domain.FilterCondition{Type: domain.FilterContains, Field: "host", Value: "db"}},
+		{`user!=bob`, domain.FilterCondition{Type: domain.FilterExclusion, Field: "user", Value: "bob"}},
+		{`"disk full"`, domain.FilterCondition{Type: domain.FilterContains, Field: "message", Value: "disk full"}},
+		{`timestamp>=2024-01-14`, domain.FilterCondition{Type: domain.FilterRange, Field: "timestamp", Operator: "gte", Value: testNow.Add(-36 * time.Hour).UnixMilli()}},
+		{`timestamp<"2024-01-15T11:30:00Z"`, domain.FilterCondition{Type: domain.FilterRange, Field: "timestamp", Operator: "lt", Value: testNow.Add(-30 * time.Minute).UnixMilli()}},
+		{`timestamp:1700000000000`, domain.FilterCondition{Type: domain.FilterEquality, Field: "timestamp", Value: int64(1700000000000)}},
+		{`timestamp<now+2d`, domain.FilterCondition{Type: domain.FilterRange, Field: "timestamp", Operator: "lt", Value: testNow.Add(48 * time.Hour).UnixMilli()}},
+	}
+	for _, c := range cases {
+		filters, err := ParseFilters(c.input, testNow)
+		if err != nil {
+			t.Errorf("%q: ParseFilters failed: %v", c.input, err)
+			continue
+		}
+		if len(filters) != 1 || !reflect.DeepEqual(filters[0], c.want) {
+			t.Errorf("%q: got %+v, want %+v", c.input, filters, c.want)
+		}
+	}
+}
+
+func TestCompile_InvalidTime(t *testing.T) {
+	_, err := ParseFilters(`level:ERROR @timestamp>now-1y`, testNow)
+	var syntaxErr *SyntaxError
+	if !errors.As(err, &syntaxErr) || syntaxErr.Pos != 23 {
+		t.Errorf("expected SyntaxError at 23, got %v", err)
+	}
+}
+
+func TestFormatFilters_RoundTrip(t *testing.T) {
+	filters := []domain.FilterCondition{
+		{Type: domain.FilterOr, Children: []domain.FilterCondition{
+			{Type: domain.FilterEquality, Field: "level", Value: "ERROR"},
+			{Type: domain.FilterEquality, Field: "level", Value: "FATAL"},
+		}},
+		{Type: domain.FilterNot, Children: []domain.FilterCondition{
+			{Type: domain.FilterEquality, Field: "service", Value: "healthcheck"},
+		}},
+		{Type: domain.FilterContains, Field: "message", Value: "connection reset"},
+		{Type: domain.FilterEquality, Field: "message", Value: "done"},
+		{Type: domain.FilterEquality, Field: "code", Value: "42"},
+		{Type: domain.FilterRange, Field: "timestamp", Operator: "gte", Value: int64(1700000000000)},
+		{Type: domain.FilterRange, Field: "latency", Operator: "gt", Value: 1.5},
+		{Type: domain.FilterRegexp, Field: "path", Value: `^/api/`},
+	}
+
+	text, err := FormatFilters(filters)
+	if err != nil {
+		t.Fatalf("FormatFilters failed: %v", err)
+	}
+	want := `(level:ERROR OR level:FATAL) -service:healthcheck message:"connection reset" message=done code:"42" timestamp>=1700000000000 latency>1.5 path:/^\/api\//`
+	if text != want {
+		t.Errorf("got  %s\nwant %s", text, want)
+	}
+
+	parsed, err := ParseFilters(text, testNow)
+	if err != nil {
+		t.Fatalf("ParseFilters failed: %v", err)
+	}
+	if !reflect.DeepEqual(parsed, filters) {
+		t.Errorf("round trip changed filters:\n got %+v\nwant %+v", parsed, filters)
+	}
+}
+
+func TestFormatFilters_Unrepresentable(t *testing.T) {
+	for _, c := range []domain.FilterCondition{
+		{Type: domain.FilterOr},
+		{Type: domain.FilterEquality, Field: "has space", Value: "x"},
+	} {
+		if _, err := FormatFilters([]domain.FilterCondition{c}); err == nil {
+			t.Errorf("expected error formatting %+v", c)
+		}
+	}
+}
@@ -0,0 +1,230 @@
+package lang
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"LogLens/internal/domain"
+)
+
+// Format renders n in canonical form. Parsing the result yields an equal
+// tree, apart from positions.
+func Format(n Node) string {
+	if n == nil {
+		return ""
+	}
+	var b strings.Builder
+	format(&b, n)
+	return b.String()
+}
+
+func format(b *strings.Builder, n Node) {
+	switch n := n.(type) {
+	case *And:
+		for i, t := range n.Terms {
+			if i > 0 {
+				b.WriteByte(' ')
+			}
+			formatOperand(b, t, func(c Node) bool { return isGroup(c) })
+		}
+	case *Or:
+		for i, t := range n.Terms {
+			if i > 0 {
+				b.WriteString(" OR ")
+			}
+			formatOperand(b, t, func(c Node) bool { _, ok := c.(*Or); return ok })
+		}
+	case *Not:
+		b.WriteByte('-')
+		formatOperand(b, n.Term, isGroup)
+	case *Term:
+		if n.Field != "" {
+			b.WriteString(n.Field)
+			b.WriteString(string(n.Op))
+		}
+		formatValue(b, n.Value)
+	}
+}
+
+func isGroup(n Node) bool {
+	switch n.(type) {
+	case *And, *Or:
+		return true
+	}
+	return false
+}
+
+func formatOperand(b *strings.Builder, n Node, parens func(Node) bool) {
+	if parens(n) {
+		b.WriteByte('(')
+		format(b, n)
+		b.WriteByte(')')
+		return
+	}
+	format(b, n)
+}
+
+func formatValue(b *strings.Builder, v Value) {
+	switch v.Kind {
+	case Regexp:
+		b.WriteByte('/')
+		b.WriteString(strings.ReplaceAll(v.Text, "/", `\/`))
+		b.WriteByte('/')
+	case Quoted:
+		b.WriteString(quote(v.Text))
+	default:
+		b.WriteString(v.Text)
+	}
+}
+
+func quote(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, `"`, `\"`)
+	return `"` + r.Replace(s) + `"`
+}
+
+// isWord reports whether s lexes back as a single plain word.
+func isWord(s string) bool {
+	if s == "" || s == "AND" || s == "OR" || s == "NOT" || s[0] == '-' || s[0] == '/' || s[0] == '@' {
+		return false
+	}
+	for _, r := range s {
+		if isSpecial(r) || r == utf8.RuneError {
+			return false
+		}
+	}
+	return true
+}
+
+// FormatFilters renders conditions as query text. Conditions that the
+// language cannot express, such as empty groups, are reported as errors.
+func FormatFilters(conditions []domain.FilterCondition) (string, error) {
+	if len(conditions) == 0 {
+		return "", nil
+	}
+	if len(conditions) == 1 {
+		n, err := fromCondition(conditions[0])
+		if err != nil {
+			return "", err
+		}
+		return Format(n), nil
+	}
+	terms, err := fromConditions(conditions)
+	if err != nil {
+		return "", err
+	}
+	return Format(&And{Terms: terms}), nil
+}
+
+func fromConditions(conditions []domain.FilterCondition) ([]Node, error) {
+	nodes := make([]Node, len(conditions))
+	for i, c := range conditions {
+		n, err := fromCondition(c)
+		if err != nil {
+			return nil, err
+		}
+		nodes[i] = n
+	}
+	return nodes, nil
+}
+
+func fromCondition(c domain.FilterCondition) (Node, error) {
+	switch c.Type {
+	case domain.FilterAnd, domain.FilterOr:
+		if len(c.Children) == 0 {
+			return nil, fmt.Errorf("empty %s filter has no query text form", c.Type)
+		}
+		terms, err := fromConditions(c.Children)
+		if err != nil {
+			return nil, err
+		}
+		if len(terms) == 1 {
+			return terms[0], nil
+		}
+		if c.Type == domain.FilterOr {
+			return &Or{Terms: terms}, nil
+		}
+		return &And{Terms: terms}, nil
+	case domain.FilterNot:
+		if len(c.Children) != 1 {
+			return nil, fmt.Errorf("not filter requires exactly one child, got %d", len(c.Children))
+		}
+		child, err := fromCondition(c.Children[0])
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Term: child}, nil
+	}
+
+	field := strings.TrimPrefix(c.Field, "@")
+	if !isWord(field) {
+		return nil, fmt.Errorf("field name %q has no query text form", c.Field)
+	}
+	t := &Term{Field: field}
+	switch c.Type {
+	case domain.FilterEquality:
+		t.Op = OpMatch
+		if isFreeText(field) {
+			t.Op = OpEq
+		}
+	case domain.FilterExclusion:
+		t.Op = OpNe
+	case domain.FilterContains:
+		t.Op = OpContains
+		if isFreeText(field) {
+			t.Op = OpMatch
+		}
+		t.Value = textValue(fmt.Sprintf("%v", c.Value), true)
+		return t, nil
+	case domain.FilterRegexp:
+		t.Op = OpMatch
+		t.Value = Value{Kind: Regexp, Text: fmt.Sprintf("%v", c.Value)}
+		return t, nil
+	case domain.FilterRange:
+		for op, name := range rangeOperators {
+			if name == c.Operator {
+				t.Op = op
+			}
+		}
+		if t.Op == "" {
+			return nil, fmt.Errorf("invalid range operator: %s", c.Operator)
+		}
+	default:
+		return nil, fmt.Errorf("filter type %q has no query text form", c.Type)
+	}
+
+	t.Value = conditionValue(field, c.Value)
+	return t, nil
+}
+
+// conditionValue picks a value form that compiles back to v: numbers stay
+// words and strings that would read as numbers on numeric fields get quoted.
+func conditionValue(field string, v interface{}) Value {
+	switch n := v.(type) {
+	case float64:
+		return Value{Kind: Word, Text: strconv.FormatFloat(n, 'f', -1, 64)}
+	case float32:
+		return Value{Kind: Word, Text: strconv.FormatFloat(float64(n), 'f', -1, 32)}
+	case int:
+		return Value{Kind: Word, Text: strconv.Itoa(n)}
+	case int64:
+		return Value{Kind: Word, Text: strconv.FormatInt(n, 10)}
+	case json.Number:
+		return Value{Kind: Word, Text: n.String()}
+	case string:
+		f := strings.ToLower(field)
+		_, numeric := strconv.ParseFloat(n, 64)
+		return textValue(n, textColumns[f] || f == "timestamp" || numeric != nil)
+	default:
+		return textValue(fmt.Sprintf("%v", v), true)
+	}
+}
+
+func textValue(s string, bare bool) Value {
+	if bare && isWord(s) {
+		return Value{Kind: Word, Text: s}
+	}
+	return Value{Kind: Quoted, Text: s}
+}
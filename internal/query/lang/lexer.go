@@ -0,0 +1,156 @@
+package lang
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokWord
+	tokString
+	tokRegexp
+	tokOp
+	tokMinus
+	tokLParen
+	tokRParen
+	tokAnd
+	tokOr
+	tokNot
+)
+
+type token struct {
+	kind tokenKind
+	pos  int
+	text string
+}
+
+func (t token) describe() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokString:
+		return "quoted string"
+	case tokRegexp:
+		return "regular expression"
+	case tokLParen, tokRParen, tokOp:
+		return "'" + t.text + "'"
+	default:
+		return t.text
+	}
+}
+
+// isSpecial reports whether r ends a word.
+func isSpecial(r rune) bool {
+	return unicode.IsSpace(r) || strings.ContainsRune(`()":=!<>~`, r)
+}
+
+func lex(input string) ([]token, error) {
+	var tokens []token
+	prevOp := false
+	for i := 0; i < len(input); {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		start := i
+
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+			continue
+		case r == '(':
+			tokens = append(tokens, token{tokLParen, start, "("})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{tokRParen, start, ")"})
+			i++
+		case r == '"':
+			text, end, err := lexQuoted(input, start, '"')
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{tokString, start, text})
+			i = end
+		case r == '/' && isRegexp(input, start):
+			text, end, _ := lexQuoted(input, start, '/')
+			tokens = append(tokens, token{tokRegexp, start, text})
+			i = end
+		case strings.ContainsRune(":=~<>!", r):
+			op := string(r)
+			if (r == '<' || r == '>' || r == '!') && i+1 < len(input) && input[i+1] == '=' {
+				op += "="
+			}
+			if op == "!" {
+				return nil, errorf(start, "expected '!='")
+			}
+			tokens = append(tokens, token{tokOp, start, op})
+			i += len(op)
+		case r == '-' && !prevOp && i+1 < len(input) && !unicode.IsSpace(rune(input[i+1])):
+			tokens = append(tokens, token{tokMinus, start, "-"})
+			i++
+		default:
+			i = wordEnd(input, i)
+			text := input[start:i]
+			kind := tokWord
+			switch text {
+			case "AND":
+				kind = tokAnd
+			case "OR":
+				kind = tokOr
+			case "NOT":
+				kind = tokNot
+			}
+			tokens = append(tokens, token{kind, start, text})
+		}
+		prevOp = tokens[len(tokens)-1].kind == tokOp
+	}
+	return append(tokens, token{tokEOF, len(input), ""}), nil
+}
+
+// wordEnd returns where the word starting at input[i] ends.
+func wordEnd(input string, i int) int {
+	for i < len(input) {
+		r, size := utf8.DecodeRuneInString(input[i:])
+		if isSpecial(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+// isRegexp reports whether the '/' at input[start] opens a regular
+// expression: one closed by another '/' that ends the value, so that paths
+// such as /api/v1 and a lone / are read as words.
+func isRegexp(input string, start int) bool {
+	_, end, err := lexQuoted(input, start, '/')
+	if err != nil {
+		return false
+	}
+	if end == len(input) {
+		return true
+	}
+	r, _ := utf8.DecodeRuneInString(input[end:])
+	return unicode.IsSpace(r) || r == ')'
+}
+
+// lexQuoted reads a value delimited by quote starting at input[start]. A
+// backslash escapes the delimiter; in strings it also escapes itself, while
+// regular expressions keep every other backslash for the regexp syntax.
+func lexQuoted(input string, start int, quote byte) (string, int, error) {
+	var b strings.Builder
+	for i := start + 1; i < len(input); i++ {
+		c := input[i]
+		switch {
+		case c == quote:
+			return b.String(), i + 1, nil
+		case c == '\\' && i+1 < len(input) && (input[i+1] == quote || (quote == '"' && input[i+1] == '\\')):
+			b.WriteByte(input[i+1])
+			i++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", 0, errorf(start, "unterminated string")
+}
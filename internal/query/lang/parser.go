@@ -0,0 +1,179 @@
+package lang
+
+import (
+	"regexp"
+	"strings"
+)
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+// Parse parses a query. An empty query returns a nil Node. Errors are
+// *SyntaxError values pointing at the offending byte.
+func Parse(input string) (Node, error) {
+	tokens, err := lex(input)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, nil
+	}
+
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		if t.kind == tokRParen {
+			return nil, errorf(t.pos, "unmatched ')'")
+		}
+		return nil, errorf(t.pos, "unexpected %s", t.describe())
+	}
+	return n, nil
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseOr() (Node, error) {
+	first, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	terms := []Node{first}
+	for p.peek().kind == tokOr {
+		p.next()
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, n)
+	}
+	if len(terms) == 1 {
+		return first, nil
+	}
+	return &Or{Position: first.Pos(), Terms: terms}, nil
+}
+
+func (p *parser) parseAnd() (Node, error) {
+	first, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	terms := []Node{first}
+	for {
+		switch p.peek().kind {
+		case tokEOF, tokRParen, tokOr:
+			if len(terms) == 1 {
+				return first, nil
+			}
+			return &And{Position: first.Pos(), Terms: terms}, nil
+		case tokAnd:
+			p.next()
+		}
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		terms = append(terms, n)
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if t := p.peek(); t.kind == tokMinus || t.kind == tokNot {
+		p.next()
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Position: t.pos, Term: n}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokLParen:
+		if p.peek().kind == tokRParen {
+			return nil, errorf(p.peek().pos, "empty group")
+		}
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek().kind != tokRParen {
+			return nil, errorf(t.pos, "unclosed '('")
+		}
+		p.next()
+		return n, nil
+
+	case tokWord:
+		if op := p.peek(); op.kind == tokOp {
+			p.next()
+			return p.parseComparison(t, op)
+		}
+		return &Term{Position: t.pos, Value: Value{Position: t.pos, Kind: Word, Text: t.text}}, nil
+
+	case tokString:
+		return &Term{Position: t.pos, Value: Value{Position: t.pos, Kind: Quoted, Text: t.text}}, nil
+
+	case tokRegexp:
+		if err := checkRegexp(t); err != nil {
+			return nil, err
+		}
+		return &Term{Position: t.pos, Value: Value{Position: t.pos, Kind: Regexp, Text: t.text}}, nil
+
+	case tokOp:
+		return nil, errorf(t.pos, "missing field name before %s", t.describe())
+
+	default:
+		return nil, errorf(t.pos, "expected a search term, found %s", t.describe())
+	}
+}
+
+func (p *parser) parseComparison(field, op token) (Node, error) {
+	name := strings.TrimPrefix(field.text, "@")
+	if name == "" {
+		return nil, errorf(field.pos, "missing field name")
+	}
+
+	term := &Term{Position: field.pos, Field: name, Op: Op(op.text)}
+	v := p.next()
+	switch v.kind {
+	case tokWord, tokAnd, tokOr, tokNot:
+		term.Value = Value{Position: v.pos, Kind: Word, Text: v.text}
+	case tokString:
+		term.Value = Value{Position: v.pos, Kind: Quoted, Text: v.text}
+	case tokRegexp:
+		if term.Op != OpMatch {
+			return nil, errorf(v.pos, "regular expressions need ':', not '%s'", op.text)
+		}
+		if err := checkRegexp(v); err != nil {
+			return nil, err
+		}
+		term.Value = Value{Position: v.pos, Kind: Regexp, Text: v.text}
+	default:
+		return nil, errorf(v.pos, "expected a value after %s%s, found %s", field.text, op.text, v.describe())
+	}
+	return term, nil
+}
+
+func checkRegexp(t token) error {
+	if _, err := regexp.Compile(t.text); err != nil {
+		return errorf(t.pos, "invalid regular expression: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,156 @@
+package lang
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestParse_Structure(t *testing.T) {
+	n, err := Parse(`level:ERROR service:api -message:"health" status>=500 @timestamp>now-1h /timeout|refused/`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	and, ok := n.(*And)
+	if !ok || len(and.Terms) != 6 {
+		t.Fatalf("expected AND of 6 terms, got %#v", n)
+	}
+
+	want := []*Term{
+		{Position: 0, Field: "level", Op: OpMatch, Value: Value{Position: 6, Kind: Word, Text: "ERROR"}},
+		{Position: 12, Field: "service", Op: OpMatch, Value: Value{Position: 20, Kind: Word, Text: "api"}},
+		{Position: 25, Field: "message", Op: OpMatch, Value: Value{Position: 33, Kind: Quoted, Text: "health"}},
+		{Position: 42, Field: "status", Op: OpGte, Value: Value{Position: 50, Kind: Word, Text: "500"}},
+		{Position: 54, Field: "timestamp", Op: OpGt, Value: Value{Position: 65, Kind: Word, Text: "now-1h"}},
+		{Position: 72, Value: Value{Position: 72, Kind: Regexp, Text: "timeout|refused"}},
+	}
+	for i, term := range and.Terms {
+		if not, ok := term.(*Not); ok {
+			if not.Position != 24 {
+				t.Errorf("expected NOT at 24, got %d", not.Position)
+			}
+			term = not.Term
+		}
+		if !reflect.DeepEqual(term, want[i]) {
+			t.Errorf("term %d: got %+v, want %+v", i, term, want[i])
+		}
+	}
+}
+
+func TestParse_Precedence(t *testing.T) {
+	n, err := Parse(`a b OR NOT c AND (d OR e)`)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	or, ok := n.(*Or)
+	if !ok || len(or.Terms) != 2 {
+		t.Fatalf("expected OR of 2 terms, got %#v", n)
+	}
+	left, ok := or.Terms[0].(*And)
+	if !ok || len(left.Terms) != 2 {
+		t.Fatalf("expected AND on the left, got %#v", or.Terms[0])
+	}
+	right, ok := or.Terms[1].(*And)
+	if !ok || len(right.Terms) != 2 {
+		t.Fatalf("expected AND on the right, got %#v", or.Terms[1])
+	}
+	if _, ok := right.Terms[0].(*Not); !ok {
+		t.Errorf("expected NOT c, got %#v", right.Terms[0])
+	}
+	if _, ok := right.Terms[1].(*Or); !ok {
+		t.Errorf("expected grouped OR, got %#v", right.Terms[1])
+	}
+}
+
+func TestParse_Empty(t *testing.T) {
+	n, err := Parse("   ")
+	if err != nil || n != nil {
+		t.Errorf("expected nil node for blank query, got %#v, %v", n, err)
+	}
+}
+
+func TestParse_ErrorPositions(t *testing.T) {
+	cases := []struct {
+		input string
+		pos   int
+	}{
+		{`level:ERROR message:"unterminated`, 20},
+		{`service:api (level:ERROR OR level:WARN`, 12},
+		{`level:ERROR)`, 11},
+		{`status>=`, 8},
+		{`:ERROR`, 0},
+		{`level:ERROR /time(out/`, 12},
+		{`status>/5../`, 7},
+		{`a OR`, 4},
+		{`level!ERROR`, 5},
+		{`()`, 1},
+	}
+	for _, c := range cases {
+		_, err := Parse(c.input)
+		var syntaxErr *SyntaxError
+		if !errors.As(err, &syntaxErr) {
+			t.Errorf("%q: expected SyntaxError, got %v", c.input, err)
+			continue
+		}
+		if syntaxErr.Pos != c.pos {
+			t.Errorf("%q: expected error at %d, got %d (%v)", c.input, c.pos, syntaxErr.Pos, err)
+		}
+	}
+}
+
+func TestParse_SlashesInWords(t *testing.T) {
+	cases := map[string]Node{
+		`path:/api/v1`: &Term{Position: 0, Field: "path", Op: OpMatch, Value: Value{Position: 5, Kind: Word, Text: "/api/v1"}},
+		`/`:            &Term{Position: 0, Value: Value{Position: 0, Kind: Word, Text: "/"}},
+		`path:/api/`:   &Term{Position: 0, Field: "path", Op: OpMatch, Value: Value{Position: 5, Kind: Regexp, Text: "api"}},
+		`(/a b/)`:      &Term{Position: 1, Value: Value{Position: 1, Kind: Regexp, Text: "a b"}},
+	}
+	for input, want := range cases {
+		n, err := Parse(input)
+		if err != nil {
+			t.Errorf("%q: Parse failed: %v", input, err)
+			continue
+		}
+		if !reflect.DeepEqual(n, want) {
+			t.Errorf("%q: got %+v, want %+v", input, n, want)
+		}
+	}
+
+	n, err := Parse(`a / b`)
+	if and, ok := n.(*And); err != nil || !ok || len(and.Terms) != 3 {
+		t.Errorf("expected a lone / between words to be a word, got %#v: %v", n, err)
+	}
+}
+
+func TestFormat_RoundTrip(t *testing.T) {
+	cases := map[string]string{
+		`level:ERROR service:api -message:"health" status>=500 @timestamp>now-1h /timeout|refused/`: `level:ERROR service:api -message:"health" status>=500 timestamp>now-1h /timeout|refused/`,
+		`(level:ERROR OR level:FATAL) AND NOT service:healthcheck`:                                  `(level:ERROR OR level:FATAL) -service:healthcheck`,
+		`a b OR c`:                    `a b OR c`,
+		`a (b c)`:                     `a (b c)`,
+		`a OR (b OR c)`:               `a OR (b OR c)`,
+		`-(a OR b) --c`:               `-(a OR b) --c`,
+		`path:/\/api\/v\d+/ delta>-5`: `path:/\/api\/v\d+/ delta>-5`,
+		`msg:"say \"hi\" \\ bye" source~"/var/log/x.log"`: `msg:"say \"hi\" \\ bye" source~"/var/log/x.log"`,
+		`level:AND  service!=api`:                         `level:AND service!=api`,
+	}
+	for input, canonical := range cases {
+		n, err := Parse(input)
+		if err != nil {
+			t.Errorf("%q: Parse failed: %v", input, err)
+			continue
+		}
+		got := Format(n)
+		if got != canonical {
+			t.Errorf("%q: formatted as %q, want %q", input, got, canonical)
+		}
+		again, err := Parse(got)
+		if err != nil {
+			t.Errorf("%q: reparse failed: %v", got, err)
+			continue
+		}
+		if Format(again) != got {
+			t.Errorf("%q: not stable, reformatted as %q", got, Format(again))
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"sync"
+
+	"LogLens/internal/domain"
+	sqlite3 "modernc.org/sqlite"
+)
+
+// SQL aggregate functions behind the percentile, stddev, histogram and
+// cardinality aggregations. Numeric text is accepted; NULLs and other
+// non-numeric values are skipped.
+//
+//	percentile(x, p)             exact, linearly interpolated, p in (0, 100]
+//	stddev(x)                    sample standard deviation
+//	histogram(x, width, logBase) JSON array of {lower, upper, count}
+//	approx_distinct(x)           HyperLogLog estimate of COUNT(DISTINCT x)
+
+var (
+	aggregateOnce sync.Once
+	aggregateErr  error
+)
+
+func registerAggregateFuncs() error {
+	aggregateOnce.Do(func() {
+		funcs := []struct {
+			name  string
+			nArgs int32
+			make  func() sqlite3.AggregateFunction
+		}{
+			{"percentile", 2, func() sqlite3.AggregateFunction { return &percentileAgg{} }},
+			{"stddev", 1, func() sqlite3.AggregateFunction { return &stddevAgg{} }},
+			{"histogram", 3, func() sqlite3.AggregateFunction { return &histogramAgg{} }},
+			{"approx_distinct", 1, func() sqlite3.AggregateFunction { return &hllAgg{} }},
+		}
+		for _, f := range funcs {
+			newAgg := f.make
+			aggregateErr = sqlite3.RegisterFunction(f.name, &sqlite3.FunctionImpl{
+				NArgs:         f.nArgs,
+				Deterministic: true,
+				MakeAggregate: func(ctx sqlite3.FunctionContext) (sqlite3.AggregateFunction, error) {
+					return newAgg(), nil
+				},
+			})
+			if aggregateErr != nil {
+				return
+			}
+		}
+	})
+	return aggregateErr
+}
+
+func numericValue(v driver.Value) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, !math.IsNaN(n)
+	case string:
+		f, err := strconv.ParseFloat(n, 64)
+		return f, err == nil && !math.IsNaN(f)
+	default:
+		return 0, false
+	}
+}
+
+// aggregate provides the window callbacks, which these functions do not
+// support.
+type aggregate struct{}
+
+func (aggregate) WindowInverse(*sqlite3.FunctionContext, []driver.Value) error {
+	return fmt.Errorf("not supported as a window function")
+}
+
+func (aggregate) Final(*sqlite3.FunctionContext) {}
+
+type percentileAgg struct {
+	aggregate
+	p      float64
+	values []float64
+}
+
+func (a *percentileAgg) Step(ctx *sqlite3.FunctionContext, args []driver.Value) error {
+	p, ok := numericValue(args[1])
+	if !ok || p <= 0 || p > 100 {
+		return fmt.Errorf("percentile: rank must be in (0, 100]")
+	}
+	a.p = p
+	if v, ok := numericValue(args[0]); ok {
+		a.values = append(a.values, v)
+	}
+	return nil
+}
+
+func (a *percentileAgg) WindowValue(ctx *sqlite3.FunctionContext) (driver.Value, error) {
+	if len(a.values) == 0 {
+		return nil, nil
+	}
+	sort.Float64s(a.values)
+	rank := a.p / 100 * float64(len(a.values)-1)
+	lo := int(math.Floor(rank))
+	hi := min(lo+1, len(a.values)-1)
+	return a.values[lo] + (a.values[hi]-a.values[lo])*(rank-float64(lo)), nil
+}
+
+// stddevAgg uses Welford's algorithm.
+type stddevAgg struct {
+	aggregate
+	n    int64
+	mean float64
+	m2   float64
+}
+
+func (a *stddevAgg) Step(ctx *sqlite3.FunctionContext, args []driver.Value) error {
+	v, ok := numericValue(args[0])
+	if !ok {
+		return nil
+	}
+	a.n++
+	delta := v - a.mean
+	a.mean += delta / float64(a.n)
+	a.m2 += delta * (v - a.mean)
+	return nil
+}
+
+func (a *stddevAgg) WindowValue(ctx *sqlite3.FunctionContext) (driver.Value, error) {
+	if a.n < 2 {
+		return nil, nil
+	}
+	return math.Sqrt(a.m2 / float64(a.n-1)), nil
+}
+
+// histogramAgg counts values into fixed-width buckets, or into
+// [logBase^k, logBase^(k+1)) when logBase > 1. On a log scale, values <= 0
+// are counted in a [0, 0] bucket.
+type histogramAgg struct {
+	aggregate
+	width, base float64
+	counts      map[float64]int64
+}
+
+func (a *histogramAgg) Step(ctx *sqlite3.FunctionContext, args []driver.Value) error {
+	if a.counts == nil {
+		a.width, _ = numericValue(args[1])
+		a.base, _ = numericValue(args[2])
+		if a.width <= 0 && a.base <= 1 {
+			return fmt.Errorf("histogram: needs a bucket width > 0 or a log base > 1")
+		}
+		a.counts = make(map[float64]int64)
+	}
+	v, ok := numericValue(args[0])
+	if !ok {
+		return nil
+	}
+	switch {
+	case a.base > 1 && v <= 0:
+		a.counts[0]++
+	case a.base > 1:
+		k := math.Floor(math.Log(v) / math.Log(a.base))
+		// Correct for rounding at exact powers of the base.
+		if math.Pow(a.base, k+1) <= v {
+			k++
+		} else if math.Pow(a.base, k) > v {
+			k--
+		}
+		a.counts[math.Pow(a.base, k)]++
+	default:
+		a.counts[math.Floor(v/a.width)*a.width]++
+	}
+	return nil
+}
+
+func (a *histogramAgg) WindowValue(ctx *sqlite3.FunctionContext) (driver.Value, error) {
+	buckets := make([]domain.HistogramBucket, 0, len(a.counts))
+	for lower, count := range a.counts {
+		upper := lower + a.width
+		if a.base > 1 {
+			upper = lower * a.base
+		}
+		buckets = append(buckets, domain.HistogramBucket{Lower: lower, Upper: upper, Count: count})
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].Lower < buckets[j].Lower })
+	out, err := json.Marshal(buckets)
+	if err != nil {
+		return nil, err
+	}
+	return string(out), nil
+}
+
+type hllAgg struct {
+	aggregate
+	sketch *hyperLogLog
+}
+
+func (a *hllAgg) Step(ctx *sqlite3.FunctionContext, args []driver.Value) error {
+	if args[0] == nil {
+		return nil
+	}
+	if a.sketch == nil {
+		a.sketch = newHyperLogLog()
+	}
+	a.sketch.Add(distinctKey(args[0]))
+	return nil
+}
+
+func (a *hllAgg) WindowValue(ctx *sqlite3.FunctionContext) (driver.Value, error) {
+	if a.sketch == nil {
+		return int64(0), nil
+	}
+	return a.sketch.Estimate(), nil
+}
+
+// distinctKey renders v so that values SQL treats as equal, such as 200 and
+// 200.0, get the same key.
+func distinctKey(v driver.Value) string {
+	switch n := v.(type) {
+	case int64:
+		return "n" + strconv.FormatInt(n, 10)
+	case float64:
+		if n == math.Trunc(n) && math.Abs(n) < 1<<63 {
+			return "n" + strconv.FormatInt(int64(n), 10)
+		}
+		return "n" + strconv.FormatFloat(n, 'g', -1, 64)
+	case string:
+		return "s" + n
+	case []byte:
+		return "b" + string(n)
+	default:
+		return fmt.Sprintf("?%v", n)
+	}
+}
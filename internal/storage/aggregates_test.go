@@ -0,0 +1,135 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+
+	"LogLens/internal/domain"
+)
+
+// latencyRecords returns records with latency_ms = 1..n and n/10 distinct
+// users.
+func latencyRecords(n int) []domain.LogRecord {
+	records := make([]domain.LogRecord, n)
+	for i := range records {
+		records[i] = domain.LogRecord{
+			ID:        fmt.Sprintf("req_%d", i),
+			Timestamp: int64(i) * 1000,
+			Level:     "INFO",
+			Message:   "request",
+			Fields:    map[string]interface{}{"latency_ms": float64(i + 1), "user": fmt.Sprintf("u%d", i%(n/10))},
+			Raw:       "raw",
+		}
+	}
+	return records
+}
+
+func TestAggregate_PercentilesAndStddev(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	storeRecords(t, storage, latencyRecords(1000))
+
+	results, err := storage.Aggregate(context.Background(), nil, []domain.Aggregation{
+		{Function: "p50", Field: "latency_ms"},
+		{Function: "p99", Field: "latency_ms"},
+		{Function: "percentile", Field: "latency_ms", Percentile: 99.9},
+		{Function: "stddev", Field: "latency_ms"},
+		{Function: "cardinality", Field: "user"},
+	})
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+
+	for alias, want := range map[string]float64{
+		"p50_latency_ms":   500.5,
+		"p99_latency_ms":   990.01,
+		"p99.9_latency_ms": 999.001,
+		// Sample standard deviation of 1..1000.
+		"stddev_latency_ms": math.Sqrt(1000 * 1001 / 12.0),
+	} {
+		got, ok := results[alias].(float64)
+		if !ok || math.Abs(got-want) > 1e-6 {
+			t.Errorf("%s: expected %v, got %v", alias, want, results[alias])
+		}
+	}
+	if got := results["cardinality_user"].(int64); got < 98 || got > 102 {
+		t.Errorf("expected about 100 distinct users, got %d", got)
+	}
+}
+
+func histogramString(buckets []domain.HistogramBucket) string {
+	parts := make([]string, len(buckets))
+	for i, b := range buckets {
+		parts[i] = fmt.Sprintf("[%g,%g)=%d", b.Lower, b.Upper, b.Count)
+	}
+	return strings.Join(parts, " ")
+}
+
+func TestAggregate_Histograms(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	storeRecords(t, storage, latencyRecords(1000))
+
+	results, err := storage.Aggregate(context.Background(), nil, []domain.Aggregation{
+		{Function: "histogram", Field: "latency_ms", Interval: 250, Alias: "linear"},
+		{Function: "histogram", Field: "latency_ms", LogBase: 10, Alias: "log"},
+	})
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+
+	want := map[string]string{
+		"linear": "[0,250)=249 [250,500)=250 [500,750)=250 [750,1000)=250 [1000,1250)=1",
+		"log":    "[1,10)=9 [10,100)=90 [100,1000)=900 [1000,10000)=1",
+	}
+	for alias, w := range want {
+		if got := histogramString(results[alias].([]domain.HistogramBucket)); got != w {
+			t.Errorf("%s histogram: got %s, want %s", alias, got, w)
+		}
+	}
+}
+
+func TestAggregateGroups_PercentilesPerTimeBucket(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	storeRecords(t, storage, latencyRecords(200))
+
+	groups, err := storage.AggregateGroups(context.Background(), domain.Query{
+		BucketMs: 100000,
+		Aggregations: []domain.Aggregation{
+			{Function: "p90", Field: "latency_ms"},
+			{Function: "histogram", Field: "latency_ms", Interval: 100},
+		},
+	})
+	if err != nil {
+		t.Fatalf("AggregateGroups failed: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 time buckets, got %+v", groups)
+	}
+	if p90, _ := groups[1].Values["p90_latency_ms"].(float64); math.Abs(p90-190.1) > 1e-9 {
+		t.Errorf("expected p90 of 101..200 to be 190.1, got %v", p90)
+	}
+	hist := groups[0].Values["histogram_latency_ms"].([]domain.HistogramBucket)
+	if len(hist) != 2 || hist[0].Count != 99 || hist[1].Count != 1 {
+		t.Errorf("unexpected histogram for first bucket: %v", hist)
+	}
+}
+
+func TestAggregate_RejectsBadParameters(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	for _, agg := range []domain.Aggregation{
+		{Function: "percentile", Field: "latency_ms", Percentile: 150},
+		{Function: "histogram", Field: "latency_ms"},
+		{Function: "p95"},
+	} {
+		if _, err := storage.Aggregate(context.Background(), nil, []domain.Aggregation{agg}); err == nil {
+			t.Errorf("expected error for %+v", agg)
+		}
+	}
+}
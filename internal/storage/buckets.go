@@ -0,0 +1,230 @@
+package storage
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+	_ "time/tzdata" // timezones on systems without a zoneinfo database
+
+	sqlite3 "modernc.org/sqlite"
+)
+
+const defaultTimelinePoints = 100
+
+// unitMs is the nominal width of each interval unit. Units from an hour up
+// are calendar units, aligned in the bucketing's location.
+var unitMs = map[string]int64{
+	"ms": 1,
+	"s":  1000,
+	"m":  60 * 1000,
+	"h":  60 * 60 * 1000,
+	"d":  24 * 60 * 60 * 1000,
+	"w":  7 * 24 * 60 * 60 * 1000,
+	"M":  30 * 24 * 60 * 60 * 1000,
+	"y":  365 * 24 * 60 * 60 * 1000,
+}
+
+// autoIntervals are the candidates for automatic bucket sizing, narrowest
+// first. Wider ranges use whole years.
+var autoIntervals = []string{
+	"1ms", "10ms", "100ms",
+	"1s", "2s", "5s", "10s", "15s", "30s",
+	"1m", "2m", "5m", "10m", "15m", "30m",
+	"1h", "2h", "3h", "6h", "12h",
+	"1d", "2d", "1w", "1M", "3M", "1y",
+}
+
+// bucketing splits time into buckets of n units.
+type bucketing struct {
+	n    int64
+	unit string
+	loc  *time.Location
+}
+
+// parseBucketing parses an interval such as "5m" or "1d" in timezone tz.
+func parseBucketing(interval, tz string) (bucketing, error) {
+	i := 0
+	for i < len(interval) && interval[i] >= '0' && interval[i] <= '9' {
+		i++
+	}
+	n, err := strconv.ParseInt(interval[:i], 10, 64)
+	if err != nil || n <= 0 {
+		return bucketing{}, fmt.Errorf("invalid interval: %q", interval)
+	}
+	unit := interval[i:]
+	if _, ok := unitMs[unit]; !ok {
+		return bucketing{}, fmt.Errorf("invalid interval unit: %q", interval)
+	}
+	loc, err := time.LoadLocation(tz)
+	if err != nil {
+		return bucketing{}, fmt.Errorf("invalid timezone: %q", tz)
+	}
+	return bucketing{n: n, unit: unit, loc: loc}, nil
+}
+
+// autoBucketing picks the narrowest interval giving at most points buckets
+// over [lo, hi].
+func autoBucketing(lo, hi int64, points int, tz string) (bucketing, error) {
+	span := hi - lo + 1
+	for _, interval := range autoIntervals {
+		b, err := parseBucketing(interval, tz)
+		if err != nil {
+			return bucketing{}, err
+		}
+		if span/b.width() < int64(points) {
+			return b, nil
+		}
+	}
+	years := span/unitMs["y"]/int64(points) + 1
+	return parseBucketing(strconv.FormatInt(years, 10)+"y", tz)
+}
+
+func (b bucketing) String() string {
+	return strconv.FormatInt(b.n, 10) + b.unit
+}
+
+func (b bucketing) calendar() bool {
+	return unitMs[b.unit] >= unitMs["h"]
+}
+
+// width is the nominal bucket width in milliseconds.
+func (b bucketing) width() int64 {
+	return b.n * unitMs[b.unit]
+}
+
+// expr returns the SQL for the start of a record's bucket and its
+// arguments.
+func (b bucketing) expr() (string, []interface{}) {
+	if !b.calendar() {
+		return bucketExpr(b.width())
+	}
+	return "time_bucket(timestamp, ?, ?)", []interface{}{b.String(), b.loc.String()}
+}
+
+func floorDiv(a, b int64) int64 {
+	q := a / b
+	if a%b != 0 && (a < 0) != (b < 0) {
+		q--
+	}
+	return q
+}
+
+// civilDay numbers the calendar date of t, counting from 1970-01-01.
+func civilDay(t time.Time) int64 {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC).Unix() / 86400
+}
+
+func (b bucketing) midnight(day int64) time.Time {
+	u := time.Unix(day*86400, 0).UTC()
+	return time.Date(u.Year(), u.Month(), u.Day(), 0, 0, 0, 0, b.loc)
+}
+
+// start returns the start of the bucket holding ts. Days and weeks are
+// counted from 1970-01-01 and Monday 1970-01-05, so multi-day buckets are
+// stable whatever the range.
+func (b bucketing) start(ts int64) int64 {
+	if !b.calendar() {
+		return bucketStart(ts, b.width())
+	}
+	t := time.UnixMilli(ts).In(b.loc)
+	switch b.unit {
+	case "h":
+		hour := int64(t.Hour()) / b.n * b.n
+		return time.Date(t.Year(), t.Month(), t.Day(), int(hour), 0, 0, 0, b.loc).UnixMilli()
+	case "d":
+		return b.midnight(floorDiv(civilDay(t), b.n) * b.n).UnixMilli()
+	case "w":
+		return b.midnight(floorDiv(civilDay(t)-4, 7*b.n)*7*b.n + 4).UnixMilli()
+	case "M":
+		month := floorDiv(int64(t.Year())*12+int64(t.Month())-1, b.n) * b.n
+		return time.Date(int(floorDiv(month, 12)), time.Month(month-floorDiv(month, 12)*12+1), 1, 0, 0, 0, 0, b.loc).UnixMilli()
+	default:
+		year := floorDiv(int64(t.Year()), b.n) * b.n
+		return time.Date(int(year), 1, 1, 0, 0, 0, 0, b.loc).UnixMilli()
+	}
+}
+
+// next returns the start of the bucket after the one starting at start.
+func (b bucketing) next(start int64) int64 {
+	if !b.calendar() {
+		return start + b.width()
+	}
+	t := time.UnixMilli(start).In(b.loc)
+	var n time.Time
+	switch b.unit {
+	case "h":
+		n = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+int(b.n), 0, 0, 0, b.loc)
+	case "d":
+		n = time.Date(t.Year(), t.Month(), t.Day()+int(b.n), 0, 0, 0, 0, b.loc)
+	case "w":
+		n = time.Date(t.Year(), t.Month(), t.Day()+7*int(b.n), 0, 0, 0, 0, b.loc)
+	case "M":
+		n = time.Date(t.Year(), t.Month()+time.Month(b.n), 1, 0, 0, 0, 0, b.loc)
+	default:
+		n = time.Date(t.Year()+int(b.n), 1, 1, 0, 0, 0, 0, b.loc)
+	}
+	// A repeated wall clock hour can map back to the same bucket.
+	if next := b.start(n.UnixMilli()); next > start {
+		return next
+	}
+	return n.UnixMilli()
+}
+
+var (
+	bucketingCache   = make(map[string]bucketing)
+	bucketingCacheMu sync.RWMutex
+
+	timeBucketOnce sync.Once
+	timeBucketErr  error
+)
+
+// maxCachedBucketings bounds the parsed interval and time zone pairs kept.
+// A workspace only uses a handful, so the cache is simply reset when full.
+const maxCachedBucketings = 64
+
+func cachedBucketing(interval, tz string) (bucketing, error) {
+	key := interval + "|" + tz
+	bucketingCacheMu.RLock()
+	b, ok := bucketingCache[key]
+	bucketingCacheMu.RUnlock()
+	if ok {
+		return b, nil
+	}
+
+	b, err := parseBucketing(interval, tz)
+	if err != nil {
+		return bucketing{}, err
+	}
+	bucketingCacheMu.Lock()
+	if len(bucketingCache) >= maxCachedBucketings {
+		clear(bucketingCache)
+	}
+	bucketingCache[key] = b
+	bucketingCacheMu.Unlock()
+	return b, nil
+}
+
+// registerTimeBucketFunc registers time_bucket(ts, interval, tz), the start
+// of the calendar bucket holding ts.
+func registerTimeBucketFunc() error {
+	timeBucketOnce.Do(func() {
+		timeBucketErr = sqlite3.RegisterDeterministicScalarFunction("time_bucket", 3,
+			func(ctx *sqlite3.FunctionContext, args []driver.Value) (driver.Value, error) {
+				ts, ok := args[0].(int64)
+				if !ok {
+					return nil, nil
+				}
+				interval, _ := args[1].(string)
+				tz, _ := args[2].(string)
+				b, err := cachedBucketing(interval, tz)
+				if err != nil {
+					return nil, err
+				}
+				return b.start(ts), nil
+			},
+		)
+	})
+	return timeBucketErr
+}
@@ -0,0 +1,138 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"LogLens/internal/domain"
+)
+
+func TestBucketing_CalendarAlignment(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatalf("LoadLocation failed: %v", err)
+	}
+	at := func(s string) int64 {
+		ts, err := time.ParseInLocation("2006-01-02 15:04", s, ny)
+		if err != nil {
+			t.Fatalf("bad time %q: %v", s, err)
+		}
+		return ts.UnixMilli()
+	}
+
+	tests := []struct {
+		interval string
+		ts       string
+		start    string
+		next     string
+	}{
+		{"1d", "2024-03-10 15:30", "2024-03-10 00:00", "2024-03-11 00:00"}, // 23h DST day
+		{"1d", "2024-11-03 23:59", "2024-11-03 00:00", "2024-11-04 00:00"}, // 25h DST day
+		{"6h", "2024-03-10 05:00", "2024-03-10 00:00", "2024-03-10 06:00"},
+		{"1w", "2024-03-14 09:00", "2024-03-11 00:00", "2024-03-18 00:00"}, // Monday
+		{"1M", "2024-02-29 12:00", "2024-02-01 00:00", "2024-03-01 00:00"},
+		{"3M", "2024-05-20 12:00", "2024-04-01 00:00", "2024-07-01 00:00"},
+		{"1y", "2024-05-20 12:00", "2024-01-01 00:00", "2025-01-01 00:00"},
+	}
+	for _, tt := range tests {
+		b, err := parseBucketing(tt.interval, "America/New_York")
+		if err != nil {
+			t.Fatalf("parseBucketing(%s) failed: %v", tt.interval, err)
+		}
+		start := b.start(at(tt.ts))
+		if start != at(tt.start) {
+			t.Errorf("%s: start of %s = %s, want %s", tt.interval, tt.ts, time.UnixMilli(start).In(ny), tt.start)
+		}
+		if next := b.next(start); next != at(tt.next) {
+			t.Errorf("%s: next of %s = %s, want %s", tt.interval, tt.start, time.UnixMilli(next).In(ny), tt.next)
+		}
+	}
+}
+
+func TestBucketing_ParseErrors(t *testing.T) {
+	for _, interval := range []string{"", "d", "0d", "5x", "-1h"} {
+		if _, err := parseBucketing(interval, ""); err == nil {
+			t.Errorf("expected an error for interval %q", interval)
+		}
+	}
+	if _, err := parseBucketing("1d", "Mars/Olympus"); err == nil {
+		t.Error("expected an error for an unknown timezone")
+	}
+}
+
+func TestAutoBucketing(t *testing.T) {
+	hour := int64(time.Hour / time.Millisecond)
+	tests := []struct {
+		span     int64
+		points   int
+		interval string
+	}{
+		{hour, 100, "1m"},
+		{24 * hour, 100, "15m"},
+		{30 * 24 * hour, 100, "12h"},
+		{3 * 365 * 24 * hour, 50, "1M"},
+		{300 * 365 * 24 * hour, 100, "4y"},
+	}
+	for _, tt := range tests {
+		b, err := autoBucketing(0, tt.span-1, tt.points, "")
+		if err != nil {
+			t.Fatalf("autoBucketing failed: %v", err)
+		}
+		if b.String() != tt.interval {
+			t.Errorf("span %dh, %d points: expected %s, got %s", tt.span/hour, tt.points, tt.interval, b)
+		}
+	}
+}
+
+func TestTimelineSeries_CalendarInterval(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	// 22:00 UTC is 07:00 the next day in Tokyo.
+	storeRecords(t, storage, []domain.LogRecord{
+		{ID: "1", Timestamp: time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC).UnixMilli(), Level: "INFO", Message: "a", Raw: "a"},
+		{ID: "2", Timestamp: time.Date(2024, 1, 1, 22, 0, 0, 0, time.UTC).UnixMilli(), Level: "INFO", Message: "b", Raw: "b"},
+		{ID: "3", Timestamp: time.Date(2024, 1, 2, 10, 0, 0, 0, time.UTC).UnixMilli(), Level: "INFO", Message: "c", Raw: "c"},
+	})
+
+	utc, err := storage.TimelineSeries(context.Background(), domain.TimelineRequest{Interval: "1d"})
+	if err != nil {
+		t.Fatalf("TimelineSeries failed: %v", err)
+	}
+	if got := seriesCounts(utc.Series[0]); len(got) != 2 || got[0] != 2 || got[1] != 1 {
+		t.Errorf("UTC days: expected [2 1], got %v", got)
+	}
+
+	tokyo, err := storage.TimelineSeries(context.Background(), domain.TimelineRequest{Interval: "1d", Timezone: "Asia/Tokyo"})
+	if err != nil {
+		t.Fatalf("TimelineSeries failed: %v", err)
+	}
+	if tokyo.Interval != "1d" || tokyo.Timezone != "Asia/Tokyo" {
+		t.Errorf("expected effective bucket 1d in Asia/Tokyo, got %s in %s", tokyo.Interval, tokyo.Timezone)
+	}
+	if got := seriesCounts(tokyo.Series[0]); len(got) != 2 || got[0] != 1 || got[1] != 2 {
+		t.Errorf("Tokyo days: expected [1 2], got %v", got)
+	}
+	if want := time.Date(2024, 1, 1, 0, 0, 0, 0, time.FixedZone("JST", 9*3600)).UnixMilli(); tokyo.From != want {
+		t.Errorf("expected the range to start at Tokyo midnight, got %d", tokyo.From)
+	}
+}
+
+func TestTimelineSeries_AutoInterval(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	// 120 records one second apart.
+	storeRecords(t, storage, serviceRecords(120))
+
+	result, err := storage.TimelineSeries(context.Background(), domain.TimelineRequest{Points: 10})
+	if err != nil {
+		t.Fatalf("TimelineSeries failed: %v", err)
+	}
+	if result.Interval != "15s" || result.BucketMs != 15000 {
+		t.Errorf("expected 15s buckets, got %s (%dms)", result.Interval, result.BucketMs)
+	}
+	if n := len(result.Series[0].Points); n != 8 {
+		t.Errorf("expected 8 points, got %d", n)
+	}
+}
@@ -0,0 +1,532 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"LogLens/internal/domain"
+)
+
+// The sqlite driver matches each placeholder against every argument, so
+// binding cost grows quadratically with statement width; 64 rows per INSERT
+// measured fastest.
+const (
+	bulkBatchSize     = 20000
+	bulkRowsPerInsert = 64
+)
+
+// bulkPragmas are applied to the writer connection for the duration of a bulk
+// import. In WAL mode synchronous=NORMAL skips the fsync per commit but keeps
+// the database consistent after a crash; at worst the last batches are lost.
+var bulkPragmas = []struct{ name, value string }{
+	{"synchronous", "NORMAL"},
+	{"cache_size", "-262144"},
+	{"temp_store", "MEMORY"},
+}
+
+func (s *SQLiteStorage) storeBulk(ctx context.Context, records <-chan domain.LogRecord, opts domain.StoreOptions) (*domain.ImportResult, error) {
+	startTime := time.Now()
+
+	w, err := s.newBulkWriter(ctx, opts.Dedup)
+	if err != nil {
+		return nil, err
+	}
+	defer w.Close()
+
+	restore, err := s.tunePragmas(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer restore()
+
+	if opts.DeferIndexes {
+		if err := s.dropRecordIndexes(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	result := s.storeBatches(ctx, records, bulkBatchSize, w.writeBatch)
+
+	if opts.DeferIndexes {
+		// Rebuild even when ctx was cancelled so the database is left indexed.
+		if err := s.restoreRecordIndexes(context.Background()); err != nil {
+			result.Errors = append(result.Errors, err.Error())
+		}
+	}
+
+	result.Duration = time.Since(startTime).Milliseconds()
+	return result, nil
+}
+
+// tunePragmas applies bulkPragmas and returns a func restoring the previous
+// values.
+func (s *SQLiteStorage) tunePragmas(ctx context.Context) (func(), error) {
+	var saved []string
+	restore := func() {
+		for _, q := range saved {
+			if _, err := s.db.Exec(q); err != nil {
+				log.Printf("Failed to restore pragma: %v", err)
+			}
+		}
+	}
+
+	for _, p := range bulkPragmas {
+		var current string
+		if err := s.db.QueryRowContext(ctx, "PRAGMA "+p.name).Scan(&current); err != nil {
+			restore()
+			return nil, fmt.Errorf("failed to read pragma %s: %w", p.name, err)
+		}
+		if _, err := s.db.ExecContext(ctx, fmt.Sprintf("PRAGMA %s=%s", p.name, p.value)); err != nil {
+			restore()
+			return nil, fmt.Errorf("failed to set pragma %s: %w", p.name, err)
+		}
+		saved = append(saved, fmt.Sprintf("PRAGMA %s=%s", p.name, current))
+	}
+	return restore, nil
+}
+
+// dropRecordIndexes drops the secondary indexes on records and its partitions,
+// journaling their definitions in deferred_indexes so init can rebuild them
+// after a crash.
+func (s *SQLiteStorage) dropRecordIndexes(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT name, sql FROM sqlite_master WHERE type = 'index' AND (tbl_name = 'records' OR tbl_name IN (SELECT name FROM partitions)) AND sql IS NOT NULL")
+	if err != nil {
+		return fmt.Errorf("failed to list indexes: %w", err)
+	}
+	indexes := make(map[string]string)
+	for rows.Next() {
+		var name, def string
+		if err := rows.Scan(&name, &def); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to list indexes: %w", err)
+		}
+		indexes[name] = def
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to list indexes: %w", err)
+	}
+
+	for name, def := range indexes {
+		if _, err := tx.ExecContext(ctx, "INSERT OR REPLACE INTO deferred_indexes (name, sql) VALUES (?, ?)", name, def); err != nil {
+			return fmt.Errorf("failed to journal index %s: %w", name, err)
+		}
+		if _, err := tx.ExecContext(ctx, "DROP INDEX "+quoteIdent(name)); err != nil {
+			return fmt.Errorf("failed to drop index %s: %w", name, err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func (s *SQLiteStorage) restoreRecordIndexes(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT name, sql FROM deferred_indexes ORDER BY name")
+	if err != nil {
+		return fmt.Errorf("failed to read deferred indexes: %w", err)
+	}
+	var defs [][2]string
+	for rows.Next() {
+		var name, def string
+		if err := rows.Scan(&name, &def); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read deferred indexes: %w", err)
+		}
+		defs = append(defs, [2]string{name, def})
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read deferred indexes: %w", err)
+	}
+	if len(defs) == 0 {
+		return nil
+	}
+
+	for _, d := range defs {
+		if _, err := tx.ExecContext(ctx, d[1]); err != nil {
+			return fmt.Errorf("failed to rebuild index %s: %w", d[0], err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM deferred_indexes WHERE name = ?", d[0]); err != nil {
+			return fmt.Errorf("failed to rebuild index %s: %w", d[0], err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.ReplaceAll(name, `"`, `""`) + `"`
+}
+
+// bulkWriter inserts bulkRowsPerInsert records per statement. keep-both is
+// not supported because it needs a per-row retry on conflict.
+type bulkWriter struct {
+	s      *SQLiteStorage
+	dedup  domain.DedupPolicy
+	verb   string
+	insert *sql.Stmt
+	exists *sql.Stmt
+	args   []interface{}
+}
+
+func (s *SQLiteStorage) newBulkWriter(ctx context.Context, dedup domain.DedupPolicy) (*bulkWriter, error) {
+	w := &bulkWriter{s: s, dedup: dedup, args: make([]interface{}, 0, bulkRowsPerInsert*recordColumnCount)}
+	switch dedup {
+	case "", domain.DedupReplace:
+		w.dedup = domain.DedupReplace
+		w.verb = "INSERT OR REPLACE"
+	case domain.DedupSkip:
+		w.verb = "INSERT OR IGNORE"
+	case domain.DedupKeepBoth:
+		return nil, fmt.Errorf("dedup policy %s is not supported in bulk mode", dedup)
+	default:
+		return nil, fmt.Errorf("unsupported dedup policy: %s", dedup)
+	}
+
+	var err error
+	w.insert, err = s.db.PrepareContext(ctx, w.insertSQL("records", bulkRowsPerInsert))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+	w.exists, err = s.db.PrepareContext(ctx, existingSQL("records"))
+	if err != nil {
+		w.insert.Close()
+		return nil, fmt.Errorf("failed to prepare lookup statement: %w", err)
+	}
+	return w, nil
+}
+
+func (w *bulkWriter) Close() {
+	w.insert.Close()
+	w.exists.Close()
+}
+
+func (w *bulkWriter) insertSQL(table string, rows int) string {
+	var b strings.Builder
+	b.WriteString(w.verb)
+	b.WriteString(" INTO " + table + " (" + recordColumns + ") VALUES ")
+	for i := 0; i < rows; i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString("(?, ?, ?, ?, ?, ?, ?, ?, ?)")
+	}
+	return b.String()
+}
+
+// existingSQL looks up which of a JSON array of IDs are stored, and where.
+// IDs of partitioned records are unique across partitions, so those are
+// looked up in partition_ids, ignoring entries left by dropped partitions.
+func existingSQL(table string) string {
+	if table != "records" {
+		return "SELECT id, part FROM partition_ids WHERE id IN (SELECT value FROM json_each(?)) AND part IN (SELECT name FROM partitions)"
+	}
+	return "SELECT id, 'records' FROM records WHERE id IN (SELECT value FROM json_each(?))"
+}
+
+// txStatements prepares statements on demand inside one bulk transaction,
+// reusing the long-lived ones for the records table.
+type txStatements struct {
+	ctx     context.Context
+	tx      *sql.Tx
+	w       *bulkWriter
+	cache   map[string]*sql.Stmt
+	enc     *recordEncoder
+	catalog *catalogDelta
+}
+
+func (t *txStatements) get(key string, pre *sql.Stmt, query string) (*sql.Stmt, error) {
+	if stmt, ok := t.cache[key]; ok {
+		return stmt, nil
+	}
+	var stmt *sql.Stmt
+	if pre != nil {
+		stmt = t.tx.StmtContext(t.ctx, pre)
+	} else {
+		var err error
+		if stmt, err = t.tx.PrepareContext(t.ctx, query); err != nil {
+			return nil, fmt.Errorf("failed to prepare statement: %w", err)
+		}
+	}
+	t.cache[key] = stmt
+	return stmt, nil
+}
+
+func (t *txStatements) insert(table string, rows int) (*sql.Stmt, error) {
+	var pre *sql.Stmt
+	if table == "records" && rows == bulkRowsPerInsert {
+		pre = t.w.insert
+	}
+	return t.get(fmt.Sprintf("insert/%s/%d", table, rows), pre, t.w.insertSQL(table, rows))
+}
+
+func (t *txStatements) exists(table string) (*sql.Stmt, error) {
+	if table != "records" {
+		return t.get("exists/partitions", nil, existingSQL(table))
+	}
+	return t.get("exists/records", t.w.exists, existingSQL(table))
+}
+
+// claim returns the statement registering a JSON array of IDs as stored in a
+// partition. Callers only pass IDs they may take, so any entry still holding
+// one belongs to the record being replaced or to a dropped partition.
+func (t *txStatements) claim() (*sql.Stmt, error) {
+	return t.get("claim", nil, "INSERT OR REPLACE INTO partition_ids (id, part) SELECT value, ? FROM json_each(?)")
+}
+
+func (w *bulkWriter) writeBatch(ctx context.Context, batch []domain.LogRecord) (int64, error) {
+	if err := w.s.prepareBatch(ctx, batch); err != nil {
+		return 0, err
+	}
+
+	tx, err := w.s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	stmts := &txStatements{ctx: ctx, tx: tx, w: w, cache: make(map[string]*sql.Stmt), enc: w.s.newRecordEncoder(tx), catalog: newCatalogDelta()}
+	var duplicates int64
+	for _, group := range w.s.groupByTable(batch) {
+		added, err := w.writeGroup(ctx, stmts, group.table, group.records)
+		if err != nil {
+			return 0, err
+		}
+		for _, a := range added {
+			if !a {
+				duplicates++
+			}
+		}
+	}
+
+	if err := stmts.enc.flush(ctx); err != nil {
+		return 0, err
+	}
+	if err := w.s.applyCatalog(ctx, tx, stmts.catalog); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	stmts.enc.committed(duplicates)
+	return duplicates, nil
+}
+
+// writeGroup inserts records into table and reports which of them added a
+// row. Both policies keep one row per ID, so a record adds a row only when its
+// ID is neither stored nor repeated earlier in the group. For a partition,
+// IDs held by other partitions are skipped, or moved here on replace.
+func (w *bulkWriter) writeGroup(ctx context.Context, stmts *txStatements, table string, records []domain.LogRecord) ([]bool, error) {
+	added := make([]bool, len(records))
+	seen := make(map[string]struct{}, len(records))
+	for start := 0; start < len(records); start += bulkRowsPerInsert {
+		end := start + bulkRowsPerInsert
+		if end > len(records) {
+			end = len(records)
+		}
+		chunk := records[start:end]
+
+		exists, err := stmts.exists(table)
+		if err != nil {
+			return nil, err
+		}
+		existing, err := existingIDs(ctx, exists, chunk)
+		if err != nil {
+			return nil, err
+		}
+		for i, record := range chunk {
+			if _, ok := seen[record.ID]; ok {
+				continue
+			}
+			seen[record.ID] = struct{}{}
+			if _, ok := existing[record.ID]; !ok {
+				added[start+i] = true
+			}
+		}
+		if err := w.updateCatalog(ctx, stmts, chunk, added[start:end], existing); err != nil {
+			return nil, err
+		}
+
+		if table != "records" {
+			if chunk, err = w.claimPartitionIDs(ctx, stmts, table, chunk, added[start:end], existing); err != nil {
+				return nil, err
+			}
+			if len(chunk) == 0 {
+				continue
+			}
+		}
+
+		w.args = w.args[:0]
+		for _, record := range chunk {
+			var err error
+			if w.args, err = stmts.enc.appendRecordArgs(ctx, w.args, record); err != nil {
+				return nil, err
+			}
+		}
+
+		stmt, err := stmts.insert(table, len(chunk))
+		if err != nil {
+			return nil, err
+		}
+		if _, err := stmt.ExecContext(ctx, w.args...); err != nil {
+			return nil, fmt.Errorf("failed to insert records %s..%s: %w", chunk[0].ID, chunk[len(chunk)-1].ID, err)
+		}
+	}
+	return added, nil
+}
+
+// updateCatalog records chunk's effect on the field catalog before it is
+// written: on replace the stored rows are forgotten and the last record per ID
+// observed, on skip only the records that add a row are observed.
+func (w *bulkWriter) updateCatalog(ctx context.Context, stmts *txStatements, chunk []domain.LogRecord, added []bool, existing map[string]string) error {
+	if w.dedup == domain.DedupSkip {
+		for i, record := range chunk {
+			if added[i] {
+				stmts.catalog.observed.Observe(record)
+			}
+		}
+		return nil
+	}
+
+	byOwner := make(map[string][]string)
+	for id, owner := range existing {
+		byOwner[owner] = append(byOwner[owner], id)
+	}
+	for owner, ids := range byOwner {
+		if err := stmts.catalog.forgetStored(ctx, stmts.tx, owner, ids); err != nil {
+			return err
+		}
+	}
+	last := make(map[string]int, len(chunk))
+	for i, record := range chunk {
+		last[record.ID] = i
+	}
+	for i, record := range chunk {
+		if last[record.ID] == i {
+			stmts.catalog.observed.Observe(record)
+		}
+	}
+	return nil
+}
+
+type tableGroup struct {
+	table     string
+	records   []domain.LogRecord
+	positions []int
+}
+
+// groupByTable splits batch by destination table, keeping record order
+// within each table. positions holds each record's index in batch.
+func (s *SQLiteStorage) groupByTable(batch []domain.LogRecord) []tableGroup {
+	index := make(map[string]int)
+	var groups []tableGroup
+	for pos, record := range batch {
+		table := s.tableFor(record.Timestamp)
+		i, ok := index[table]
+		if !ok {
+			i = len(groups)
+			index[table] = i
+			groups = append(groups, tableGroup{table: table})
+		}
+		groups[i].records = append(groups[i].records, record)
+		groups[i].positions = append(groups[i].positions, pos)
+	}
+	return groups
+}
+
+// claimPartitionIDs claims chunk's IDs for table and returns the records to
+// insert. added and existing describe chunk as writeGroup found it.
+func (w *bulkWriter) claimPartitionIDs(ctx context.Context, stmts *txStatements, table string, chunk []domain.LogRecord, added []bool, existing map[string]string) ([]domain.LogRecord, error) {
+	var ids []string
+	switch w.dedup {
+	case domain.DedupSkip:
+		kept := make([]domain.LogRecord, 0, len(chunk))
+		for i, record := range chunk {
+			if added[i] {
+				kept = append(kept, record)
+				ids = append(ids, record.ID)
+			}
+		}
+		chunk = kept
+	default:
+		moved := make(map[string][]string)
+		for _, record := range chunk {
+			ids = append(ids, record.ID)
+			if owner, ok := existing[record.ID]; ok && owner != table {
+				moved[owner] = append(moved[owner], record.ID)
+			}
+		}
+		for owner, movedIDs := range moved {
+			movedJSON, _ := json.Marshal(movedIDs)
+			if _, err := stmts.tx.ExecContext(ctx, "DELETE FROM "+owner+" WHERE id IN (SELECT value FROM json_each(?))", string(movedJSON)); err != nil {
+				return nil, fmt.Errorf("failed to move records from %s: %w", owner, err)
+			}
+		}
+	}
+	if len(ids) == 0 {
+		return chunk, nil
+	}
+
+	claim, err := stmts.claim()
+	if err != nil {
+		return nil, err
+	}
+	idsJSON, _ := json.Marshal(ids)
+	if _, err := claim.ExecContext(ctx, table, string(idsJSON)); err != nil {
+		return nil, fmt.Errorf("failed to claim record ids: %w", err)
+	}
+	return chunk, nil
+}
+
+// existingIDs maps the IDs in chunk that are already stored to the table
+// holding them.
+func existingIDs(ctx context.Context, exists *sql.Stmt, chunk []domain.LogRecord) (map[string]string, error) {
+	ids := make([]string, len(chunk))
+	for i, record := range chunk {
+		ids[i] = record.ID
+	}
+	idsJSON, _ := json.Marshal(ids)
+
+	rows, err := exists.QueryContext(ctx, string(idsJSON))
+	if err != nil {
+		return nil, fmt.Errorf("failed to look up existing records: %w", err)
+	}
+	defer rows.Close()
+
+	existing := make(map[string]string)
+	for rows.Next() {
+		var id, table string
+		if err := rows.Scan(&id, &table); err != nil {
+			return nil, fmt.Errorf("failed to look up existing records: %w", err)
+		}
+		existing[id] = table
+	}
+	return existing, rows.Err()
+}
+
+func encodeFields(fields map[string]interface{}) (string, error) {
+	if fields == nil {
+		return "null", nil
+	}
+	if len(fields) == 0 {
+		return "{}", nil
+	}
+	data, err := json.Marshal(fields)
+	return string(data), err
+}
@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"LogLens/internal/domain"
+)
+
+func storeWithOptions(t *testing.T, storage *SQLiteStorage, records []domain.LogRecord, opts domain.StoreOptions) *domain.ImportResult {
+	t.Helper()
+	ch := make(chan domain.LogRecord, len(records))
+	for _, r := range records {
+		ch <- r
+	}
+	close(ch)
+
+	result, err := storage.StoreWithOptions(context.Background(), ch, opts)
+	if err != nil {
+		t.Fatalf("StoreWithOptions failed: %v", err)
+	}
+	if len(result.Errors) > 0 {
+		t.Fatalf("unexpected store errors: %v", result.Errors)
+	}
+	return result
+}
+
+func countIndexes(t *testing.T, storage *SQLiteStorage) int {
+	t.Helper()
+	var n int
+	if err := storage.db.QueryRow("SELECT COUNT(*) FROM sqlite_master WHERE type = 'index' AND tbl_name = 'records' AND sql IS NOT NULL").Scan(&n); err != nil {
+		t.Fatalf("failed to count indexes: %v", err)
+	}
+	return n
+}
+
+func TestSQLiteStorage_BulkStore(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	records := newRecords(1234)
+	records[5].Fields = map[string]interface{}{"user": "alice"}
+	indexes := countIndexes(t, storage)
+
+	result := storeWithOptions(t, storage, records, domain.StoreOptions{Bulk: true, DeferIndexes: true})
+	if result.Processed != 1234 {
+		t.Errorf("expected 1234 processed, got %d", result.Processed)
+	}
+
+	record, err := storage.GetRecord(context.Background(), "rec_5")
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if record.Fields["user"] != "alice" {
+		t.Errorf("fields not stored: %v", record.Fields)
+	}
+
+	if got := countIndexes(t, storage); got != indexes {
+		t.Errorf("expected %d indexes rebuilt, got %d", indexes, got)
+	}
+	var pending int
+	storage.db.QueryRow("SELECT COUNT(*) FROM deferred_indexes").Scan(&pending)
+	if pending != 0 {
+		t.Errorf("expected index journal to be empty, got %d", pending)
+	}
+
+	var sync int
+	storage.db.QueryRow("PRAGMA synchronous").Scan(&sync)
+	if sync == 0 {
+		t.Error("synchronous pragma was not restored")
+	}
+}
+
+func TestSQLiteStorage_BulkStoreDedup(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	storeRecords(t, storage, newRecords(10))
+
+	replaced := storeWithOptions(t, storage, newRecords(300), domain.StoreOptions{Bulk: true})
+	if replaced.Duplicates != 10 {
+		t.Errorf("expected 10 replaced duplicates, got %d", replaced.Duplicates)
+	}
+
+	skipped := storeWithOptions(t, storage, newRecords(400), domain.StoreOptions{Bulk: true, Dedup: domain.DedupSkip})
+	if skipped.Duplicates != 300 {
+		t.Errorf("expected 300 skipped duplicates, got %d", skipped.Duplicates)
+	}
+
+	count, err := storage.GetTotalCount(context.Background())
+	if err != nil {
+		t.Fatalf("GetTotalCount failed: %v", err)
+	}
+	if count != 400 {
+		t.Errorf("expected 400 records, got %d", count)
+	}
+
+	ch := make(chan domain.LogRecord)
+	close(ch)
+	if _, err := storage.StoreWithOptions(context.Background(), ch, domain.StoreOptions{Bulk: true, Dedup: domain.DedupKeepBoth}); err == nil {
+		t.Error("expected keep-both to be rejected in bulk mode")
+	}
+}
+
+func TestSQLiteStorage_DeferredIndexesRecoveredOnOpen(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	indexes := countIndexes(t, storage)
+	if err := storage.dropRecordIndexes(context.Background()); err != nil {
+		t.Fatalf("dropRecordIndexes failed: %v", err)
+	}
+	if got := countIndexes(t, storage); got != 0 {
+		t.Fatalf("expected indexes dropped, got %d", got)
+	}
+	storage.Close()
+
+	reopened, err := NewSQLiteStorage(storage.dbPath)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer reopened.Close()
+	if got := countIndexes(t, reopened); got != indexes {
+		t.Errorf("expected %d indexes after recovery, got %d", indexes, got)
+	}
+}
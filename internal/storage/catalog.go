@@ -0,0 +1,422 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"LogLens/internal/domain"
+)
+
+const (
+	catalogMaxPaths   = 2000
+	catalogMaxSamples = 5
+	catalogMaxSample  = 120
+)
+
+type catalogEntry struct {
+	types   map[string]int64
+	samples []string
+	seen    int64
+	nulls   int64
+	sketch  *hyperLogLog
+}
+
+func newCatalogEntry() *catalogEntry {
+	return &catalogEntry{types: make(map[string]int64), sketch: newHyperLogLog()}
+}
+
+func (e *catalogEntry) addSample(sample string) {
+	if len(e.samples) >= catalogMaxSamples {
+		return
+	}
+	if len(sample) > catalogMaxSample {
+		sample = sample[:catalogMaxSample]
+	}
+	for _, existing := range e.samples {
+		if existing == sample {
+			return
+		}
+	}
+	e.samples = append(e.samples, sample)
+}
+
+func (e *catalogEntry) merge(other *catalogEntry) {
+	for t, n := range other.types {
+		e.types[t] += n
+	}
+	for _, sample := range other.samples {
+		e.addSample(sample)
+	}
+	e.seen += other.seen
+	e.nulls += other.nulls
+	e.sketch.Merge(other.sketch)
+}
+
+// subtract removes the counts of other. Samples and the sketch cannot be
+// taken back and are left as they are.
+func (e *catalogEntry) subtract(other *catalogEntry) {
+	for t, n := range other.types {
+		if e.types[t] -= n; e.types[t] <= 0 {
+			delete(e.types, t)
+		}
+	}
+	e.seen -= other.seen
+	e.nulls -= other.nulls
+	if e.nulls < 0 {
+		e.nulls = 0
+	}
+}
+
+// fieldCatalogBuilder accumulates field statistics for a batch of records
+// so that the catalog tables are touched once per batch, not once per row.
+type fieldCatalogBuilder struct {
+	sources map[string]*sourceCatalog
+}
+
+type sourceCatalog struct {
+	records int64
+	fields  map[string]*catalogEntry
+}
+
+func newFieldCatalogBuilder() *fieldCatalogBuilder {
+	return &fieldCatalogBuilder{sources: make(map[string]*sourceCatalog)}
+}
+
+func (b *fieldCatalogBuilder) Observe(record domain.LogRecord) {
+	sc, ok := b.sources[record.Source]
+	if !ok {
+		sc = &sourceCatalog{fields: make(map[string]*catalogEntry)}
+		b.sources[record.Source] = sc
+	}
+	sc.records++
+	sc.observeMap("", record.Fields)
+}
+
+func (sc *sourceCatalog) observeMap(prefix string, fields map[string]interface{}) {
+	for key, value := range fields {
+		path := key
+		if prefix != "" {
+			path = prefix + "." + key
+		}
+
+		entry, ok := sc.fields[path]
+		if !ok {
+			if len(sc.fields) >= catalogMaxPaths {
+				continue
+			}
+			entry = newCatalogEntry()
+			sc.fields[path] = entry
+		}
+		entry.seen++
+
+		typ := fieldType(value)
+		entry.types[typ]++
+		switch v := value.(type) {
+		case nil:
+			entry.nulls++
+		case map[string]interface{}:
+			sc.observeMap(path, v)
+		default:
+			str := fieldString(v)
+			entry.sketch.Add(str)
+			entry.addSample(str)
+		}
+	}
+}
+
+func fieldType(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	case float64, float32, int, int32, int64, uint, uint32, uint64, json.Number:
+		return "number"
+	case map[string]interface{}:
+		return "object"
+	case []interface{}:
+		return "array"
+	default:
+		return "string"
+	}
+}
+
+func fieldString(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case []interface{}:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
+
+// catalogDelta is what one write transaction changes in the catalog: the
+// records it stored and the stored rows those overwrote.
+type catalogDelta struct {
+	observed  *fieldCatalogBuilder
+	forgotten *fieldCatalogBuilder
+}
+
+func newCatalogDelta() *catalogDelta {
+	return &catalogDelta{observed: newFieldCatalogBuilder(), forgotten: newFieldCatalogBuilder()}
+}
+
+// observeRows adds the records that selectQ returns, as source and inflated
+// fields, to b.
+func observeRows(ctx context.Context, tx *sql.Tx, b *fieldCatalogBuilder, selectQ string, args ...interface{}) error {
+	rows, err := tx.QueryContext(ctx, selectQ, args...)
+	if err != nil {
+		return fmt.Errorf("failed to read records for the catalog: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var record domain.LogRecord
+		var fieldsJSON sql.NullString
+		if err := rows.Scan(&record.Source, &fieldsJSON); err != nil {
+			return fmt.Errorf("failed to read records for the catalog: %w", err)
+		}
+		if fieldsJSON.Valid {
+			json.Unmarshal([]byte(fieldsJSON.String), &record.Fields)
+		}
+		b.Observe(record)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read records for the catalog: %w", err)
+	}
+	return nil
+}
+
+// forgetStored adds the rows of table with ids to d.forgotten. It runs before
+// they are replaced.
+func (d *catalogDelta) forgetStored(ctx context.Context, tx *sql.Tx, table string, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	idsJSON, _ := json.Marshal(ids)
+	return observeRows(ctx, tx, d.forgotten, "SELECT source, "+columnExpr("fields")+" FROM "+table+" WHERE id IN (SELECT value FROM json_each(?))", string(idsJSON))
+}
+
+// applyCatalog writes d to the catalog tables in the transaction that made
+// the changes, so the catalog always matches the committed rows. Fields and
+// sources left without records are dropped; distinct estimates and samples
+// may still reflect overwritten or deleted records.
+func (s *SQLiteStorage) applyCatalog(ctx context.Context, tx *sql.Tx, d *catalogDelta) error {
+	sources := make(map[string]bool)
+	for source := range d.observed.sources {
+		sources[source] = true
+	}
+	for source := range d.forgotten.sources {
+		sources[source] = true
+	}
+	if len(sources) == 0 {
+		return nil
+	}
+
+	for source := range sources {
+		added, removed := d.observed.sources[source], d.forgotten.sources[source]
+		var delta int64
+		paths := make(map[string]bool)
+		if added != nil {
+			delta += added.records
+			for path := range added.fields {
+				paths[path] = true
+			}
+		}
+		if removed != nil {
+			delta -= removed.records
+			for path := range removed.fields {
+				paths[path] = true
+			}
+		}
+		if _, err := tx.ExecContext(ctx, `
+			INSERT INTO catalog_sources (source, records) VALUES (?, ?)
+			ON CONFLICT(source) DO UPDATE SET records = records + excluded.records
+		`, source, delta); err != nil {
+			return fmt.Errorf("failed to update catalog source %s: %w", source, err)
+		}
+
+		for path := range paths {
+			entry, err := loadCatalogEntry(ctx, tx, source, path)
+			if err != nil {
+				return err
+			}
+			if entry == nil {
+				entry = newCatalogEntry()
+			}
+			if added != nil && added.fields[path] != nil {
+				entry.merge(added.fields[path])
+			}
+			if removed != nil && removed.fields[path] != nil {
+				entry.subtract(removed.fields[path])
+			}
+			if entry.seen > 0 {
+				err = storeCatalogEntry(ctx, tx, source, path, entry)
+			} else {
+				_, err = tx.ExecContext(ctx, "DELETE FROM field_catalog WHERE source = ? AND path = ?", source, path)
+			}
+			if err != nil {
+				return fmt.Errorf("failed to update catalog field %s: %w", path, err)
+			}
+		}
+	}
+
+	if _, err := tx.ExecContext(ctx, `
+		DELETE FROM catalog_sources WHERE records <= 0;
+		DELETE FROM field_catalog WHERE source NOT IN (SELECT source FROM catalog_sources);
+	`); err != nil {
+		return fmt.Errorf("failed to prune field catalog: %w", err)
+	}
+	return nil
+}
+
+func storeCatalogEntry(ctx context.Context, tx *sql.Tx, source, path string, entry *catalogEntry) error {
+	typesJSON, _ := json.Marshal(entry.types)
+	samplesJSON, _ := json.Marshal(entry.samples)
+	if _, err := tx.ExecContext(ctx, `
+		INSERT OR REPLACE INTO field_catalog (source, path, types, samples, seen, nulls, sketch)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`, source, path, string(typesJSON), string(samplesJSON), entry.seen, entry.nulls, entry.sketch.Bytes()); err != nil {
+		return fmt.Errorf("failed to store catalog field %s: %w", path, err)
+	}
+	return nil
+}
+
+// forgetRecords takes the records that selectQ returns, as source and inflated
+// fields, out of the catalog. It runs in the deleting transaction, before the
+// delete.
+func (s *SQLiteStorage) forgetRecords(ctx context.Context, tx *sql.Tx, selectQ string, args ...interface{}) error {
+	d := newCatalogDelta()
+	if err := observeRows(ctx, tx, d.forgotten, selectQ, args...); err != nil {
+		return err
+	}
+	return s.applyCatalog(ctx, tx, d)
+}
+
+// rebuildCatalog recomputes the catalog from the stored records, dropping
+// what partition drops left behind and the distinct values and samples of
+// deleted records.
+func (s *SQLiteStorage) rebuildCatalog(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM field_catalog; DELETE FROM catalog_sources;"); err != nil {
+		return fmt.Errorf("failed to clear field catalog: %w", err)
+	}
+	d := newCatalogDelta()
+	for _, table := range s.recordTables(nil) {
+		if err := observeRows(ctx, tx, d.observed, "SELECT source, "+columnExpr("fields")+" FROM "+table); err != nil {
+			return err
+		}
+	}
+	if err := s.applyCatalog(ctx, tx, d); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+func loadCatalogEntry(ctx context.Context, tx *sql.Tx, source, path string) (*catalogEntry, error) {
+	var typesJSON, samplesJSON string
+	var sketch []byte
+	entry := newCatalogEntry()
+
+	err := tx.QueryRowContext(ctx, `
+		SELECT types, samples, seen, nulls, sketch FROM field_catalog WHERE source = ? AND path = ?
+	`, source, path).Scan(&typesJSON, &samplesJSON, &entry.seen, &entry.nulls, &sketch)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load catalog field %s: %w", path, err)
+	}
+
+	json.Unmarshal([]byte(typesJSON), &entry.types)
+	json.Unmarshal([]byte(samplesJSON), &entry.samples)
+	entry.sketch = hyperLogLogFromBytes(sketch)
+	return entry, nil
+}
+
+// GetFieldCatalog returns every custom field path observed for source, or for
+// all sources merged together when source is empty.
+func (s *SQLiteStorage) GetFieldCatalog(ctx context.Context, source string) ([]domain.FieldInfo, error) {
+	var total int64
+	countQuery := "SELECT COALESCE(SUM(records), 0) FROM catalog_sources"
+	fieldsQuery := "SELECT path, types, samples, seen, nulls, sketch FROM field_catalog"
+	var args []interface{}
+	if source != "" {
+		countQuery += " WHERE source = ?"
+		fieldsQuery += " WHERE source = ?"
+		args = append(args, source)
+	}
+
+	if err := s.rdb.QueryRowContext(ctx, countQuery, args...).Scan(&total); err != nil {
+		return nil, fmt.Errorf("failed to count catalog records: %w", err)
+	}
+
+	rows, err := s.rdb.QueryContext(ctx, fieldsQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query field catalog: %w", err)
+	}
+	defer rows.Close()
+
+	merged := make(map[string]*catalogEntry)
+	for rows.Next() {
+		var path, typesJSON, samplesJSON string
+		var sketch []byte
+		entry := newCatalogEntry()
+		if err := rows.Scan(&path, &typesJSON, &samplesJSON, &entry.seen, &entry.nulls, &sketch); err != nil {
+			return nil, fmt.Errorf("failed to scan catalog row: %w", err)
+		}
+		json.Unmarshal([]byte(typesJSON), &entry.types)
+		json.Unmarshal([]byte(samplesJSON), &entry.samples)
+		entry.sketch = hyperLogLogFromBytes(sketch)
+
+		if existing, ok := merged[path]; ok {
+			existing.merge(entry)
+		} else {
+			merged[path] = entry
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("catalog rows error: %w", err)
+	}
+
+	catalog := make([]domain.FieldInfo, 0, len(merged))
+	for path, entry := range merged {
+		info := domain.FieldInfo{
+			Path:     path,
+			Types:    entry.types,
+			Samples:  entry.samples,
+			Seen:     entry.seen,
+			Nulls:    entry.nulls,
+			Distinct: entry.sketch.Estimate(),
+		}
+		if total > 0 {
+			missing := total - entry.seen
+			if missing < 0 {
+				missing = 0
+			}
+			info.NullRatio = float64(missing+entry.nulls) / float64(total)
+		}
+		catalog = append(catalog, info)
+	}
+
+	sort.Slice(catalog, func(i, j int) bool {
+		return catalog[i].Path < catalog[j].Path
+	})
+
+	return catalog, nil
+}
@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"LogLens/internal/domain"
+)
+
+func TestSQLiteStorage_FieldCatalog(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	records := []domain.LogRecord{
+		{ID: "1", Timestamp: 1000, Level: "INFO", Message: "m", Source: "a.log", Raw: "r",
+			Fields: map[string]interface{}{"status": float64(200), "user": "alice", "http": map[string]interface{}{"method": "GET"}}},
+		{ID: "2", Timestamp: 2000, Level: "INFO", Message: "m", Source: "a.log", Raw: "r",
+			Fields: map[string]interface{}{"status": float64(500), "user": nil}},
+		{ID: "3", Timestamp: 3000, Level: "INFO", Message: "m", Source: "a.log", Raw: "r",
+			Fields: map[string]interface{}{"status": "n/a", "ok": true}},
+		{ID: "4", Timestamp: 4000, Level: "INFO", Message: "m", Source: "b.log", Raw: "r",
+			Fields: map[string]interface{}{"status": float64(404)}},
+	}
+	storeRecords(t, storage, records)
+
+	catalog, err := storage.GetFieldCatalog(context.Background(), "a.log")
+	if err != nil {
+		t.Fatalf("GetFieldCatalog failed: %v", err)
+	}
+
+	byPath := make(map[string]domain.FieldInfo)
+	for _, info := range catalog {
+		byPath[info.Path] = info
+	}
+
+	for _, path := range []string{"status", "user", "http", "http.method", "ok"} {
+		if _, ok := byPath[path]; !ok {
+			t.Errorf("expected path %q in catalog", path)
+		}
+	}
+
+	status := byPath["status"]
+	if status.Types["number"] != 2 || status.Types["string"] != 1 {
+		t.Errorf("unexpected status types: %v", status.Types)
+	}
+	if status.Distinct != 3 {
+		t.Errorf("expected 3 distinct status values, got %d", status.Distinct)
+	}
+	if status.NullRatio != 0 {
+		t.Errorf("expected status null ratio 0, got %f", status.NullRatio)
+	}
+
+	user := byPath["user"]
+	if user.Nulls != 1 {
+		t.Errorf("expected 1 null user, got %d", user.Nulls)
+	}
+	// one null and one missing out of three records
+	if got := user.NullRatio; got < 0.66 || got > 0.67 {
+		t.Errorf("expected user null ratio ~0.667, got %f", got)
+	}
+	if byPath["http"].Types["object"] != 1 {
+		t.Errorf("expected http to be an object, got %v", byPath["http"].Types)
+	}
+
+	all, err := storage.GetFieldCatalog(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetFieldCatalog failed: %v", err)
+	}
+	for _, info := range all {
+		if info.Path == "status" && info.Seen != 4 {
+			t.Errorf("expected status seen 4 across sources, got %d", info.Seen)
+		}
+	}
+}
+
+func TestSQLiteStorage_FieldCatalogAccumulates(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	for batch := 0; batch < 2; batch++ {
+		records := make([]domain.LogRecord, 500)
+		for i := range records {
+			n := batch*500 + i
+			records[i] = domain.LogRecord{
+				ID: fmt.Sprintf("r%d", n), Timestamp: int64(n), Level: "INFO", Message: "m", Raw: "r",
+				Fields: map[string]interface{}{"request_id": fmt.Sprintf("req-%d", n)},
+			}
+		}
+		storeRecords(t, storage, records)
+	}
+
+	catalog, err := storage.GetFieldCatalog(context.Background(), "")
+	if err != nil {
+		t.Fatalf("GetFieldCatalog failed: %v", err)
+	}
+	if len(catalog) != 1 {
+		t.Fatalf("expected 1 field, got %d", len(catalog))
+	}
+	if catalog[0].Seen != 1000 {
+		t.Errorf("expected seen 1000, got %d", catalog[0].Seen)
+	}
+	if d := catalog[0].Distinct; d < 950 || d > 1050 {
+		t.Errorf("expected ~1000 distinct values, got %d", d)
+	}
+}
+
+func TestSQLiteStorage_FieldCatalogFollowsStoredRows(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	records := []domain.LogRecord{
+		{ID: "1", Timestamp: 1000, Level: "INFO", Message: "m", Source: "a.log", Raw: "r", Fields: map[string]interface{}{"k": "x", "n": nil}},
+		{ID: "2", Timestamp: 2000, Level: "DEBUG", Message: "m", Source: "a.log", Raw: "r", Fields: map[string]interface{}{"k": "y"}},
+		{ID: "3", Timestamp: 3000, Level: "DEBUG", Message: "m", Source: "a.log", Raw: "r", Fields: map[string]interface{}{"k": "z", "n": nil}},
+	}
+	storeRecords(t, storage, records)
+	// Re-imports replace or skip the stored rows and must not count them again.
+	storeRecords(t, storage, records)
+	storeWithOptions(t, storage, records, domain.StoreOptions{Dedup: domain.DedupSkip})
+	storeWithOptions(t, storage, records, domain.StoreOptions{Bulk: true})
+	storeWithOptions(t, storage, records, domain.StoreOptions{Bulk: true, Dedup: domain.DedupSkip})
+
+	fieldsByPath := func() map[string]domain.FieldInfo {
+		t.Helper()
+		catalog, err := storage.GetFieldCatalog(ctx, "a.log")
+		if err != nil {
+			t.Fatalf("GetFieldCatalog failed: %v", err)
+		}
+		byPath := make(map[string]domain.FieldInfo)
+		for _, info := range catalog {
+			byPath[info.Path] = info
+		}
+		return byPath
+	}
+	if k := fieldsByPath()["k"]; k.Seen != 3 || k.Types["string"] != 3 {
+		t.Errorf("expected k seen 3 times after re-imports, got %+v", k)
+	}
+
+	filters := []domain.FilterCondition{{Type: domain.FilterEquality, Field: "level", Value: "DEBUG"}}
+	if _, err := storage.DeleteByQuery(ctx, filters, false); err != nil {
+		t.Fatalf("DeleteByQuery failed: %v", err)
+	}
+	byPath := fieldsByPath()
+	if k := byPath["k"]; k.Seen != 1 || k.Types["string"] != 1 || k.NullRatio != 0 {
+		t.Errorf("expected k seen once after the delete, got %+v", k)
+	}
+	if n := byPath["n"]; n.Seen != 1 || n.Nulls != 1 || n.NullRatio != 1 {
+		t.Errorf("expected one null n after the delete, got %+v", n)
+	}
+
+	if err := storage.SetRetentionPolicies(ctx, []domain.RetentionPolicy{{Source: "a.log", MaxRows: 1}}); err != nil {
+		t.Fatalf("SetRetentionPolicies failed: %v", err)
+	}
+	storeRecords(t, storage, records[1:])
+	if _, err := storage.ApplyRetention(ctx, false); err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	byPath = fieldsByPath()
+	if k := byPath["k"]; k.Seen != 1 {
+		t.Errorf("expected k seen once after retention, got %+v", k)
+	}
+	if n := byPath["n"]; n.Seen != 1 || n.NullRatio != 1 {
+		t.Errorf("expected only the newest record's fields after retention, got %+v", n)
+	}
+}
+
+func TestSQLiteStorage_FieldCatalogForgetsReplacedRows(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		partitioned bool
+		opts        domain.StoreOptions
+	}{
+		{"rows", false, domain.StoreOptions{}},
+		{"bulk", false, domain.StoreOptions{Bulk: true}},
+		{"partitioned rows", true, domain.StoreOptions{}},
+		{"partitioned bulk", true, domain.StoreOptions{Bulk: true}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			storage, cleanup := newTestStorage(t)
+			if tc.partitioned {
+				cleanup()
+				storage, cleanup = newPartitionedStorage(t)
+			}
+			defer cleanup()
+
+			old := domain.LogRecord{ID: "1", Timestamp: 1000, Level: "INFO", Message: "m", Source: "a.log", Raw: "r",
+				Fields: map[string]interface{}{"k": "x", "old": true}}
+			storeWithOptions(t, storage, []domain.LogRecord{old}, tc.opts)
+
+			// The replacement lands in another partition and is repeated in its batch.
+			replacement := old
+			replacement.Timestamp = 2 * dayMs
+			replacement.Fields = map[string]interface{}{"k": float64(5)}
+			storeWithOptions(t, storage, []domain.LogRecord{replacement, replacement}, tc.opts)
+
+			catalog, err := storage.GetFieldCatalog(context.Background(), "a.log")
+			if err != nil {
+				t.Fatalf("GetFieldCatalog failed: %v", err)
+			}
+			if len(catalog) != 1 {
+				t.Fatalf("expected only k in the catalog, got %+v", catalog)
+			}
+			if k := catalog[0]; k.Path != "k" || k.Seen != 1 || k.Types["number"] != 1 || k.Types["string"] != 0 {
+				t.Errorf("expected k to be one number, got %+v", k)
+			}
+		})
+	}
+}
+
+func TestSQLiteStorage_FieldCatalogKeptForCancelledImport(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	ch := make(chan domain.LogRecord)
+	done := make(chan *domain.ImportResult)
+	go func() {
+		result, _ := storage.Store(ctx, ch)
+		done <- result
+	}()
+	// The first batch has committed once the record after it is received.
+	for i := 0; i <= 1000; i++ {
+		ch <- domain.LogRecord{ID: fmt.Sprintf("r%d", i), Timestamp: int64(i), Level: "INFO", Message: "m", Source: "a.log", Raw: "r",
+			Fields: map[string]interface{}{"k": i}}
+	}
+	cancel()
+	close(ch)
+	if result := <-done; !result.Partial {
+		t.Fatalf("expected a partial import, got %+v", result)
+	}
+
+	var stored int64
+	if err := storage.db.QueryRow("SELECT COUNT(*) FROM records").Scan(&stored); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	catalog, err := storage.GetFieldCatalog(context.Background(), "a.log")
+	if err != nil {
+		t.Fatalf("GetFieldCatalog failed: %v", err)
+	}
+	if stored == 0 || len(catalog) != 1 || catalog[0].Seen != stored {
+		t.Errorf("expected k seen in all %d stored records, got %+v", stored, catalog)
+	}
+}
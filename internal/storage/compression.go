@@ -0,0 +1,803 @@
+package storage
+
+import (
+	"bytes"
+	"compress/flate"
+	"context"
+	"crypto/rand"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/binary"
+	"fmt"
+	"hash/maphash"
+	"io"
+	"sync"
+	"time"
+
+	"LogLens/internal/domain"
+	sqlite3 "modernc.org/sqlite"
+)
+
+// Compressed values are stored as BLOBs in the raw and fields columns, while
+// uncompressed ones stay TEXT, so both kinds can live side by side.
+//
+// Raw lines are deflated together in blocks of up to blockMaxRecords records
+// written to record_blocks, and a row's raw column holds a reference to its
+// slot:
+//
+//	[3][uint64 dictionary id][uvarint block id][uvarint slot][uvarint length]
+//
+// A block is [uvarint count][uvarint length]... followed by the deflated
+// concatenation of its lines. Fields are read by json_extract for filters and
+// expression indexes, one row at a time, so they are deflated per value:
+//
+//	[2][uint64 dictionary id][uvarint length][deflate data]
+//
+// Both are deflated against a preset dictionary sampled from the first batch
+// stored with compression on, which is what makes short log lines compress
+// well. Dictionaries have random 64-bit ids; values of format 1, written
+// before blocks, carry a 32-bit id and are still read. The inflate() SQL
+// function reverses all of them, so filters and reads work on any
+// representation; columnExpr supplies the block a reference points into.
+
+const (
+	legacyValueFormat = 1
+	valueFormat       = 2
+	blockRefFormat    = 3
+
+	maxDictionarySize = 32 * 1024
+	compressionLevel  = 6
+
+	// A block is closed once it holds blockMaxRecords lines or blockMaxBytes
+	// of text, whichever comes first.
+	blockMaxRecords = 64
+	blockMaxBytes   = 16 * 1024
+
+	// blockCacheSize is the number of decoded blocks kept for reads.
+	blockCacheSize = 64
+)
+
+var (
+	compressionOnce sync.Once
+	compressionErr  error
+
+	dictionaries   = make(map[uint64][]byte)
+	dictionariesMu sync.RWMutex
+	writerPools    sync.Map // uint64 -> *sync.Pool of *flate.Writer
+
+	blocks = newBlockCache(blockCacheSize)
+)
+
+func registerCompressionFuncs() error {
+	compressionOnce.Do(func() {
+		compressionErr = sqlite3.RegisterDeterministicScalarFunction("inflate", -1,
+			func(ctx *sqlite3.FunctionContext, args []driver.Value) (driver.Value, error) {
+				if len(args) == 0 || len(args) > 2 {
+					return nil, fmt.Errorf("inflate: expected a value and an optional block")
+				}
+				blob, ok := args[0].([]byte)
+				if !ok {
+					return args[0], nil
+				}
+				var block []byte
+				if len(args) == 2 {
+					block, _ = args[1].([]byte)
+				}
+				text, err := inflate(blob, block)
+				if err != nil {
+					return nil, err
+				}
+				return text, nil
+			},
+		)
+		if compressionErr != nil {
+			return
+		}
+		compressionErr = sqlite3.RegisterDeterministicScalarFunction("block_ref", 1,
+			func(ctx *sqlite3.FunctionContext, args []driver.Value) (driver.Value, error) {
+				blob, ok := args[0].([]byte)
+				if !ok || len(blob) == 0 || blob[0] != blockRefFormat {
+					return nil, nil
+				}
+				ref, err := parseBlockRef(blob)
+				if err != nil {
+					return nil, err
+				}
+				return int64(ref.block), nil
+			},
+		)
+		if compressionErr != nil {
+			return
+		}
+		compressionErr = sqlite3.RegisterDeterministicScalarFunction("inflated_size", 1,
+			func(ctx *sqlite3.FunctionContext, args []driver.Value) (driver.Value, error) {
+				switch v := args[0].(type) {
+				case []byte:
+					size, err := inflatedSize(v)
+					if err != nil {
+						return nil, err
+					}
+					return int64(size), nil
+				case string:
+					return int64(len(v)), nil
+				default:
+					return int64(0), nil
+				}
+			},
+		)
+	})
+	return compressionErr
+}
+
+// newDictionaryID returns a random id for a new dictionary. It is stored in
+// an INTEGER column, so it is kept positive; 0 means no dictionary.
+func newDictionaryID() (uint64, error) {
+	var b [8]byte
+	for {
+		if _, err := rand.Read(b[:]); err != nil {
+			return 0, err
+		}
+		if id := binary.BigEndian.Uint64(b[:]) >> 1; id != 0 {
+			return id, nil
+		}
+	}
+}
+
+// registerDictionary makes dict available under id to every open database.
+// Registering different contents under an id already in use is an error
+// rather than silently decoding one database's rows with another's dictionary.
+func registerDictionary(id uint64, dict []byte) error {
+	dictionariesMu.Lock()
+	defer dictionariesMu.Unlock()
+	if existing, ok := dictionaries[id]; ok {
+		if !bytes.Equal(existing, dict) {
+			return fmt.Errorf("compression dictionary %016x conflicts with one already loaded", id)
+		}
+		return nil
+	}
+	dictionaries[id] = dict
+	return nil
+}
+
+func lookupDictionary(id uint64) ([]byte, bool) {
+	dictionariesMu.RLock()
+	defer dictionariesMu.RUnlock()
+	dict, ok := dictionaries[id]
+	return dict, ok
+}
+
+// parseValue splits a value compressed on its own into its dictionary id,
+// original length and deflate data.
+func parseValue(blob []byte) (uint64, uint64, []byte, error) {
+	var id uint64
+	var rest []byte
+	switch {
+	case len(blob) >= 6 && blob[0] == legacyValueFormat:
+		id, rest = uint64(binary.BigEndian.Uint32(blob[1:5])), blob[5:]
+	case len(blob) >= 10 && blob[0] == valueFormat:
+		id, rest = binary.BigEndian.Uint64(blob[1:9]), blob[9:]
+	default:
+		return 0, 0, nil, fmt.Errorf("inflate: unknown compressed format")
+	}
+	size, n := binary.Uvarint(rest)
+	if n <= 0 {
+		return 0, 0, nil, fmt.Errorf("inflate: corrupt length header")
+	}
+	return id, size, rest[n:], nil
+}
+
+type blockRef struct {
+	dict, block, slot, size uint64
+}
+
+func parseBlockRef(blob []byte) (blockRef, error) {
+	if len(blob) < 12 || blob[0] != blockRefFormat {
+		return blockRef{}, fmt.Errorf("inflate: unknown compressed format")
+	}
+	ref := blockRef{dict: binary.BigEndian.Uint64(blob[1:9])}
+	rest := blob[9:]
+	for _, v := range []*uint64{&ref.block, &ref.slot, &ref.size} {
+		n := 0
+		if *v, n = binary.Uvarint(rest); n <= 0 {
+			return blockRef{}, fmt.Errorf("inflate: corrupt block reference")
+		}
+		rest = rest[n:]
+	}
+	return ref, nil
+}
+
+func inflatedSize(blob []byte) (uint64, error) {
+	if len(blob) > 0 && blob[0] == blockRefFormat {
+		ref, err := parseBlockRef(blob)
+		return ref.size, err
+	}
+	_, size, _, err := parseValue(blob)
+	return size, err
+}
+
+// inflate decodes a compressed value. For a block reference, block is the
+// data of the record_blocks row it points to.
+func inflate(blob, block []byte) (string, error) {
+	if len(blob) > 0 && blob[0] == blockRefFormat {
+		ref, err := parseBlockRef(blob)
+		if err != nil {
+			return "", err
+		}
+		if block == nil {
+			return "", fmt.Errorf("inflate: block %d not found", ref.block)
+		}
+		decoded, err := blocks.get(ref.dict, block)
+		if err != nil {
+			return "", err
+		}
+		if ref.slot >= uint64(len(decoded.offsets)-1) {
+			return "", fmt.Errorf("inflate: slot %d out of range in block %d", ref.slot, ref.block)
+		}
+		return decoded.text[decoded.offsets[ref.slot]:decoded.offsets[ref.slot+1]], nil
+	}
+
+	id, size, data, err := parseValue(blob)
+	if err != nil {
+		return "", err
+	}
+	out, err := inflateData(id, data, size)
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func inflateData(id uint64, data []byte, size uint64) ([]byte, error) {
+	dict, ok := lookupDictionary(id)
+	if !ok {
+		return nil, fmt.Errorf("inflate: dictionary %016x not loaded", id)
+	}
+	r := flate.NewReaderDict(bytes.NewReader(data), dict)
+	defer r.Close()
+	out := make([]byte, size)
+	if _, err := io.ReadFull(r, out); err != nil {
+		return nil, fmt.Errorf("inflate: %w", err)
+	}
+	return out, nil
+}
+
+// deflateData appends data deflated against the dictionary id to buf.
+func deflateData(buf *bytes.Buffer, id uint64, data []byte) error {
+	dict, ok := lookupDictionary(id)
+	if !ok {
+		return fmt.Errorf("deflate: dictionary %016x not loaded", id)
+	}
+
+	pool, _ := writerPools.LoadOrStore(id, &sync.Pool{})
+	writers := pool.(*sync.Pool)
+	w, _ := writers.Get().(*flate.Writer)
+	if w == nil {
+		var err error
+		if w, err = flate.NewWriterDict(buf, compressionLevel, dict); err != nil {
+			return err
+		}
+	} else {
+		w.Reset(buf)
+	}
+	defer writers.Put(w)
+
+	if _, err := w.Write(data); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+// deflate compresses text on its own against the dictionary id. It returns
+// text itself when compression would not make it smaller.
+func deflate(id uint64, text string) (interface{}, error) {
+	var buf bytes.Buffer
+	buf.WriteByte(valueFormat)
+	binary.Write(&buf, binary.BigEndian, id)
+	var lenBuf [binary.MaxVarintLen64]byte
+	buf.Write(lenBuf[:binary.PutUvarint(lenBuf[:], uint64(len(text)))])
+
+	if err := deflateData(&buf, id, []byte(text)); err != nil {
+		return nil, err
+	}
+	if buf.Len() >= len(text) {
+		return text, nil
+	}
+	return buf.Bytes(), nil
+}
+
+// decodedBlock is an inflated block; line i is text[offsets[i]:offsets[i+1]].
+type decodedBlock struct {
+	text    string
+	offsets []int
+}
+
+func decodeBlock(dict uint64, data []byte) (*decodedBlock, error) {
+	count, n := binary.Uvarint(data)
+	if n <= 0 || count > uint64(len(data)) {
+		return nil, fmt.Errorf("inflate: corrupt block header")
+	}
+	data = data[n:]
+	offsets := make([]int, 1, count+1)
+	var size uint64
+	for i := uint64(0); i < count; i++ {
+		length, n := binary.Uvarint(data)
+		if n <= 0 {
+			return nil, fmt.Errorf("inflate: corrupt block header")
+		}
+		data = data[n:]
+		size += length
+		offsets = append(offsets, int(size))
+	}
+	text, err := inflateData(dict, data, size)
+	if err != nil {
+		return nil, err
+	}
+	return &decodedBlock{text: string(text), offsets: offsets}, nil
+}
+
+// blockCache keeps recently decoded blocks, so that reading the lines of a
+// block in a row inflates it once. Entries are keyed by a hash of the block
+// data rather than its id, which is only unique within one database.
+type blockCache struct {
+	mu      sync.Mutex
+	seed    maphash.Seed
+	entries map[blockKey]*decodedBlock
+	order   []blockKey
+	next    int
+}
+
+type blockKey struct {
+	dict uint64
+	hash uint64
+	size int
+}
+
+func newBlockCache(size int) *blockCache {
+	return &blockCache{
+		seed:    maphash.MakeSeed(),
+		entries: make(map[blockKey]*decodedBlock, size),
+		order:   make([]blockKey, 0, size),
+	}
+}
+
+func (c *blockCache) get(dict uint64, data []byte) (*decodedBlock, error) {
+	key := blockKey{dict: dict, hash: maphash.Bytes(c.seed, data), size: len(data)}
+	c.mu.Lock()
+	decoded, ok := c.entries[key]
+	c.mu.Unlock()
+	if ok {
+		return decoded, nil
+	}
+
+	decoded, err := decodeBlock(dict, data)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, ok := c.entries[key]; ok {
+		return decoded, nil
+	}
+	if len(c.order) < cap(c.order) {
+		c.order = append(c.order, key)
+	} else {
+		delete(c.entries, c.order[c.next])
+		c.order[c.next] = key
+		c.next = (c.next + 1) % len(c.order)
+	}
+	c.entries[key] = decoded
+	return decoded, nil
+}
+
+// compressionState is the per-database compression configuration. stats
+// caches CompressionStats: inserts add to it, and writes whose effect on the
+// stored bytes is not known up front drop it. gen changes on every update,
+// so a scan that raced with a write is not cached.
+type compressionState struct {
+	mu    sync.RWMutex
+	mode  domain.CompressionMode
+	dict  uint64
+	stats *domain.CompressionStats
+	gen   uint64
+}
+
+func (c *compressionState) addStats(delta domain.CompressionStats) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	if c.stats != nil {
+		c.stats.StoredBytes += delta.StoredBytes
+		c.stats.OriginalBytes += delta.OriginalBytes
+		c.stats.CompressedRows += delta.CompressedRows
+	}
+}
+
+func (c *compressionState) invalidateStats() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.gen++
+	c.stats = nil
+}
+
+func (s *SQLiteStorage) loadCompression(ctx context.Context) error {
+	s.compression = &compressionState{mode: domain.CompressionNone}
+
+	var value string
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM storage_settings WHERE key = 'compression'").Scan(&value)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read compression setting: %w", err)
+	}
+	if value != "" {
+		s.compression.mode = domain.CompressionMode(value)
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT id, dict FROM compression_dicts ORDER BY created_at")
+	if err != nil {
+		return fmt.Errorf("failed to load compression dictionaries: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var dict []byte
+		if err := rows.Scan(&id, &dict); err != nil {
+			return fmt.Errorf("failed to load compression dictionaries: %w", err)
+		}
+		if err := registerDictionary(uint64(id), dict); err != nil {
+			return err
+		}
+		s.compression.dict = uint64(id)
+	}
+	return rows.Err()
+}
+
+func (s *SQLiteStorage) Compression(ctx context.Context) (domain.CompressionMode, error) {
+	s.compression.mu.RLock()
+	defer s.compression.mu.RUnlock()
+	return s.compression.mode, nil
+}
+
+// SetCompression changes how newly stored records are written. Existing rows
+// keep their representation; reads handle both.
+func (s *SQLiteStorage) SetCompression(ctx context.Context, mode domain.CompressionMode) error {
+	switch mode {
+	case "":
+		mode = domain.CompressionNone
+	case domain.CompressionNone, domain.CompressionRaw, domain.CompressionRawFields:
+	default:
+		return fmt.Errorf("unsupported compression mode: %s", mode)
+	}
+
+	s.compression.mu.Lock()
+	defer s.compression.mu.Unlock()
+	if _, err := s.db.ExecContext(ctx,
+		"INSERT INTO storage_settings (key, value) VALUES ('compression', ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value",
+		string(mode),
+	); err != nil {
+		return fmt.Errorf("failed to store compression setting: %w", err)
+	}
+	s.compression.mode = mode
+	return nil
+}
+
+// ensureDictionary samples batch into the database's compression dictionary
+// the first time records are stored with compression on. Like
+// ensurePartitions it runs outside the write transaction.
+func (s *SQLiteStorage) ensureDictionary(ctx context.Context, batch []domain.LogRecord) error {
+	s.compression.mu.RLock()
+	ready := s.compression.mode == domain.CompressionNone || s.compression.dict != 0
+	s.compression.mu.RUnlock()
+	if ready || len(batch) == 0 {
+		return nil
+	}
+
+	s.compression.mu.Lock()
+	defer s.compression.mu.Unlock()
+	if s.compression.dict != 0 {
+		return nil
+	}
+
+	dict := sampleDictionary(batch, s.compression.mode == domain.CompressionRawFields)
+	id, err := newDictionaryID()
+	if err != nil {
+		return fmt.Errorf("failed to create compression dictionary id: %w", err)
+	}
+	if err := registerDictionary(id, dict); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx,
+		"INSERT INTO compression_dicts (id, dict, created_at) VALUES (?, ?, ?)",
+		int64(id), dict, time.Now().UnixMilli(),
+	); err != nil {
+		return fmt.Errorf("failed to store compression dictionary: %w", err)
+	}
+	s.compression.dict = id
+	return nil
+}
+
+// sampleDictionary concatenates evenly spaced raw lines (and field documents)
+// up to the deflate window size.
+func sampleDictionary(batch []domain.LogRecord, withFields bool) []byte {
+	var dict []byte
+	step := len(batch) / 256
+	if step == 0 {
+		step = 1
+	}
+	for i := 0; i < len(batch) && len(dict) < maxDictionarySize; i += step {
+		dict = append(dict, batch[i].Raw...)
+		dict = append(dict, '\n')
+		if withFields && len(batch[i].Fields) > 0 {
+			if fieldsJSON, err := encodeFields(batch[i].Fields); err == nil {
+				dict = append(dict, fieldsJSON...)
+				dict = append(dict, '\n')
+			}
+		}
+	}
+	if len(dict) > maxDictionarySize {
+		dict = dict[len(dict)-maxDictionarySize:]
+	}
+	return dict
+}
+
+// recordEncoder builds the insert arguments of one write transaction. Raw
+// lines go into an open block per record table; a block's row is inserted
+// when its first line is added and filled in when it is closed, so flush must
+// run before the transaction commits. stats sums what the encoded rows add.
+type recordEncoder struct {
+	s      *SQLiteStorage
+	tx     *sql.Tx
+	mode   domain.CompressionMode
+	dict   uint64
+	blocks map[string]*openBlock
+	stats  domain.CompressionStats
+}
+
+type openBlock struct {
+	id      int64
+	lengths []int
+	text    []byte
+}
+
+func (s *SQLiteStorage) newRecordEncoder(tx *sql.Tx) *recordEncoder {
+	s.compression.mu.RLock()
+	defer s.compression.mu.RUnlock()
+	enc := &recordEncoder{s: s, tx: tx, mode: s.compression.mode, dict: s.compression.dict}
+	if enc.mode == domain.CompressionNone || enc.dict == 0 {
+		enc.mode = domain.CompressionNone
+	}
+	return enc
+}
+
+// appendRecordArgs appends the insert arguments for record in recordColumns
+// order, compressing raw and fields according to the current mode.
+func (e *recordEncoder) appendRecordArgs(ctx context.Context, args []interface{}, record domain.LogRecord) ([]interface{}, error) {
+	fieldsJSON, err := encodeFields(record.Fields)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode fields of record %s: %w", record.ID, err)
+	}
+
+	var raw, fields interface{} = record.Raw, fieldsJSON
+	if e.mode != domain.CompressionNone {
+		if raw, err = e.addToBlock(ctx, e.s.tableFor(record.Timestamp), record.Raw); err != nil {
+			return nil, fmt.Errorf("failed to compress record %s: %w", record.ID, err)
+		}
+		if e.mode == domain.CompressionRawFields {
+			if fields, err = deflate(e.dict, fieldsJSON); err != nil {
+				return nil, fmt.Errorf("failed to compress record %s: %w", record.ID, err)
+			}
+		}
+	}
+
+	e.stats.OriginalBytes += int64(len(record.Raw) + len(fieldsJSON))
+	e.stats.StoredBytes += storedLen(raw) + storedLen(fields)
+	if _, ok := raw.([]byte); ok {
+		e.stats.CompressedRows++
+	}
+
+	return append(args,
+		record.ID,
+		record.Timestamp,
+		record.Level,
+		record.Message,
+		record.Service,
+		fields,
+		raw,
+		record.Source,
+		record.Line,
+	), nil
+}
+
+func storedLen(v interface{}) int64 {
+	switch v := v.(type) {
+	case []byte:
+		return int64(len(v))
+	case string:
+		return int64(len(v))
+	default:
+		return 0
+	}
+}
+
+// addToBlock adds text to the open block of table and returns the reference
+// stored in the row's raw column.
+func (e *recordEncoder) addToBlock(ctx context.Context, table, text string) ([]byte, error) {
+	if e.blocks == nil {
+		e.blocks = make(map[string]*openBlock)
+	}
+	b := e.blocks[table]
+	if b == nil {
+		res, err := e.tx.ExecContext(ctx, "INSERT INTO record_blocks (tbl, data) VALUES (?, x'')", table)
+		if err != nil {
+			return nil, fmt.Errorf("failed to add record block: %w", err)
+		}
+		b = &openBlock{}
+		if b.id, err = res.LastInsertId(); err != nil {
+			return nil, err
+		}
+		e.blocks[table] = b
+	}
+
+	ref := make([]byte, 9, 9+3*binary.MaxVarintLen64)
+	ref[0] = blockRefFormat
+	binary.BigEndian.PutUint64(ref[1:], e.dict)
+	ref = binary.AppendUvarint(ref, uint64(b.id))
+	ref = binary.AppendUvarint(ref, uint64(len(b.lengths)))
+	ref = binary.AppendUvarint(ref, uint64(len(text)))
+
+	b.lengths = append(b.lengths, len(text))
+	b.text = append(b.text, text...)
+	if len(b.lengths) >= blockMaxRecords || len(b.text) >= blockMaxBytes {
+		if err := e.closeBlock(ctx, b); err != nil {
+			return nil, err
+		}
+		delete(e.blocks, table)
+	}
+	return ref, nil
+}
+
+func (e *recordEncoder) closeBlock(ctx context.Context, b *openBlock) error {
+	var buf bytes.Buffer
+	var n [binary.MaxVarintLen64]byte
+	buf.Write(n[:binary.PutUvarint(n[:], uint64(len(b.lengths)))])
+	for _, length := range b.lengths {
+		buf.Write(n[:binary.PutUvarint(n[:], uint64(length))])
+	}
+	if err := deflateData(&buf, e.dict, b.text); err != nil {
+		return err
+	}
+	if _, err := e.tx.ExecContext(ctx, "UPDATE record_blocks SET data = ? WHERE id = ?", buf.Bytes(), b.id); err != nil {
+		return fmt.Errorf("failed to write record block: %w", err)
+	}
+	e.stats.StoredBytes += int64(buf.Len())
+	return nil
+}
+
+// flush closes the blocks still open.
+func (e *recordEncoder) flush(ctx context.Context) error {
+	for table, b := range e.blocks {
+		if err := e.closeBlock(ctx, b); err != nil {
+			return err
+		}
+		delete(e.blocks, table)
+	}
+	return nil
+}
+
+// committed updates the cached stats once the transaction has committed.
+// When some records were duplicates it is unclear which rows replaced or
+// kept what, so the cache is dropped instead.
+func (e *recordEncoder) committed(duplicates int64) {
+	if duplicates > 0 {
+		e.s.compression.invalidateStats()
+		return
+	}
+	e.s.compression.addStats(e.stats)
+}
+
+// CompressionStats compares the bytes stored for raw and fields, blocks
+// included, with their uncompressed size. With compression off it reports
+// nothing rather than scanning; otherwise the scan runs once and is then
+// kept up to date as records are stored.
+func (s *SQLiteStorage) CompressionStats(ctx context.Context) (*domain.CompressionStats, error) {
+	release := s.usePartitions()
+	defer release()
+
+	s.compression.mu.RLock()
+	mode, gen := s.compression.mode, s.compression.gen
+	var cached *domain.CompressionStats
+	if s.compression.stats != nil {
+		c := *s.compression.stats
+		cached = &c
+	}
+	s.compression.mu.RUnlock()
+
+	if mode == domain.CompressionNone {
+		return &domain.CompressionStats{Mode: mode}, nil
+	}
+	if cached == nil {
+		var err error
+		if cached, err = s.scanCompressionStats(ctx); err != nil {
+			return nil, err
+		}
+		s.compression.mu.Lock()
+		if s.compression.gen == gen {
+			c := *cached
+			s.compression.stats = &c
+		}
+		s.compression.mu.Unlock()
+	}
+
+	cached.Mode = mode
+	if cached.StoredBytes > 0 {
+		cached.Ratio = float64(cached.OriginalBytes) / float64(cached.StoredBytes)
+	}
+	return cached, nil
+}
+
+func (s *SQLiteStorage) scanCompressionStats(ctx context.Context) (*domain.CompressionStats, error) {
+	stats := &domain.CompressionStats{}
+	q := `SELECT
+		COALESCE(SUM(length(CAST(raw AS BLOB)) + COALESCE(length(CAST(fields AS BLOB)), 0)), 0)
+			+ (SELECT COALESCE(SUM(length(data)), 0) FROM record_blocks),
+		COALESCE(SUM(inflated_size(raw) + COALESCE(inflated_size(fields), 0)), 0),
+		COALESCE(SUM(typeof(raw) = 'blob'), 0)
+		FROM ` + s.recordsFrom(nil)
+	if err := s.rdb.QueryRowContext(ctx, q).Scan(&stats.StoredBytes, &stats.OriginalBytes, &stats.CompressedRows); err != nil {
+		return nil, fmt.Errorf("failed to compute compression stats: %w", err)
+	}
+	return stats, nil
+}
+
+// collectBlocks deletes the blocks that no row points to any more, left
+// behind by deletes and replaced records.
+func (s *SQLiteStorage) collectBlocks(ctx context.Context) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, table := range s.recordTables(nil) {
+		if err := collectTableBlocks(ctx, tx, table); err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to delete unused blocks: %w", err)
+	}
+	s.compression.invalidateStats()
+	return nil
+}
+
+func collectTableBlocks(ctx context.Context, tx *sql.Tx, table string) error {
+	if _, err := tx.ExecContext(ctx, "CREATE TEMP TABLE IF NOT EXISTS live_blocks (id INTEGER PRIMARY KEY)"); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM temp.live_blocks"); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT OR IGNORE INTO temp.live_blocks SELECT block_ref(raw) FROM "+table+" WHERE typeof(raw) = 'blob' AND block_ref(raw) IS NOT NULL",
+	); err != nil {
+		return fmt.Errorf("failed to find live blocks of %s: %w", table, err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM record_blocks WHERE tbl = ? AND id NOT IN (SELECT id FROM temp.live_blocks)", table,
+	); err != nil {
+		return fmt.Errorf("failed to delete unused blocks of %s: %w", table, err)
+	}
+	_, err := tx.ExecContext(ctx, "DELETE FROM temp.live_blocks")
+	return err
+}
+
+// columnExpr returns the SQL expression that reads col as text.
+func columnExpr(col string) string {
+	switch col {
+	case "raw":
+		return "CASE WHEN typeof(raw) = 'blob' THEN inflate(raw, (SELECT data FROM record_blocks WHERE record_blocks.id = block_ref(raw))) ELSE raw END"
+	case "fields":
+		return "inflate(fields)"
+	default:
+		return col
+	}
+}
@@ -0,0 +1,283 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"LogLens/internal/domain"
+)
+
+func jsonLogRecords(prefix string, n int) []domain.LogRecord {
+	records := make([]domain.LogRecord, n)
+	for i := range records {
+		status := 200 + (i%3)*100
+		records[i] = domain.LogRecord{
+			ID:        fmt.Sprintf("%s_%d", prefix, i),
+			Timestamp: int64(i) * 1000,
+			Level:     "INFO",
+			Message:   fmt.Sprintf("GET /api/v1/orders/%d", i),
+			Service:   "checkout",
+			Fields:    map[string]interface{}{"status": float64(status), "method": "GET", "user": fmt.Sprintf("user-%d", i%50)},
+			Raw: fmt.Sprintf(`{"ts":"2024-01-15T10:%02d:%02dZ","level":"INFO","service":"checkout","msg":"GET /api/v1/orders/%d","status":%d,"method":"GET","user":"user-%d"}`,
+				i/60%60, i%60, i, status, i%50),
+		}
+	}
+	return records
+}
+
+func TestCompression_TransparentReads(t *testing.T) {
+	for _, opts := range []domain.StoreOptions{{}, {Bulk: true}} {
+		t.Run(fmt.Sprintf("bulk=%v", opts.Bulk), func(t *testing.T) {
+			storage, cleanup := newTestStorage(t)
+			defer cleanup()
+			ctx := context.Background()
+
+			plain := jsonLogRecords("plain", 50)
+			storeWithOptions(t, storage, plain, opts)
+
+			if err := storage.SetCompression(ctx, domain.CompressionRawFields); err != nil {
+				t.Fatalf("SetCompression failed: %v", err)
+			}
+			packed := jsonLogRecords("packed", 2000)
+			storeWithOptions(t, storage, packed, opts)
+
+			for _, want := range []domain.LogRecord{plain[7], packed[1234]} {
+				got, err := storage.GetRecord(ctx, want.ID)
+				if err != nil {
+					t.Fatalf("GetRecord failed: %v", err)
+				}
+				if got.Raw != want.Raw {
+					t.Errorf("raw mismatch for %s:\n got %s\nwant %s", want.ID, got.Raw, want.Raw)
+				}
+				if got.Fields["user"] != want.Fields["user"] || got.Fields["status"] != want.Fields["status"] {
+					t.Errorf("fields mismatch for %s: %v", want.ID, got.Fields)
+				}
+			}
+
+			contains, err := storage.Query(ctx, domain.Query{
+				Filters: []domain.FilterCondition{{Type: domain.FilterContains, Field: "raw", Value: `"user":"user-7"`}},
+				Limit:   10,
+			})
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if contains.Total != 41 {
+				t.Errorf("expected 41 raw matches across compressed and plain rows, got %d", contains.Total)
+			}
+
+			regex, err := storage.Query(ctx, domain.Query{
+				Filters: []domain.FilterCondition{{Type: domain.FilterRegexp, Field: "raw", Value: `"status":400,`}},
+				Limit:   1,
+			})
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if regex.Total != 682 {
+				t.Errorf("expected 682 regexp matches, got %d", regex.Total)
+			}
+
+			stats, err := storage.CompressionStats(ctx)
+			if err != nil {
+				t.Fatalf("CompressionStats failed: %v", err)
+			}
+			if stats.CompressedRows != 2000 {
+				t.Errorf("expected 2000 compressed rows, got %d", stats.CompressedRows)
+			}
+			if stats.Ratio < 1.5 {
+				t.Errorf("expected a compression ratio of at least 1.5, got %.2f", stats.Ratio)
+			}
+		})
+	}
+}
+
+func TestCompression_DictionaryReloadedOnOpen(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if err := storage.SetCompression(ctx, domain.CompressionRaw); err != nil {
+		t.Fatalf("SetCompression failed: %v", err)
+	}
+	records := jsonLogRecords("r", 100)
+	storeRecords(t, storage, records)
+	storage.Close()
+
+	dictionariesMu.Lock()
+	delete(dictionaries, storage.compression.dict)
+	dictionariesMu.Unlock()
+
+	reopened, err := NewSQLiteStorage(storage.dbPath)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer reopened.Close()
+
+	mode, _ := reopened.Compression(ctx)
+	if mode != domain.CompressionRaw {
+		t.Errorf("expected raw compression after reopen, got %s", mode)
+	}
+	got, err := reopened.GetRecord(ctx, "r_42")
+	if err != nil {
+		t.Fatalf("GetRecord failed: %v", err)
+	}
+	if got.Raw != records[42].Raw {
+		t.Errorf("raw mismatch after reopen: %s", got.Raw)
+	}
+}
+
+func TestCompression_RejectsUnknownMode(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	if err := storage.SetCompression(context.Background(), "zip"); err == nil {
+		t.Error("expected error for unknown compression mode")
+	}
+}
+
+func countBlocks(t *testing.T, storage *SQLiteStorage) int {
+	t.Helper()
+	var n int
+	if err := storage.db.QueryRow("SELECT COUNT(*) FROM record_blocks").Scan(&n); err != nil {
+		t.Fatalf("failed to count blocks: %v", err)
+	}
+	return n
+}
+
+func TestCompression_RawStoredInBlocks(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	if err := storage.SetCompression(ctx, domain.CompressionRaw); err != nil {
+		t.Fatalf("SetCompression failed: %v", err)
+	}
+	records := jsonLogRecords("b", 200)
+	storeRecords(t, storage, records)
+
+	if n := countBlocks(t, storage); n != 4 {
+		t.Errorf("expected 200 lines in 4 blocks of %d, got %d blocks", blockMaxRecords, n)
+	}
+	var raw []byte
+	if err := storage.db.QueryRow("SELECT raw FROM records WHERE id = 'b_7'").Scan(&raw); err != nil {
+		t.Fatalf("failed to read raw column: %v", err)
+	}
+	if len(raw) == 0 || raw[0] != blockRefFormat || len(raw) > 32 {
+		t.Errorf("expected a short block reference, got %d bytes", len(raw))
+	}
+	got, err := storage.GetRecord(ctx, "b_70")
+	if err != nil || got.Raw != records[70].Raw {
+		t.Fatalf("expected raw read back from its block, got %+v: %v", got, err)
+	}
+
+	// Deleting the first block's records leaves it unused until Compact.
+	filters := []domain.FilterCondition{{Type: domain.FilterRange, Field: "timestamp", Operator: "lt", Value: float64(blockMaxRecords * 1000)}}
+	if deleted, err := storage.DeleteByQuery(ctx, filters, false); err != nil || deleted.Deleted != blockMaxRecords {
+		t.Fatalf("expected %d deleted, got %+v: %v", blockMaxRecords, deleted, err)
+	}
+	if _, err := storage.Compact(ctx); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if n := countBlocks(t, storage); n != 3 {
+		t.Errorf("expected the unused block to be deleted, got %d blocks", n)
+	}
+	for _, id := range []int{64, 150, 199} {
+		got, err := storage.GetRecord(ctx, fmt.Sprintf("b_%d", id))
+		if err != nil || got.Raw != records[id].Raw {
+			t.Errorf("record %d unreadable after Compact: %v", id, err)
+		}
+	}
+}
+
+func TestCompression_DictionaryIDsDoNotCollide(t *testing.T) {
+	if err := registerDictionary(42, []byte("one")); err != nil {
+		t.Fatalf("registerDictionary failed: %v", err)
+	}
+	if err := registerDictionary(42, []byte("one")); err != nil {
+		t.Errorf("expected re-registering the same dictionary to succeed, got %v", err)
+	}
+	if err := registerDictionary(42, []byte("two")); err == nil {
+		t.Error("expected an error registering a different dictionary under a used id")
+	}
+
+	// Two databases sampling different records get distinct dictionaries,
+	// and each keeps decoding its own rows.
+	ctx := context.Background()
+	var opened []*SQLiteStorage
+	for _, prefix := range []string{"x", "y"} {
+		storage, cleanup := newTestStorage(t)
+		defer cleanup()
+		if err := storage.SetCompression(ctx, domain.CompressionRawFields); err != nil {
+			t.Fatalf("SetCompression failed: %v", err)
+		}
+		storeRecords(t, storage, jsonLogRecords(prefix, 100))
+		opened = append(opened, storage)
+	}
+	if opened[0].compression.dict == opened[1].compression.dict {
+		t.Fatal("expected distinct dictionary ids")
+	}
+	for i, prefix := range []string{"x", "y"} {
+		got, err := opened[i].GetRecord(ctx, prefix+"_5")
+		if err != nil || got.Raw != jsonLogRecords(prefix, 6)[5].Raw || got.Fields["user"] != "user-5" {
+			t.Errorf("database %s decoded wrongly: %+v: %v", prefix, got, err)
+		}
+	}
+}
+
+func TestCompressionStats_CachedAndSkippedWhenOff(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	storeRecords(t, storage, jsonLogRecords("plain", 50))
+	stats, err := storage.CompressionStats(ctx)
+	if err != nil {
+		t.Fatalf("CompressionStats failed: %v", err)
+	}
+	if stats.Mode != domain.CompressionNone || stats.StoredBytes != 0 {
+		t.Errorf("expected no scan with compression off, got %+v", stats)
+	}
+
+	if err := storage.SetCompression(ctx, domain.CompressionRawFields); err != nil {
+		t.Fatalf("SetCompression failed: %v", err)
+	}
+	storeRecords(t, storage, jsonLogRecords("a", 100))
+	if _, err := storage.CompressionStats(ctx); err != nil {
+		t.Fatalf("CompressionStats failed: %v", err)
+	}
+
+	check := func(step string) {
+		t.Helper()
+		got, err := storage.CompressionStats(ctx)
+		if err != nil {
+			t.Fatalf("CompressionStats failed: %v", err)
+		}
+		want, err := storage.scanCompressionStats(ctx)
+		if err != nil {
+			t.Fatalf("scanCompressionStats failed: %v", err)
+		}
+		if got.StoredBytes != want.StoredBytes || got.OriginalBytes != want.OriginalBytes || got.CompressedRows != want.CompressedRows {
+			t.Errorf("%s: cached stats %+v differ from a scan %+v", step, got, want)
+		}
+	}
+
+	storeRecords(t, storage, jsonLogRecords("b", 100))
+	if storage.compression.stats == nil {
+		t.Error("expected the stats to stay cached across inserts")
+	}
+	check("insert")
+
+	storeRecords(t, storage, jsonLogRecords("b", 10))
+	check("duplicates")
+
+	filters := []domain.FilterCondition{{Type: domain.FilterContains, Field: "id", Value: "a_"}}
+	if _, err := storage.DeleteByQuery(ctx, filters, false); err != nil {
+		t.Fatalf("DeleteByQuery failed: %v", err)
+	}
+	check("delete")
+
+	if _, err := storage.Compact(ctx); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	check("compact")
+}
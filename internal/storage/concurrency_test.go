@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"LogLens/internal/domain"
+)
+
+// generateRecords streams up to n records, stopping early once stop is closed.
+func generateRecords(n int, stop <-chan struct{}) <-chan domain.LogRecord {
+	ch := make(chan domain.LogRecord, 1000)
+	go func() {
+		defer close(ch)
+		for i := 0; i < n; i++ {
+			record := domain.LogRecord{
+				ID:        fmt.Sprintf("gen_%d", i),
+				Timestamp: int64(i),
+				Level:     "INFO",
+				Message:   fmt.Sprintf("generated %d", i),
+				Fields:    map[string]interface{}{"n": i},
+				Raw:       "raw",
+			}
+			select {
+			case ch <- record:
+			case <-stop:
+				return
+			}
+		}
+	}()
+	return ch
+}
+
+func TestSQLiteStorage_QueriesDuringImport(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	storeRecords(t, storage, newRecords(100))
+
+	// Hold the writer lock the way a running import batch does: an open
+	// write transaction on the writer connection with uncommitted rows.
+	ctx := context.Background()
+	writer, err := storage.db.Conn(ctx)
+	if err != nil {
+		t.Fatalf("failed to take the writer connection: %v", err)
+	}
+	defer writer.Close()
+	if _, err := writer.ExecContext(ctx, "BEGIN IMMEDIATE"); err != nil {
+		t.Fatalf("failed to begin write transaction: %v", err)
+	}
+	defer writer.ExecContext(ctx, "ROLLBACK")
+	if _, err := writer.ExecContext(ctx, `INSERT INTO records (id, timestamp, level, message, raw) VALUES ('pending', 1, 'INFO', 'm', 'r')`); err != nil {
+		t.Fatalf("failed to write in the open transaction: %v", err)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+	result, err := storage.Query(queryCtx, domain.Query{Limit: 50})
+	if err != nil {
+		t.Fatalf("Query blocked behind the open write transaction: %v", err)
+	}
+	if result.Total != 100 {
+		t.Errorf("expected the 100 committed records, got %d", result.Total)
+	}
+	points, err := storage.Timeline(queryCtx, nil, 60000)
+	if err != nil {
+		t.Fatalf("Timeline blocked behind the open write transaction: %v", err)
+	}
+	var total int64
+	for _, p := range points {
+		total += p.Count
+	}
+	if total != 100 {
+		t.Errorf("expected the timeline to count 100 committed records, got %d", total)
+	}
+}
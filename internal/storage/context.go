@@ -0,0 +1,105 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"LogLens/internal/domain"
+)
+
+const maxContextLines = 5000
+
+// rawLines is the number of file lines a record spans, counting its
+// continuation lines.
+func rawLines(record domain.LogRecord) int64 {
+	return int64(strings.Count(strings.TrimRight(record.Raw, "\n"), "\n")) + 1
+}
+
+// GetContext returns the records of id's source covering the before lines
+// preceding it and the after lines following it, in file order and
+// regardless of timestamps. A record with continuation lines counts once
+// per line and is included if any of its lines fall in the window. To page
+// further, call GetContext again with the first or last record's ID.
+func (s *SQLiteStorage) GetContext(ctx context.Context, id string, before, after int) (*domain.RecordContext, error) {
+	release := s.usePartitions()
+	defer release()
+
+	if before < 0 || after < 0 {
+		return nil, fmt.Errorf("context line counts cannot be negative")
+	}
+	before, after = min(before, maxContextLines), min(after, maxContextLines)
+
+	tx, err := s.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	record, err := scanRecord(tx.QueryRowContext(ctx, "SELECT "+recordSelect+" FROM "+s.recordsFrom(nil)+" WHERE id = ?", id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get record: %w", err)
+	}
+	if record.Line <= 0 {
+		return nil, fmt.Errorf("record %s has no line number", id)
+	}
+	result := &domain.RecordContext{Record: record, Before: make([]domain.LogRecord, 0), After: make([]domain.LogRecord, 0)}
+
+	// Every record starts on its own line, so a window of n lines holds at
+	// most n records plus one reaching into it from outside.
+	q := "SELECT " + recordSelect + " FROM " + s.recordsFrom(nil) +
+		" WHERE source = ? AND (line < ? OR (line = ? AND id < ?)) ORDER BY line DESC, id DESC LIMIT ?"
+	preceding, err := s.queryRecords(ctx, tx, q, record.Source, record.Line, record.Line, record.ID, before+2)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get context: %w", err)
+	}
+	first := record.Line - int64(before)
+	for i, r := range preceding {
+		if before == 0 || r.Line+rawLines(r)-1 < first {
+			result.MoreBefore = true
+			break
+		}
+		result.Before = append(result.Before, r)
+		if r.Line <= first {
+			result.MoreBefore = i+1 < len(preceding)
+			break
+		}
+	}
+	for i, j := 0, len(result.Before)-1; i < j; i, j = i+1, j-1 {
+		result.Before[i], result.Before[j] = result.Before[j], result.Before[i]
+	}
+
+	q = "SELECT " + recordSelect + " FROM " + s.recordsFrom(nil) +
+		" WHERE source = ? AND (line > ? OR (line = ? AND id > ?)) ORDER BY line, id LIMIT ?"
+	following, err := s.queryRecords(ctx, tx, q, record.Source, record.Line, record.Line, record.ID, after+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get context: %w", err)
+	}
+	last := record.Line + rawLines(record) - 1 + int64(after)
+	for _, r := range following {
+		if r.Line > last {
+			result.MoreAfter = true
+			break
+		}
+		result.After = append(result.After, r)
+	}
+	return result, nil
+}
+
+func (s *SQLiteStorage) queryRecords(ctx context.Context, q queryer, query string, args ...interface{}) ([]domain.LogRecord, error) {
+	rows, err := q.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var records []domain.LogRecord
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return nil, err
+		}
+		records = append(records, record)
+	}
+	return records, rows.Err()
+}
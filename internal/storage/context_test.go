@@ -0,0 +1,128 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"LogLens/internal/domain"
+)
+
+// fileRecords returns one record per line of a.log, all with the same
+// timestamp, interleaved with records from b.log. The record on line 5
+// continues over lines 6 and 7.
+func fileRecords(base int64) []domain.LogRecord {
+	var records []domain.LogRecord
+	for line := int64(1); line <= 20; line++ {
+		if line == 6 || line == 7 {
+			continue
+		}
+		raw := fmt.Sprintf("line %d", line)
+		if line == 5 {
+			raw = "line 5\n  at frame 1\n  at frame 2"
+		}
+		records = append(records,
+			domain.LogRecord{ID: fmt.Sprintf("a%d", line), Timestamp: base, Level: "INFO", Message: raw, Raw: raw, Source: "a.log", Line: line},
+			domain.LogRecord{ID: fmt.Sprintf("b%d", line), Timestamp: base, Level: "INFO", Message: raw, Raw: raw, Source: "b.log", Line: line},
+		)
+	}
+	return records
+}
+
+func contextIDs(records []domain.LogRecord) string {
+	ids := ""
+	for _, r := range records {
+		ids += r.ID + " "
+	}
+	return ids
+}
+
+func TestGetContext_FileOrderWithContinuationLines(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	storeRecords(t, storage, fileRecords(1000))
+
+	tests := []struct {
+		id            string
+		before, after int
+		wantBefore    string
+		wantAfter     string
+		moreBefore    bool
+		moreAfter     bool
+	}{
+		// Lines 6 and 7 belong to a5, so 3 lines before a9 reach into it.
+		{"a9", 3, 2, "a5 a8 ", "a10 a11 ", true, true},
+		{"a8", 1, 0, "a5 ", "", true, true},
+		// The lines after a5 start below its continuation lines.
+		{"a5", 2, 2, "a3 a4 ", "a8 a9 ", true, true},
+		{"a2", 5, 0, "a1 ", "", false, true},
+		{"a20", 0, 5, "", "", true, false},
+	}
+	for _, tt := range tests {
+		result, err := storage.GetContext(context.Background(), tt.id, tt.before, tt.after)
+		if err != nil {
+			t.Fatalf("GetContext(%s) failed: %v", tt.id, err)
+		}
+		if result.Record.ID != tt.id {
+			t.Errorf("expected record %s, got %s", tt.id, result.Record.ID)
+		}
+		if got := contextIDs(result.Before); got != tt.wantBefore || result.MoreBefore != tt.moreBefore {
+			t.Errorf("%s: expected before %q (more=%v), got %q (more=%v)", tt.id, tt.wantBefore, tt.moreBefore, got, result.MoreBefore)
+		}
+		if got := contextIDs(result.After); got != tt.wantAfter || result.MoreAfter != tt.moreAfter {
+			t.Errorf("%s: expected after %q (more=%v), got %q (more=%v)", tt.id, tt.wantAfter, tt.moreAfter, got, result.MoreAfter)
+		}
+	}
+}
+
+func TestGetContext_PagesAcrossPartitions(t *testing.T) {
+	storage, cleanup := newPartitionedStorage(t)
+	defer cleanup()
+
+	// Lines of the same file on either side of midnight.
+	base := time.Date(2024, 1, 15, 23, 59, 59, 0, time.UTC).UnixMilli()
+	records := fileRecords(base)
+	for i := range records {
+		if records[i].Line > 10 {
+			records[i].Timestamp += 2000
+		}
+	}
+	storeRecords(t, storage, records)
+
+	var index string
+	if err := storage.db.QueryRow("SELECT name FROM sqlite_master WHERE type = 'index' AND name = 'idx_records_p20240116_source_line'").Scan(&index); err != nil {
+		t.Errorf("expected a source and line index on the new partition: %v", err)
+	}
+
+	page, err := storage.GetContext(context.Background(), "a10", 0, 2)
+	if err != nil {
+		t.Fatalf("GetContext failed: %v", err)
+	}
+	if got := contextIDs(page.After); got != "a11 a12 " {
+		t.Fatalf("expected a11 a12, got %q", got)
+	}
+	page, err = storage.GetContext(context.Background(), page.After[1].ID, 0, 3)
+	if err != nil {
+		t.Fatalf("GetContext failed: %v", err)
+	}
+	if got := contextIDs(page.After); got != "a13 a14 a15 " || !page.MoreAfter {
+		t.Errorf("expected the next page a13 a14 a15, got %q", got)
+	}
+}
+
+func TestGetContext_Errors(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	storeRecords(t, storage, []domain.LogRecord{{ID: "x", Timestamp: 1, Level: "INFO", Message: "m", Raw: "m"}})
+
+	if _, err := storage.GetContext(context.Background(), "missing", 5, 5); err == nil {
+		t.Error("expected an error for a missing record")
+	}
+	if _, err := storage.GetContext(context.Background(), "x", 5, 5); err == nil {
+		t.Error("expected an error for a record without a line number")
+	}
+	if _, err := storage.GetContext(context.Background(), "x", -1, 5); err == nil {
+		t.Error("expected an error for a negative line count")
+	}
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"LogLens/internal/domain"
+)
+
+const defaultFacetSize = 10
+
+// Facets returns the topN values of each field among the records matching
+// query.Filters, ordered by count. Each field takes one pass over the
+// records: the grouped counts are materialized once and split into the top
+// values, the other bucket and the missing count.
+func (s *SQLiteStorage) Facets(ctx context.Context, query domain.Query, fields []string, topN int) ([]domain.Facet, error) {
+	release := s.usePartitions()
+	defer release()
+
+	if topN <= 0 {
+		topN = defaultFacetSize
+	}
+	where, whereArgs, err := s.buildWhere(query.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := s.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	facets := make([]domain.Facet, 0, len(fields))
+	for _, field := range fields {
+		expr, ok := s.fieldExpr(field)
+		if !ok {
+			return nil, fmt.Errorf("invalid facet field: %s", field)
+		}
+
+		q := `WITH counts AS MATERIALIZED (
+				SELECT ` + expr + ` AS v, COUNT(*) AS c FROM ` + s.recordsFrom(query.Filters) + where + ` GROUP BY 1
+			),
+			ranked AS (
+				SELECT v, c, ROW_NUMBER() OVER (ORDER BY c DESC, v) AS rn FROM counts WHERE v IS NOT NULL AND v != ''
+			)
+			SELECT 0, v, c FROM ranked WHERE rn <= ?
+			UNION ALL SELECT 1, NULL, COALESCE(SUM(c), 0) FROM ranked WHERE rn > ?
+			UNION ALL SELECT 2, NULL, COALESCE(SUM(c), 0) FROM counts WHERE v IS NULL OR v = ''
+			ORDER BY 1, 3 DESC, 2`
+		args := append(append([]interface{}(nil), whereArgs...), topN, topN)
+
+		rows, err := tx.QueryContext(ctx, q, args...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute facet %s: %w", field, err)
+		}
+		facet, err := scanFacet(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to compute facet %s: %w", field, err)
+		}
+		facet.Field = field
+		facets = append(facets, facet)
+	}
+	return facets, nil
+}
+
+func scanFacet(rows *sql.Rows) (domain.Facet, error) {
+	facet := domain.Facet{Values: make([]domain.FacetValue, 0)}
+	defer rows.Close()
+
+	for rows.Next() {
+		var kind int
+		var value interface{}
+		var count int64
+		if err := rows.Scan(&kind, &value, &count); err != nil {
+			return facet, err
+		}
+		switch kind {
+		case 0:
+			facet.Values = append(facet.Values, domain.FacetValue{Value: value, Count: count})
+		case 1:
+			facet.Other = count
+		case 2:
+			facet.Missing = count
+		}
+	}
+	return facet, rows.Err()
+}
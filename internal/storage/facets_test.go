@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"LogLens/internal/domain"
+)
+
+func TestFacets_TopValuesOtherAndMissing(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	records := serviceRecords(120)
+	for i := range records {
+		if i%10 == 0 {
+			records[i].Service = ""
+			delete(records[i].Fields, "status")
+		}
+	}
+	storeRecords(t, storage, records)
+
+	facets, err := storage.Facets(context.Background(), domain.Query{
+		Filters: []domain.FilterCondition{{Type: domain.FilterEquality, Field: "level", Value: "INFO"}},
+	}, []string{"service", "status", "level"}, 2)
+	if err != nil {
+		t.Fatalf("Facets failed: %v", err)
+	}
+	if len(facets) != 3 {
+		t.Fatalf("expected 3 facets, got %d", len(facets))
+	}
+
+	// INFO records are i%4 != 0: 30 each for api, worker and db. Of the
+	// records losing their service, i = 10, 30, ..., 110 are INFO workers.
+	service := facets[0]
+	if service.Field != "service" || service.Missing != 6 {
+		t.Errorf("expected 6 records missing service, got %+v", service)
+	}
+	if len(service.Values) != 2 || service.Values[0].Value != "api" || service.Values[0].Count != 30 {
+		t.Errorf("expected api first with 30, got %+v", service.Values)
+	}
+	if service.Values[1].Value != "db" || service.Values[1].Count != 30 || service.Other != 24 {
+		t.Errorf("expected db=30 and other=24 (worker), got %+v", service)
+	}
+
+	status := facets[1]
+	var total int64
+	for _, v := range status.Values {
+		total += v.Count
+	}
+	if status.Missing != 6 || total+status.Other+status.Missing != 90 {
+		t.Errorf("status counts do not add up to 90: %+v", status)
+	}
+	if _, ok := status.Values[0].Value.(int64); !ok {
+		t.Errorf("expected numeric status values, got %T", status.Values[0].Value)
+	}
+
+	level := facets[2]
+	if len(level.Values) != 1 || level.Values[0].Count != 90 || level.Other != 0 || level.Missing != 0 {
+		t.Errorf("unexpected level facet: %+v", level)
+	}
+}
+
+func TestFacets_InvalidField(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	if _, err := storage.Facets(context.Background(), domain.Query{}, []string{`a"b`}, 5); err == nil {
+		t.Error("expected error for invalid facet field")
+	}
+}
@@ -0,0 +1,96 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"hash/fnv"
+)
+
+// Custom fields live in the fields JSON, so an equality filter on one scans
+// every row. IndexFields adds an index on the exact expression fieldExpr
+// filters on, which SQLite then uses for lookups. The indexed fields are
+// kept in field_indexes so partitions created later are indexed too.
+
+// fieldIndexName is unique per table and field; field names can hold any
+// character, so they are hashed.
+func fieldIndexName(table, field string) string {
+	h := fnv.New64a()
+	h.Write([]byte(field))
+	return fmt.Sprintf("idx_%s_field_%016x", table, h.Sum64())
+}
+
+func (s *SQLiteStorage) createFieldIndex(ctx context.Context, tx *sql.Tx, table, field string) error {
+	expr, ok := s.fieldExpr(field)
+	if !ok {
+		return fmt.Errorf("invalid field: %s", field)
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s(%s)", fieldIndexName(table, field), table, expr)); err != nil {
+		return fmt.Errorf("failed to index %s on %s: %w", field, table, err)
+	}
+	return nil
+}
+
+// IndexFields indexes the custom fields on the records table and every
+// partition. Built-in columns are already indexed where useful and skipped.
+func (s *SQLiteStorage) IndexFields(ctx context.Context, fields []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	tables, err := partitionNames(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("failed to list partitions: %w", err)
+	}
+	tables = append([]string{"records"}, tables...)
+
+	for _, field := range fields {
+		if _, ok := s.allowedColumn(field); ok {
+			continue
+		}
+		res, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO field_indexes (field) VALUES (?)", field)
+		if err != nil {
+			return fmt.Errorf("failed to record field index: %w", err)
+		}
+		if n, _ := res.RowsAffected(); n == 0 {
+			continue
+		}
+		for _, table := range tables {
+			if err := s.createFieldIndex(ctx, tx, table, field); err != nil {
+				return err
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+// indexNewPartition adds the recorded field indexes to a partition being
+// created in tx.
+func (s *SQLiteStorage) indexNewPartition(ctx context.Context, tx *sql.Tx, table string) error {
+	rows, err := tx.QueryContext(ctx, "SELECT field FROM field_indexes ORDER BY field")
+	if err != nil {
+		return fmt.Errorf("failed to read field indexes: %w", err)
+	}
+	var fields []string
+	for rows.Next() {
+		var field string
+		if err := rows.Scan(&field); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to read field indexes: %w", err)
+		}
+		fields = append(fields, field)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read field indexes: %w", err)
+	}
+
+	for _, field := range fields {
+		if err := s.createFieldIndex(ctx, tx, table, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,84 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"LogLens/internal/domain"
+)
+
+func explainPlan(t *testing.T, storage *SQLiteStorage, q string, args ...interface{}) string {
+	t.Helper()
+	rows, err := storage.rdb.Query("EXPLAIN QUERY PLAN "+q, args...)
+	if err != nil {
+		t.Fatalf("EXPLAIN failed: %v", err)
+	}
+	defer rows.Close()
+	var plan []string
+	for rows.Next() {
+		var id, parent, unused int
+		var detail string
+		if err := rows.Scan(&id, &parent, &unused, &detail); err != nil {
+			t.Fatalf("failed to scan plan: %v", err)
+		}
+		plan = append(plan, detail)
+	}
+	return strings.Join(plan, "\n")
+}
+
+func requestRecords(day int64, n int) []domain.LogRecord {
+	records := make([]domain.LogRecord, n)
+	for i := range records {
+		records[i] = domain.LogRecord{
+			ID:        fmt.Sprintf("d%d_%d", day, i),
+			Timestamp: day*dayMs + int64(i),
+			Level:     "INFO",
+			Message:   "handled",
+			Fields:    map[string]interface{}{"request_id": fmt.Sprintf("r-%d", i%10)},
+			Raw:       "handled",
+		}
+	}
+	return records
+}
+
+func TestIndexFields_UsedByEqualityFilters(t *testing.T) {
+	storage, cleanup := newPartitionedStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	storeRecords(t, storage, requestRecords(0, 50))
+	if err := storage.IndexFields(ctx, []string{"request_id", "level"}); err != nil {
+		t.Fatalf("IndexFields failed: %v", err)
+	}
+	if err := storage.IndexFields(ctx, []string{"request_id"}); err != nil {
+		t.Fatalf("repeated IndexFields failed: %v", err)
+	}
+	// A partition created afterwards is indexed as well.
+	storeRecords(t, storage, requestRecords(1, 50))
+
+	filters := []domain.FilterCondition{{Type: domain.FilterEquality, Field: "request_id", Value: "r-3"}}
+	where, args, err := storage.buildWhere(filters)
+	if err != nil {
+		t.Fatalf("buildWhere failed: %v", err)
+	}
+	tables := storage.recordTables(nil)
+	if len(tables) != 2 {
+		t.Fatalf("expected 2 partitions, got %v", tables)
+	}
+	for _, table := range tables {
+		plan := explainPlan(t, storage, "SELECT id FROM "+table+where, args...)
+		if !strings.Contains(plan, fieldIndexName(table, "request_id")) {
+			t.Errorf("expected %s to use its request_id index, got plan:\n%s", table, plan)
+		}
+	}
+
+	result, err := storage.Query(ctx, domain.Query{Filters: filters, Limit: 100})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if result.Total != 10 {
+		t.Errorf("expected 10 records with r-3, got %d", result.Total)
+	}
+}
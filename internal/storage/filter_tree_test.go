@@ -0,0 +1,129 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"testing"
+
+	"LogLens/internal/domain"
+	"LogLens/internal/query"
+)
+
+// errorsOutsideHealthcheck is (level=ERROR OR level=FATAL) AND NOT service=healthcheck.
+var errorsOutsideHealthcheck = []domain.FilterCondition{
+	{Type: domain.FilterOr, Children: []domain.FilterCondition{
+		{Type: domain.FilterEquality, Field: "level", Value: "ERROR"},
+		{Type: domain.FilterEquality, Field: "level", Value: "FATAL"},
+	}},
+	{Type: domain.FilterNot, Children: []domain.FilterCondition{
+		{Type: domain.FilterEquality, Field: "service", Value: "healthcheck"},
+	}},
+}
+
+func TestSQLiteStorage_FilterTreeMatchesFilterEngine(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	levels := []string{"INFO", "ERROR", "FATAL", "WARN"}
+	services := []string{"api", "healthcheck", "worker"}
+	var records []domain.LogRecord
+	for i := 0; i < 120; i++ {
+		fields := make(map[string]interface{})
+		if i%3 == 0 {
+			fields["user"] = services[i%2]
+		}
+		records = append(records, domain.LogRecord{
+			ID:        fmt.Sprintf("r%d", i),
+			Timestamp: int64(i) * 1000,
+			Level:     levels[i%len(levels)],
+			Message:   fmt.Sprintf("request %d timed out", i),
+			Service:   services[i%len(services)],
+			Fields:    fields,
+			Raw:       "raw",
+		})
+	}
+	storeRecords(t, storage, records)
+
+	trees := map[string][]domain.FilterCondition{
+		"or and not": errorsOutsideHealthcheck,
+		"nested": {{Type: domain.FilterAnd, Children: []domain.FilterCondition{
+			{Type: domain.FilterRange, Field: "timestamp", Operator: "gte", Value: int64(30000)},
+			{Type: domain.FilterNot, Children: []domain.FilterCondition{
+				{Type: domain.FilterOr, Children: []domain.FilterCondition{
+					{Type: domain.FilterRegexp, Field: "message", Value: `request \d*7 `},
+					{Type: domain.FilterContains, Field: "service", Value: "WORK"},
+				}},
+			}},
+		}}},
+		"exclusion on missing field": {
+			{Type: domain.FilterExclusion, Field: "user", Value: "api"},
+		},
+		"not exclusion on missing field": {{Type: domain.FilterNot, Children: []domain.FilterCondition{
+			{Type: domain.FilterExclusion, Field: "user", Value: "api"},
+		}}},
+		"empty or":  {{Type: domain.FilterOr}},
+		"empty and": {{Type: domain.FilterAnd}},
+	}
+
+	engine := query.NewFilterEngine()
+	for name, filters := range trees {
+		t.Run(name, func(t *testing.T) {
+			filter, err := engine.BuildFilter(filters)
+			if err != nil {
+				t.Fatalf("BuildFilter failed: %v", err)
+			}
+			var want []string
+			for _, r := range records {
+				if filter.Match(r) {
+					want = append(want, r.ID)
+				}
+			}
+
+			result, err := storage.Query(context.Background(), domain.Query{Filters: filters, Limit: 1000})
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			var got []string
+			for _, r := range result.Records {
+				got = append(got, r.ID)
+			}
+
+			sort.Strings(want)
+			sort.Strings(got)
+			if fmt.Sprint(got) != fmt.Sprint(want) || result.Total != int64(len(want)) {
+				t.Errorf("SQL matched %d records (total %d), FilterEngine matched %d\n got %v\nwant %v",
+					len(got), result.Total, len(want), got, want)
+			}
+		})
+	}
+}
+
+func TestSQLiteStorage_FilterTreeRejectsBadNot(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	_, err := storage.Query(context.Background(), domain.Query{
+		Filters: []domain.FilterCondition{{Type: domain.FilterNot}},
+		Limit:   10,
+	})
+	if err == nil {
+		t.Error("expected error for not filter without a child")
+	}
+}
+
+func TestTimestampBounds_AndGroups(t *testing.T) {
+	lo, hi := timestampBounds([]domain.FilterCondition{
+		{Type: domain.FilterAnd, Children: []domain.FilterCondition{
+			{Type: domain.FilterRange, Field: "timestamp", Operator: "gte", Value: int64(1000)},
+		}},
+		{Type: domain.FilterOr, Children: []domain.FilterCondition{
+			{Type: domain.FilterRange, Field: "timestamp", Operator: "lt", Value: int64(2000)},
+			{Type: domain.FilterEquality, Field: "level", Value: "ERROR"},
+		}},
+	})
+	if lo != 1000 || hi != math.MaxInt64 {
+		t.Errorf("expected [1000, max], got [%d, %d]", lo, hi)
+	}
+}
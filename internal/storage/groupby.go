@@ -0,0 +1,271 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"LogLens/internal/domain"
+)
+
+// aggregationAlias is the result key for agg: its alias, or function_field.
+// Percentiles are named after their rank, as in p99.9_latency.
+func aggregationAlias(agg domain.Aggregation) string {
+	if agg.Alias != "" {
+		return agg.Alias
+	}
+	name := agg.Function
+	if name == "percentile" {
+		name = "p" + strconv.FormatFloat(agg.Percentile, 'f', -1, 64)
+	}
+	if agg.Field != "" {
+		return name + "_" + agg.Field
+	}
+	return name
+}
+
+// percentileRanks maps the shorthand percentile functions to their rank.
+var percentileRanks = map[string]float64{"p50": 50, "p90": 90, "p95": 95, "p99": 99}
+
+func sqlFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// aggregationExpr returns the SQL for agg over a built-in column or custom
+// field.
+func (s *SQLiteStorage) aggregationExpr(agg domain.Aggregation) (string, error) {
+	field := "*"
+	if agg.Field != "" && agg.Field != "*" {
+		expr, ok := s.fieldExpr(agg.Field)
+		if !ok {
+			return "", fmt.Errorf("invalid aggregation field: %s", agg.Field)
+		}
+		field = expr
+	}
+
+	switch agg.Function {
+	case "count":
+		if field == "*" {
+			return "COUNT(*)", nil
+		}
+		return "COUNT(DISTINCT " + field + ")", nil
+	case "avg":
+		return "AVG(CAST(" + field + " AS REAL))", nil
+	case "sum":
+		return "SUM(CAST(" + field + " AS REAL))", nil
+	case "min":
+		return "MIN(" + field + ")", nil
+	case "max":
+		return "MAX(" + field + ")", nil
+	case "error_ratio":
+		return "AVG(UPPER(level) IN ('ERROR', 'FATAL', 'PANIC'))", nil
+	}
+
+	if field == "*" {
+		return "", fmt.Errorf("%s aggregation requires a field", agg.Function)
+	}
+	switch agg.Function {
+	case "p50", "p90", "p95", "p99":
+		return "percentile(" + field + ", " + sqlFloat(percentileRanks[agg.Function]) + ")", nil
+	case "percentile":
+		if agg.Percentile <= 0 || agg.Percentile > 100 {
+			return "", fmt.Errorf("percentile must be in (0, 100], got %v", agg.Percentile)
+		}
+		return "percentile(" + field + ", " + sqlFloat(agg.Percentile) + ")", nil
+	case "stddev":
+		return "stddev(" + field + ")", nil
+	case "histogram":
+		if agg.Interval <= 0 && agg.LogBase <= 1 {
+			return "", fmt.Errorf("histogram requires an interval > 0 or a log base > 1")
+		}
+		return "histogram(" + field + ", " + sqlFloat(agg.Interval) + ", " + sqlFloat(agg.LogBase) + ")", nil
+	case "cardinality":
+		return "approx_distinct(" + field + ")", nil
+	default:
+		return "", fmt.Errorf("unsupported aggregation function: %s", agg.Function)
+	}
+}
+
+// aggregationSelect builds the select list for aggs, aliased a0, a1, ...,
+// and describes the resulting columns.
+func (s *SQLiteStorage) aggregationSelect(aggs []domain.Aggregation) ([]string, aggregationColumns, error) {
+	exprs := make([]string, len(aggs))
+	aliases := make([]string, len(aggs))
+	seen := make(map[string]bool)
+	histograms := make(map[string]bool)
+	for i, agg := range aggs {
+		expr, err := s.aggregationExpr(agg)
+		if err != nil {
+			return nil, aggregationColumns{}, err
+		}
+		alias := aggregationAlias(agg)
+		if seen[alias] {
+			return nil, aggregationColumns{}, fmt.Errorf("duplicate aggregation alias: %s", alias)
+		}
+		seen[alias] = true
+		exprs[i] = fmt.Sprintf("%s AS a%d", expr, i)
+		aliases[i] = alias
+		histograms[alias] = agg.Function == "histogram"
+	}
+	return exprs, aggregationColumns{aliases, histograms}, nil
+}
+
+// aggregationColumns names the aggregation results of a select list.
+type aggregationColumns struct {
+	aliases    []string
+	histograms map[string]bool
+}
+
+// value converts the raw result for alias, decoding histograms.
+func (c aggregationColumns) value(alias string, v interface{}) (interface{}, error) {
+	if !c.histograms[alias] {
+		return v, nil
+	}
+	text, _ := v.(string)
+	buckets := make([]domain.HistogramBucket, 0)
+	if err := json.Unmarshal([]byte(text), &buckets); err != nil {
+		return nil, fmt.Errorf("failed to decode histogram %s: %w", alias, err)
+	}
+	return buckets, nil
+}
+
+// groupFields returns query.GroupBy with "timestamp" added when the query
+// buckets by time.
+func groupFields(query domain.Query) []string {
+	fields := query.GroupBy
+	if query.BucketMs <= 0 {
+		return fields
+	}
+	for _, f := range fields {
+		if strings.EqualFold(f, "timestamp") {
+			return fields
+		}
+	}
+	return append([]string{"timestamp"}, fields...)
+}
+
+// AggregateGroups computes query.Aggregations for each group of
+// query.GroupBy (and time bucket) under query.Filters in a single pass.
+// Groups are ordered by GroupSort, an aggregation alias or group field,
+// falling back to the group keys, and cut to GroupLimit.
+func (s *SQLiteStorage) AggregateGroups(ctx context.Context, query domain.Query) ([]domain.GroupBucket, error) {
+	release := s.usePartitions()
+	defer release()
+
+	fields := groupFields(query)
+	if len(fields) == 0 {
+		return nil, fmt.Errorf("grouped aggregation requires groupBy or bucketMs")
+	}
+	aggs := query.Aggregations
+	if len(aggs) == 0 {
+		aggs = []domain.Aggregation{{Function: "count"}}
+	}
+
+	where, whereArgs, err := s.buildWhere(query.Filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var selects, keys []string
+	var args []interface{}
+	for i, field := range fields {
+		expr, ok := s.fieldExpr(field)
+		if !ok {
+			return nil, fmt.Errorf("invalid group field: %s", field)
+		}
+		if query.BucketMs > 0 && strings.EqualFold(field, "timestamp") {
+			var bucketArgs []interface{}
+			expr, bucketArgs = bucketExpr(query.BucketMs)
+			args = append(args, bucketArgs...)
+		}
+		selects = append(selects, fmt.Sprintf("%s AS g%d", expr, i))
+		keys = append(keys, fmt.Sprintf("g%d", i))
+	}
+	aggSelects, columns, err := s.aggregationSelect(aggs)
+	if err != nil {
+		return nil, err
+	}
+
+	order, err := groupOrder(query, fields, columns.aliases)
+	if err != nil {
+		return nil, err
+	}
+
+	q := "SELECT " + strings.Join(append(selects, aggSelects...), ", ") +
+		" FROM " + s.recordsFrom(query.Filters) + where +
+		" GROUP BY " + strings.Join(keys, ", ") +
+		" ORDER BY " + order
+	args = append(args, whereArgs...)
+	if query.GroupLimit > 0 {
+		q += " LIMIT ?"
+		args = append(args, query.GroupLimit)
+	}
+
+	rows, err := s.rdb.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute grouped aggregation: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := make([]domain.GroupBucket, 0)
+	for rows.Next() {
+		values := make([]interface{}, len(fields)+len(aggs))
+		ptrs := make([]interface{}, len(values))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("failed to scan grouped aggregation: %w", err)
+		}
+
+		bucket := domain.GroupBucket{
+			Key:    make(map[string]interface{}, len(fields)),
+			Values: make(map[string]interface{}, len(aggs)),
+		}
+		for i, field := range fields {
+			bucket.Key[field] = values[i]
+		}
+		for i, alias := range columns.aliases {
+			if bucket.Values[alias], err = columns.value(alias, values[len(fields)+i]); err != nil {
+				return nil, err
+			}
+		}
+		buckets = append(buckets, bucket)
+	}
+	return buckets, rows.Err()
+}
+
+// groupOrder resolves query.GroupSort against the aggregation aliases and
+// group fields. The group keys always follow as tiebreakers.
+func groupOrder(query domain.Query, fields, aliases []string) (string, error) {
+	var terms []string
+	dir := " ASC"
+	if query.GroupSortDesc {
+		dir = " DESC"
+	}
+
+	if query.GroupSort != "" {
+		col := ""
+		for i, alias := range aliases {
+			if alias == query.GroupSort {
+				col = fmt.Sprintf("a%d", i)
+			}
+		}
+		for i, field := range fields {
+			if col == "" && strings.EqualFold(field, query.GroupSort) {
+				col = fmt.Sprintf("g%d", i)
+			}
+		}
+		if col == "" {
+			return "", fmt.Errorf("invalid group sort: %s", query.GroupSort)
+		}
+		terms = append(terms, col+dir)
+	}
+
+	for i := range fields {
+		terms = append(terms, fmt.Sprintf("g%d ASC", i))
+	}
+	return strings.Join(terms, ", "), nil
+}
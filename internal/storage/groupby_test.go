@@ -0,0 +1,126 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"LogLens/internal/domain"
+)
+
+// serviceRecords returns n records cycling through three services, with a
+// custom status field and an ERROR every fourth record.
+func serviceRecords(n int) []domain.LogRecord {
+	services := []string{"api", "api", "worker", "db"}
+	records := make([]domain.LogRecord, n)
+	for i := range records {
+		level := "INFO"
+		if i%4 == 0 {
+			level = "ERROR"
+		}
+		records[i] = domain.LogRecord{
+			ID:        fmt.Sprintf("rec_%d", i),
+			Timestamp: int64(i) * 1000,
+			Level:     level,
+			Message:   fmt.Sprintf("message %d", i),
+			Service:   services[i%len(services)],
+			Fields:    map[string]interface{}{"status": float64(200 + (i%3)*100)},
+			Raw:       "raw",
+		}
+	}
+	return records
+}
+
+func TestAggregateGroups_TopNByAggregate(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	storeRecords(t, storage, serviceRecords(120))
+
+	groups, err := storage.AggregateGroups(context.Background(), domain.Query{
+		GroupBy: []string{"service"},
+		Aggregations: []domain.Aggregation{
+			{Function: "count"},
+			{Function: "max", Field: "status"},
+			{Function: "count", Field: "level", Alias: "levels"},
+		},
+		GroupSort:     "count",
+		GroupSortDesc: true,
+		GroupLimit:    2,
+	})
+	if err != nil {
+		t.Fatalf("AggregateGroups failed: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %+v", groups)
+	}
+	if groups[0].Key["service"] != "api" || groups[0].Values["count"] != int64(60) {
+		t.Errorf("expected api with 60 records first, got %+v", groups[0])
+	}
+	if groups[1].Key["service"] != "db" || groups[1].Values["count"] != int64(30) {
+		t.Errorf("expected db with 30 records second, got %+v", groups[1])
+	}
+	if groups[0].Values["max_status"] != int64(400) || groups[0].Values["levels"] != int64(2) {
+		t.Errorf("unexpected values for api: %+v", groups[0].Values)
+	}
+}
+
+func TestAggregateGroups_CustomFieldAndTimeBuckets(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	storeRecords(t, storage, serviceRecords(120))
+
+	groups, err := storage.AggregateGroups(context.Background(), domain.Query{
+		Filters:  []domain.FilterCondition{{Type: domain.FilterEquality, Field: "level", Value: "ERROR"}},
+		GroupBy:  []string{"status"},
+		BucketMs: 60000,
+	})
+	if err != nil {
+		t.Fatalf("AggregateGroups failed: %v", err)
+	}
+
+	// ERRORs are every 4th record, so status cycles 200, 300, 400 with 5
+	// records each per minute.
+	if len(groups) != 6 {
+		t.Fatalf("expected 2 buckets x 3 statuses, got %+v", groups)
+	}
+	first := groups[0]
+	if first.Key["timestamp"] != int64(0) || first.Key["status"] != int64(200) || first.Values["count"] != int64(5) {
+		t.Errorf("unexpected first group: %+v", first)
+	}
+	if last := groups[5]; last.Key["timestamp"] != int64(60000) || last.Key["status"] != int64(400) {
+		t.Errorf("unexpected last group: %+v", last)
+	}
+}
+
+func TestAggregateGroups_Errors(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	for name, query := range map[string]domain.Query{
+		"no groups":     {Aggregations: []domain.Aggregation{{Function: "count"}}},
+		"bad sort":      {GroupBy: []string{"level"}, GroupSort: "nope"},
+		"bad field":     {GroupBy: []string{`bad"field`}},
+		"duplicate agg": {GroupBy: []string{"level"}, Aggregations: []domain.Aggregation{{Function: "count"}, {Function: "count"}}},
+	} {
+		if _, err := storage.AggregateGroups(context.Background(), query); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestAggregate_SinglePassWithCustomFields(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	storeRecords(t, storage, serviceRecords(120))
+
+	results, err := storage.Aggregate(context.Background(),
+		[]domain.FilterCondition{{Type: domain.FilterRange, Field: "status", Operator: "gte", Value: float64(300)}},
+		[]domain.Aggregation{{Function: "count"}, {Function: "avg", Field: "status"}, {Function: "min", Field: "timestamp"}},
+	)
+	if err != nil {
+		t.Fatalf("Aggregate failed: %v", err)
+	}
+	if results["count"] != int64(80) || results["avg_status"] != 350.0 || results["min_timestamp"] != int64(1000) {
+		t.Errorf("unexpected aggregates: %+v", results)
+	}
+}
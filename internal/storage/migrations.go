@@ -0,0 +1,353 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrSchemaTooNew = errors.New("database schema is newer than this version of LogLens supports")
+
+// migration moves the schema from version-1 to version. Migrations run in
+// order, each inside its own transaction together with its schema_version
+// row. The first release predates schema_version, so the first step must
+// tolerate a records table that already exists.
+type migration struct {
+	version int
+	name    string
+	up      func(ctx context.Context, tx *sql.Tx) error
+}
+
+var migrations = []migration{
+	{version: 1, name: "records table", up: migrateRecordsTable},
+	{version: 2, name: "record source and field catalog", up: migrateFieldCatalog},
+	{version: 3, name: "retention policies", up: migrateRetentionPolicies},
+	{version: 4, name: "record line numbers", up: migrateRecordLines},
+	{version: 5, name: "deferred index journal", up: migrateDeferredIndexes},
+	{version: 6, name: "time partitions", up: migratePartitions},
+	{version: 7, name: "compression dictionaries", up: migrateCompressionDicts},
+	{version: 8, name: "record source line index", up: migrateSourceLineIndex},
+	{version: 9, name: "partition record ids", up: migratePartitionIDs},
+	{version: 10, name: "record blocks", up: migrateRecordBlocks},
+	{version: 11, name: "field indexes", up: migrateFieldIndexes},
+}
+
+func latestSchemaVersion() int {
+	return migrations[len(migrations)-1].version
+}
+
+func (s *SQLiteStorage) migrate(ctx context.Context) error {
+	if _, err := s.db.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			name TEXT NOT NULL,
+			applied_at INTEGER NOT NULL
+		)
+	`); err != nil {
+		return fmt.Errorf("failed to create schema_version table: %w", err)
+	}
+
+	current, err := s.SchemaVersion(ctx)
+	if err != nil {
+		return err
+	}
+
+	latest := latestSchemaVersion()
+	if current > latest {
+		return fmt.Errorf("%w: database is at version %d, latest known is %d; upgrade LogLens to open it",
+			ErrSchemaTooNew, current, latest)
+	}
+	if current == latest {
+		return nil
+	}
+
+	hasData, err := s.tableExists(ctx, "records")
+	if err != nil {
+		return err
+	}
+	if hasData {
+		if _, err := s.backup(ctx, current); err != nil {
+			return err
+		}
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := s.applyMigration(ctx, m); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (s *SQLiteStorage) applyMigration(ctx context.Context, m migration) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin migration %d: %w", m.version, err)
+	}
+	defer tx.Rollback()
+
+	if err := m.up(ctx, tx); err != nil {
+		return fmt.Errorf("migration %d (%s) failed: %w", m.version, m.name, err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)",
+		m.version, m.name, time.Now().UnixMilli(),
+	); err != nil {
+		return fmt.Errorf("failed to record migration %d: %w", m.version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit migration %d: %w", m.version, err)
+	}
+	return nil
+}
+
+func (s *SQLiteStorage) SchemaVersion(ctx context.Context) (int, error) {
+	var version int
+	if err := s.db.QueryRowContext(ctx, "SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// backup writes a consistent copy of the database next to it before any
+// migration touches existing data.
+func (s *SQLiteStorage) backup(ctx context.Context, fromVersion int) (string, error) {
+	path := fmt.Sprintf("%s.v%d-%s.bak", s.dbPath, fromVersion, time.Now().Format("20060102-150405"))
+	escaped := strings.ReplaceAll(path, "'", "''")
+	if _, err := s.db.ExecContext(ctx, "VACUUM INTO '"+escaped+"'"); err != nil {
+		return "", fmt.Errorf("failed to back up database before migration: %w", err)
+	}
+	return path, nil
+}
+
+func (s *SQLiteStorage) tableExists(ctx context.Context, table string) (bool, error) {
+	var n int
+	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&n)
+	if err != nil {
+		return false, fmt.Errorf("failed to inspect schema: %w", err)
+	}
+	return n > 0, nil
+}
+
+func addColumnIfMissing(ctx context.Context, tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.QueryContext(ctx, fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+
+	found := false
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to inspect table %s: %w", table, err)
+		}
+		if name == column {
+			found = true
+		}
+	}
+	if err := rows.Err(); err != nil {
+		rows.Close()
+		return fmt.Errorf("failed to inspect table %s: %w", table, err)
+	}
+	rows.Close()
+
+	if found {
+		return nil
+	}
+	if _, err := tx.ExecContext(ctx, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)); err != nil {
+		return fmt.Errorf("failed to add column %s.%s: %w", table, column, err)
+	}
+	return nil
+}
+
+func migrateRecordsTable(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS records (
+		id TEXT PRIMARY KEY,
+		timestamp INTEGER NOT NULL,
+		level TEXT NOT NULL,
+		message TEXT NOT NULL,
+		service TEXT,
+		fields TEXT,
+		raw TEXT NOT NULL,
+		created_at INTEGER DEFAULT (strftime('%s', 'now'))
+	);
+	CREATE INDEX IF NOT EXISTS idx_records_timestamp ON records(timestamp);
+	CREATE INDEX IF NOT EXISTS idx_records_timestamp_level ON records(timestamp, level);
+	CREATE INDEX IF NOT EXISTS idx_records_timestamp_service ON records(timestamp, service);
+	CREATE INDEX IF NOT EXISTS idx_records_level ON records(level);
+	CREATE INDEX IF NOT EXISTS idx_records_service ON records(service);
+	CREATE INDEX IF NOT EXISTS idx_records_created_at ON records(created_at);
+	`)
+	return err
+}
+
+func migrateFieldCatalog(ctx context.Context, tx *sql.Tx) error {
+	if err := addColumnIfMissing(ctx, tx, "records", "source", "TEXT NOT NULL DEFAULT ''"); err != nil {
+		return err
+	}
+	_, err := tx.ExecContext(ctx, `
+	CREATE INDEX IF NOT EXISTS idx_records_source ON records(source);
+	CREATE TABLE IF NOT EXISTS catalog_sources (
+		source TEXT PRIMARY KEY,
+		records INTEGER NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS field_catalog (
+		source TEXT NOT NULL,
+		path TEXT NOT NULL,
+		types TEXT NOT NULL,
+		samples TEXT NOT NULL,
+		seen INTEGER NOT NULL,
+		nulls INTEGER NOT NULL,
+		sketch BLOB NOT NULL,
+		PRIMARY KEY (source, path)
+	);
+	`)
+	return err
+}
+
+func migrateRetentionPolicies(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS retention_policies (
+		source TEXT PRIMARY KEY,
+		max_age_ms INTEGER NOT NULL DEFAULT 0,
+		max_rows INTEGER NOT NULL DEFAULT 0,
+		max_bytes INTEGER NOT NULL DEFAULT 0
+	);
+	`)
+	return err
+}
+
+func migrateRecordLines(ctx context.Context, tx *sql.Tx) error {
+	return addColumnIfMissing(ctx, tx, "records", "line", "INTEGER NOT NULL DEFAULT 0")
+}
+
+func migrateDeferredIndexes(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS deferred_indexes (
+		name TEXT PRIMARY KEY,
+		sql TEXT NOT NULL
+	);
+	`)
+	return err
+}
+
+func migratePartitions(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS storage_settings (
+		key TEXT PRIMARY KEY,
+		value TEXT NOT NULL
+	);
+	CREATE TABLE IF NOT EXISTS partitions (
+		name TEXT PRIMARY KEY,
+		start_ms INTEGER NOT NULL,
+		end_ms INTEGER NOT NULL
+	);
+	`)
+	return err
+}
+
+func migrateCompressionDicts(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS compression_dicts (
+		id INTEGER PRIMARY KEY,
+		dict BLOB NOT NULL,
+		created_at INTEGER NOT NULL
+	);
+	`)
+	return err
+}
+
+// migrateSourceLineIndex indexes records in file order, on records and on
+// every existing partition.
+func migrateSourceLineIndex(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, "CREATE INDEX IF NOT EXISTS idx_records_source_line ON records(source, line)"); err != nil {
+		return err
+	}
+
+	parts, err := partitionNames(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s(source, line)", partitionIndexName(p, "source, line"), p)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func partitionNames(ctx context.Context, tx *sql.Tx) ([]string, error) {
+	rows, err := tx.QueryContext(ctx, "SELECT name FROM partitions ORDER BY start_ms")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var parts []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+		parts = append(parts, name)
+	}
+	return parts, rows.Err()
+}
+
+// migratePartitionIDs records which partition holds each record ID, so IDs
+// stay unique across partitions. Where existing partitions already share an
+// ID, the oldest partition keeps it.
+func migratePartitionIDs(ctx context.Context, tx *sql.Tx) error {
+	if _, err := tx.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS partition_ids (
+		id TEXT PRIMARY KEY,
+		part TEXT NOT NULL
+	) WITHOUT ROWID;
+	CREATE INDEX IF NOT EXISTS idx_partition_ids_part ON partition_ids(part);
+	`); err != nil {
+		return err
+	}
+
+	parts, err := partitionNames(ctx, tx)
+	if err != nil {
+		return err
+	}
+	for _, p := range parts {
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO partition_ids (id, part) SELECT id, ? FROM "+p, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateRecordBlocks adds the table holding compressed blocks of raw lines.
+// tbl is the record table whose rows point into the block.
+func migrateRecordBlocks(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, `
+	CREATE TABLE IF NOT EXISTS record_blocks (
+		id INTEGER PRIMARY KEY,
+		tbl TEXT NOT NULL,
+		data BLOB NOT NULL
+	);
+	CREATE INDEX IF NOT EXISTS idx_record_blocks_tbl ON record_blocks(tbl);
+	`)
+	return err
+}
+
+// migrateFieldIndexes adds the list of custom fields given an expression
+// index by IndexFields.
+func migrateFieldIndexes(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS field_indexes (field TEXT PRIMARY KEY)")
+	return err
+}
@@ -0,0 +1,160 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// buildFixture creates a database at version. Version 0 is the frozen schema
+// of the first release, which predates schema_version; later versions are
+// built by the migrations themselves.
+func buildFixture(t *testing.T, dbPath string, version int) {
+	t.Helper()
+	db, err := sql.Open("sqlite", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open fixture: %v", err)
+	}
+	defer db.Close()
+
+	if version == 0 {
+		baseline, err := os.ReadFile(filepath.Join("testdata", "baseline.sql"))
+		if err != nil {
+			t.Fatalf("failed to read baseline fixture: %v", err)
+		}
+		if _, err := db.Exec(string(baseline)); err != nil {
+			t.Fatalf("failed to load baseline fixture: %v", err)
+		}
+		return
+	}
+
+	ctx := context.Background()
+	if _, err := db.Exec(`CREATE TABLE schema_version (version INTEGER PRIMARY KEY, name TEXT NOT NULL, applied_at INTEGER NOT NULL)`); err != nil {
+		t.Fatalf("failed to create schema_version: %v", err)
+	}
+	for _, m := range migrations[:version] {
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("failed to begin fixture tx: %v", err)
+		}
+		if err := m.up(ctx, tx); err != nil {
+			t.Fatalf("fixture migration %d failed: %v", m.version, err)
+		}
+		if _, err := tx.Exec("INSERT INTO schema_version (version, name, applied_at) VALUES (?, ?, ?)", m.version, m.name, time.Now().UnixMilli()); err != nil {
+			t.Fatalf("failed to record fixture version: %v", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("failed to commit fixture tx: %v", err)
+		}
+	}
+
+	if _, err := db.Exec(`INSERT INTO records (id, timestamp, level, message, service, fields, raw)
+		VALUES ('legacy-1', 1000, 'ERROR', 'boom', 'api', '{"k":"v"}', 'raw line')`); err != nil {
+		t.Fatalf("failed to insert fixture record: %v", err)
+	}
+}
+
+func TestMigrations_FromEveryVersion(t *testing.T) {
+	for version := 0; version <= latestSchemaVersion(); version++ {
+		t.Run(fmt.Sprintf("v%d", version), func(t *testing.T) {
+			dir := t.TempDir()
+			dbPath := filepath.Join(dir, "fixture.db")
+			buildFixture(t, dbPath, version)
+
+			storage, err := NewSQLiteStorage(dbPath)
+			if err != nil {
+				t.Fatalf("failed to open fixture at v%d: %v", version, err)
+			}
+			defer storage.Close()
+
+			got, err := storage.SchemaVersion(context.Background())
+			if err != nil {
+				t.Fatalf("SchemaVersion failed: %v", err)
+			}
+			if got != latestSchemaVersion() {
+				t.Errorf("expected schema version %d, got %d", latestSchemaVersion(), got)
+			}
+
+			record, err := storage.GetRecord(context.Background(), "legacy-1")
+			if err != nil {
+				t.Fatalf("GetRecord failed after migration: %v", err)
+			}
+			if record.Message != "boom" || record.Fields["k"] != "v" {
+				t.Errorf("record not preserved: %+v", record)
+			}
+
+			storeRecords(t, storage, newRecords(1))
+
+			backups, _ := filepath.Glob(dbPath + ".v*.bak")
+			migrated := version != latestSchemaVersion()
+			if migrated && len(backups) != 1 {
+				t.Errorf("expected one pre-migration backup, got %v", backups)
+			}
+			if !migrated && len(backups) != 0 {
+				t.Errorf("expected no backup for an up-to-date database, got %v", backups)
+			}
+		})
+	}
+}
+
+func TestMigrations_FreshDatabase(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "fresh.db")
+
+	storage, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	defer storage.Close()
+
+	version, err := storage.SchemaVersion(context.Background())
+	if err != nil {
+		t.Fatalf("SchemaVersion failed: %v", err)
+	}
+	if version != latestSchemaVersion() {
+		t.Errorf("expected version %d, got %d", latestSchemaVersion(), version)
+	}
+	if backups, _ := filepath.Glob(dbPath + ".v*.bak"); len(backups) != 0 {
+		t.Errorf("fresh database should not be backed up, got %v", backups)
+	}
+}
+
+func TestMigrations_Idempotent(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "reopen.db")
+
+	for i := 0; i < 2; i++ {
+		storage, err := NewSQLiteStorage(dbPath)
+		if err != nil {
+			t.Fatalf("open %d failed: %v", i, err)
+		}
+		storage.Close()
+	}
+}
+
+func TestMigrations_NewerSchemaFails(t *testing.T) {
+	dir := t.TempDir()
+	dbPath := filepath.Join(dir, "future.db")
+
+	storage, err := NewSQLiteStorage(dbPath)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage failed: %v", err)
+	}
+	if _, err := storage.db.Exec("INSERT INTO schema_version (version, name, applied_at) VALUES (?, 'from the future', 0)", latestSchemaVersion()+1); err != nil {
+		t.Fatalf("failed to bump schema version: %v", err)
+	}
+	storage.Close()
+
+	_, err = NewSQLiteStorage(dbPath)
+	if err == nil {
+		t.Fatal("expected error opening a newer schema")
+	}
+	if !errors.Is(err, ErrSchemaTooNew) {
+		t.Errorf("expected ErrSchemaTooNew, got %v", err)
+	}
+}
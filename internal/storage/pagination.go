@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+
+	"LogLens/internal/domain"
+)
+
+// cappedCountLimit caps the COUNT(*) run for CountCapped; past it the total
+// is reported as a lower bound.
+const cappedCountLimit = 10000
+
+type pageSort struct {
+	col  string
+	desc bool
+}
+
+// pageCursor is the decoded form of QueryResult.NextCursor/PrevCursor. It
+// pins the (sort key, id) of the row a page starts after, which keeps pages
+// stable when many rows share the same sort key.
+type pageCursor struct {
+	Sort string      `json:"s"`
+	Desc bool        `json:"d"`
+	Key  interface{} `json:"k"`
+	ID   string      `json:"i"`
+	Back bool        `json:"b,omitempty"`
+}
+
+func (s *SQLiteStorage) resolveSort(query domain.Query) (pageSort, error) {
+	if query.SortBy == "" {
+		return pageSort{col: "timestamp", desc: true}, nil
+	}
+	col, ok := s.allowedColumn(query.SortBy)
+	if !ok {
+		return pageSort{}, fmt.Errorf("invalid sort field: %s", query.SortBy)
+	}
+	return pageSort{col: col, desc: query.SortDesc}, nil
+}
+
+func encodeCursor(sort pageSort, record domain.LogRecord, back bool) string {
+	c := pageCursor{Sort: sort.col, Desc: sort.desc, Key: sortKey(record, sort.col), ID: record.ID, Back: back}
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(token string, sort pageSort) (*pageCursor, error) {
+	if token == "" {
+		return nil, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+
+	var c pageCursor
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("malformed cursor")
+	}
+	if c.Sort != sort.col || c.Desc != sort.desc {
+		return nil, fmt.Errorf("cursor does not match the query sort order")
+	}
+
+	if n, ok := c.Key.(json.Number); ok {
+		v, err := n.Int64()
+		if err != nil {
+			return nil, fmt.Errorf("malformed cursor")
+		}
+		c.Key = v
+	}
+	return &c, nil
+}
+
+func sortKey(record domain.LogRecord, col string) interface{} {
+	switch col {
+	case "timestamp":
+		return record.Timestamp
+	case "line":
+		return record.Line
+	case "level":
+		return record.Level
+	case "message":
+		return record.Message
+	case "service":
+		return record.Service
+	case "raw":
+		return record.Raw
+	case "source":
+		return record.Source
+	default:
+		return record.ID
+	}
+}
+
+// keysetClause restricts a page to rows strictly after the cursor in the
+// scan direction. Ties on the sort column are broken by id.
+func keysetClause(col string, desc bool, c *pageCursor) (string, []interface{}) {
+	op := ">"
+	if desc {
+		op = "<"
+	}
+	if col == "id" {
+		return "id " + op + " ?", []interface{}{c.ID}
+	}
+	return fmt.Sprintf("(%s, id) %s (?, ?)", columnExpr(col), op), []interface{}{c.Key, c.ID}
+}
+
+func orderClause(col string, desc bool) string {
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	if col == "id" {
+		return " ORDER BY id " + direction
+	}
+	return fmt.Sprintf(" ORDER BY %s %s, id %s", columnExpr(col), direction, direction)
+}
+
+// countMatching fills the total fields of result for query. The first page
+// is always counted exactly.
+func (s *SQLiteStorage) countMatching(ctx context.Context, q queryer, query domain.Query, result *domain.QueryResult) error {
+	subsequent := query.Cursor != "" || query.Offset > 0
+	mode := query.CountMode
+	if !subsequent {
+		mode = domain.CountExact
+	}
+
+	switch mode {
+	case domain.CountSkip:
+		result.Total, result.TotalUnknown = 0, true
+		return nil
+	case domain.CountCapped:
+		where, args, err := s.buildWhere(query.Filters)
+		if err != nil {
+			return err
+		}
+		countQuery := fmt.Sprintf("SELECT COUNT(*) FROM (SELECT 1 FROM %s%s LIMIT %d)", s.recordsFrom(query.Filters), where, cappedCountLimit)
+		if err := q.QueryRowContext(ctx, countQuery, args...).Scan(&result.Total); err != nil {
+			return err
+		}
+		result.TotalLowerBound = result.Total >= cappedCountLimit
+		return nil
+	default:
+		total, err := s.getTotalCount(ctx, q, query)
+		result.Total = total
+		return err
+	}
+}
@@ -0,0 +1,170 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"LogLens/internal/domain"
+)
+
+func tiedRecords(n int) []domain.LogRecord {
+	records := make([]domain.LogRecord, n)
+	for i := range records {
+		records[i] = domain.LogRecord{
+			ID:        fmt.Sprintf("tie_%03d", i),
+			Timestamp: int64(i/10) * 1000,
+			Level:     "INFO",
+			Message:   fmt.Sprintf("message %d", i),
+			Fields:    make(map[string]interface{}),
+			Raw:       "raw",
+		}
+	}
+	return records
+}
+
+func TestSQLiteStorage_CursorPagination(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	storeRecords(t, storage, tiedRecords(95))
+
+	ctx := context.Background()
+	seen := make(map[string]bool)
+	var pages []*domain.QueryResult
+	query := domain.Query{Limit: 20}
+	for {
+		result, err := storage.Query(ctx, query)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		for _, r := range result.Records {
+			if seen[r.ID] {
+				t.Fatalf("record %s returned twice", r.ID)
+			}
+			seen[r.ID] = true
+		}
+		pages = append(pages, result)
+		if result.NextCursor == "" {
+			break
+		}
+		query.Cursor = result.NextCursor
+	}
+
+	if len(seen) != 95 {
+		t.Errorf("expected 95 distinct records across pages, got %d", len(seen))
+	}
+	if len(pages) != 5 {
+		t.Fatalf("expected 5 pages, got %d", len(pages))
+	}
+	if pages[0].PrevCursor != "" {
+		t.Error("first page should not have a previous cursor")
+	}
+
+	back, err := storage.Query(ctx, domain.Query{Limit: 20, Cursor: pages[2].PrevCursor})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if len(back.Records) != len(pages[1].Records) {
+		t.Fatalf("expected %d records paging back, got %d", len(pages[1].Records), len(back.Records))
+	}
+	for i := range back.Records {
+		if back.Records[i].ID != pages[1].Records[i].ID {
+			t.Errorf("page back mismatch at %d: %s != %s", i, back.Records[i].ID, pages[1].Records[i].ID)
+		}
+	}
+	if back.NextCursor == "" || back.PrevCursor == "" {
+		t.Error("middle page should have both cursors")
+	}
+}
+
+func TestSQLiteStorage_CursorWithSortAndFilter(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	records := tiedRecords(40)
+	for i := range records {
+		if i%2 == 0 {
+			records[i].Level = "ERROR"
+		}
+	}
+	storeRecords(t, storage, records)
+
+	query := domain.Query{
+		Filters: []domain.FilterCondition{{Type: domain.FilterEquality, Field: "level", Value: "ERROR"}},
+		SortBy:  "message",
+		Limit:   7,
+	}
+	var got []string
+	for {
+		result, err := storage.Query(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		for _, r := range result.Records {
+			got = append(got, r.Message)
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		query.Cursor = result.NextCursor
+	}
+
+	if len(got) != 20 {
+		t.Fatalf("expected 20 ERROR records, got %d", len(got))
+	}
+	for i := 1; i < len(got); i++ {
+		if got[i-1] > got[i] {
+			t.Fatalf("records out of order: %q before %q", got[i-1], got[i])
+		}
+	}
+}
+
+func TestSQLiteStorage_CursorSortMismatch(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	storeRecords(t, storage, newRecords(10))
+
+	result, err := storage.Query(context.Background(), domain.Query{Limit: 5})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if _, err := storage.Query(context.Background(), domain.Query{Limit: 5, SortBy: "level", Cursor: result.NextCursor}); err == nil {
+		t.Error("expected error reusing a cursor with a different sort")
+	}
+	if _, err := storage.Query(context.Background(), domain.Query{Limit: 5, Cursor: "not-a-cursor"}); err == nil {
+		t.Error("expected error for malformed cursor")
+	}
+}
+
+func TestSQLiteStorage_CountModes(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	storeRecords(t, storage, newRecords(30))
+
+	ctx := context.Background()
+	first, err := storage.Query(ctx, domain.Query{Limit: 10, CountMode: domain.CountSkip})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if first.Total != 30 {
+		t.Errorf("first page should always be counted, got %d", first.Total)
+	}
+
+	skipped, err := storage.Query(ctx, domain.Query{Limit: 10, Cursor: first.NextCursor, CountMode: domain.CountSkip})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if !skipped.TotalUnknown || skipped.Total != 0 {
+		t.Errorf("expected an unknown total, got %d unknown=%v", skipped.Total, skipped.TotalUnknown)
+	}
+	if first.TotalUnknown || first.TotalLowerBound {
+		t.Errorf("expected an exact first-page total, got %+v", first)
+	}
+
+	capped, err := storage.Query(ctx, domain.Query{Limit: 10, Cursor: first.NextCursor, CountMode: domain.CountCapped})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if capped.Total != 30 || capped.TotalLowerBound {
+		t.Errorf("expected exact total below the cap, got %d lowerBound=%v", capped.Total, capped.TotalLowerBound)
+	}
+}
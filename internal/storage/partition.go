@@ -0,0 +1,507 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"LogLens/internal/domain"
+)
+
+// With partitioning enabled every record lives in a records_p<period> table
+// cloned from the records schema, and the records table itself stays empty.
+// A partition's primary key only covers its own rows, so partition_ids maps
+// every partitioned record ID to the partition holding it; inserts claim IDs
+// there and deletes release them. Dropping a partition leaves its entries
+// behind: claims treat entries of unregistered partitions as free, and
+// Compact removes them.
+
+var partitionIndexColumns = []string{"timestamp", "level", "service", "source", "source, line"}
+
+func partitionIndexName(table, columns string) string {
+	return "idx_" + table + "_" + strings.ReplaceAll(columns, ", ", "_")
+}
+
+type partition struct {
+	name  string
+	start int64
+	end   int64
+}
+
+type partitionSet struct {
+	mu          sync.RWMutex
+	granularity domain.PartitionGranularity
+	parts       []partition
+	// inUse is held for reading by every statement planned over the
+	// partitions, from planning until it finishes, and for writing while
+	// partitions are dropped.
+	inUse sync.RWMutex
+}
+
+// usePartitions keeps the partitions from being dropped until the returned
+// func is called. Calls must not nest.
+func (s *SQLiteStorage) usePartitions() func() {
+	s.parts.inUse.RLock()
+	return s.parts.inUse.RUnlock
+}
+
+func (s *SQLiteStorage) loadPartitions(ctx context.Context) error {
+	s.parts = &partitionSet{granularity: domain.PartitionNone}
+
+	var value string
+	err := s.db.QueryRowContext(ctx, "SELECT value FROM storage_settings WHERE key = 'partitioning'").Scan(&value)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("failed to read partitioning setting: %w", err)
+	}
+	if value != "" {
+		s.parts.granularity = domain.PartitionGranularity(value)
+	}
+
+	rows, err := s.db.QueryContext(ctx, "SELECT name, start_ms, end_ms FROM partitions ORDER BY start_ms")
+	if err != nil {
+		return fmt.Errorf("failed to load partitions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var p partition
+		if err := rows.Scan(&p.name, &p.start, &p.end); err != nil {
+			return fmt.Errorf("failed to load partitions: %w", err)
+		}
+		s.parts.parts = append(s.parts.parts, p)
+	}
+	return rows.Err()
+}
+
+func partitionWidth(g domain.PartitionGranularity) int64 {
+	switch g {
+	case domain.PartitionDay:
+		return 24 * 60 * 60 * 1000
+	case domain.PartitionHour:
+		return 60 * 60 * 1000
+	default:
+		return 0
+	}
+}
+
+func partitionFor(g domain.PartitionGranularity, ts int64) partition {
+	width := partitionWidth(g)
+	start := ts / width * width
+	if ts < 0 && ts%width != 0 {
+		start -= width
+	}
+	layout := "20060102"
+	if g == domain.PartitionHour {
+		layout = "2006010215"
+	}
+	return partition{
+		name:  "records_p" + time.UnixMilli(start).UTC().Format(layout),
+		start: start,
+		end:   start + width,
+	}
+}
+
+// tableFor returns the table a record with timestamp ts is written to.
+func (s *SQLiteStorage) tableFor(ts int64) string {
+	s.parts.mu.RLock()
+	g := s.parts.granularity
+	s.parts.mu.RUnlock()
+	if partitionWidth(g) == 0 {
+		return "records"
+	}
+	return partitionFor(g, ts).name
+}
+
+// ensurePartitions creates any partition tables batch needs. It must run
+// outside a write transaction because it opens its own.
+func (s *SQLiteStorage) ensurePartitions(ctx context.Context, batch []domain.LogRecord) error {
+	s.parts.mu.RLock()
+	g := s.parts.granularity
+	known := make(map[string]bool, len(s.parts.parts))
+	for _, p := range s.parts.parts {
+		known[p.name] = true
+	}
+	s.parts.mu.RUnlock()
+	if partitionWidth(g) == 0 {
+		return nil
+	}
+
+	var missing []partition
+	for _, record := range batch {
+		p := partitionFor(g, record.Timestamp)
+		if !known[p.name] {
+			known[p.name] = true
+			missing = append(missing, p)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var schema string
+	if err := tx.QueryRowContext(ctx, "SELECT sql FROM sqlite_master WHERE type = 'table' AND name = 'records'").Scan(&schema); err != nil {
+		return fmt.Errorf("failed to read records schema: %w", err)
+	}
+	if !strings.HasPrefix(schema, "CREATE TABLE records") {
+		return fmt.Errorf("unexpected records schema: %s", schema)
+	}
+
+	for _, p := range missing {
+		ddl := "CREATE TABLE IF NOT EXISTS " + p.name + strings.TrimPrefix(schema, "CREATE TABLE records")
+		if _, err := tx.ExecContext(ctx, ddl); err != nil {
+			return fmt.Errorf("failed to create partition %s: %w", p.name, err)
+		}
+		for _, col := range partitionIndexColumns {
+			if _, err := tx.ExecContext(ctx, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %s ON %s(%s)", partitionIndexName(p.name, col), p.name, col)); err != nil {
+				return fmt.Errorf("failed to index partition %s: %w", p.name, err)
+			}
+		}
+		if err := s.indexNewPartition(ctx, tx, p.name); err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO partitions (name, start_ms, end_ms) VALUES (?, ?, ?)", p.name, p.start, p.end); err != nil {
+			return fmt.Errorf("failed to register partition %s: %w", p.name, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit partitions: %w", err)
+	}
+
+	s.parts.mu.Lock()
+	registered := make(map[string]bool, len(s.parts.parts))
+	for _, p := range s.parts.parts {
+		registered[p.name] = true
+	}
+	for _, p := range missing {
+		// A concurrent call may have registered it since known was built.
+		if !registered[p.name] {
+			s.parts.parts = append(s.parts.parts, p)
+		}
+	}
+	sort.Slice(s.parts.parts, func(i, j int) bool { return s.parts.parts[i].start < s.parts.parts[j].start })
+	s.parts.mu.Unlock()
+	return nil
+}
+
+// partitionIDs claims record IDs in partition_ids within one write
+// transaction.
+type partitionIDs struct {
+	tx    *sql.Tx
+	claim *sql.Stmt
+	owner *sql.Stmt
+}
+
+func newPartitionIDs(ctx context.Context, tx *sql.Tx) (*partitionIDs, error) {
+	claim, err := tx.PrepareContext(ctx, `INSERT INTO partition_ids (id, part) VALUES (?, ?)
+		ON CONFLICT(id) DO UPDATE SET part = excluded.part WHERE partition_ids.part NOT IN (SELECT name FROM partitions)`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare id claim: %w", err)
+	}
+	owner, err := tx.PrepareContext(ctx, "SELECT part FROM partition_ids WHERE id = ?")
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare id lookup: %w", err)
+	}
+	return &partitionIDs{tx: tx, claim: claim, owner: owner}, nil
+}
+
+// Claim registers id as stored in table. If id is already taken by a
+// registered partition it returns that partition and claims nothing.
+func (p *partitionIDs) Claim(ctx context.Context, id, table string) (string, error) {
+	res, err := p.claim.ExecContext(ctx, id, table)
+	if err != nil {
+		return "", fmt.Errorf("failed to claim record id %s: %w", id, err)
+	}
+	if n, _ := res.RowsAffected(); n > 0 {
+		return "", nil
+	}
+	var owner string
+	if err := p.owner.QueryRowContext(ctx, id).Scan(&owner); err != nil {
+		return "", fmt.Errorf("failed to look up record id %s: %w", id, err)
+	}
+	return owner, nil
+}
+
+// Move deletes the row holding id in from and hands id to table, ahead of a
+// replacement being written there.
+func (p *partitionIDs) Move(ctx context.Context, id, from, table string) error {
+	if from == table {
+		return nil
+	}
+	if _, err := p.tx.ExecContext(ctx, "DELETE FROM "+from+" WHERE id = ?", id); err != nil {
+		return fmt.Errorf("failed to move record %s: %w", id, err)
+	}
+	if _, err := p.tx.ExecContext(ctx, "UPDATE partition_ids SET part = ? WHERE id = ?", table, id); err != nil {
+		return fmt.Errorf("failed to move record %s: %w", id, err)
+	}
+	return nil
+}
+
+// releaseIDs drops the partition_ids entries of the rows in table matching
+// where, before they are deleted. It is a no-op for the records table.
+func releaseIDs(ctx context.Context, tx *sql.Tx, table, where string, args ...interface{}) error {
+	if table == "records" {
+		return nil
+	}
+	q := "DELETE FROM partition_ids WHERE part = ? AND id IN (SELECT id FROM " + table + where + ")"
+	if _, err := tx.ExecContext(ctx, q, append([]interface{}{table}, args...)...); err != nil {
+		return fmt.Errorf("failed to release record ids of %s: %w", table, err)
+	}
+	return nil
+}
+
+// recordTables lists the physical tables a read over filters has to visit,
+// skipping partitions outside the filters' timestamp bounds.
+func (s *SQLiteStorage) recordTables(filters []domain.FilterCondition) []string {
+	s.parts.mu.RLock()
+	defer s.parts.mu.RUnlock()
+
+	if partitionWidth(s.parts.granularity) == 0 || len(s.parts.parts) == 0 {
+		return []string{"records"}
+	}
+
+	lo, hi := timestampBounds(filters)
+	var tables []string
+	for _, p := range s.parts.parts {
+		if p.start <= hi && p.end > lo {
+			tables = append(tables, p.name)
+		}
+	}
+	if len(tables) == 0 {
+		return []string{"records"}
+	}
+	return tables
+}
+
+// maxUnionTerms keeps each UNION ALL below SQLite's default limit of 500
+// terms per compound SELECT.
+const maxUnionTerms = 400
+
+// recordsFrom returns a FROM target covering every record that can match
+// filters: a single table, or a UNION ALL over the surviving partitions,
+// nested in groups of at most maxUnionTerms.
+func (s *SQLiteStorage) recordsFrom(filters []domain.FilterCondition) string {
+	tables := s.recordTables(filters)
+	if len(tables) == 1 {
+		return tables[0]
+	}
+	return unionAll(tables)
+}
+
+func unionAll(sources []string) string {
+	if len(sources) > maxUnionTerms {
+		groups := make([]string, 0, (len(sources)+maxUnionTerms-1)/maxUnionTerms)
+		for start := 0; start < len(sources); start += maxUnionTerms {
+			end := min(start+maxUnionTerms, len(sources))
+			groups = append(groups, unionAll(sources[start:end]))
+		}
+		return unionAll(groups)
+	}
+	selects := make([]string, len(sources))
+	for i, source := range sources {
+		selects[i] = "SELECT * FROM " + source
+	}
+	return "(" + strings.Join(selects, " UNION ALL ") + ")"
+}
+
+// timestampBounds derives an inclusive [lo, hi] timestamp range from the
+// top-level equality and range filters, including those nested in AND groups.
+// OR and NOT branches are ignored since they cannot narrow the range.
+// Timestamps are integers, so fractional bounds round inwards.
+func timestampBounds(filters []domain.FilterCondition) (int64, int64) {
+	lo, hi := int64(math.MinInt64), int64(math.MaxInt64)
+	for _, f := range filters {
+		if f.Type == domain.FilterAnd {
+			childLo, childHi := timestampBounds(f.Children)
+			lo, hi = max(lo, childLo), min(hi, childHi)
+			continue
+		}
+		if !strings.EqualFold(f.Field, "timestamp") {
+			continue
+		}
+		v, ok := toFloat(f.Value)
+		if !ok {
+			continue
+		}
+		floor, ceil := int64(math.Floor(v)), int64(math.Ceil(v))
+		switch f.Type {
+		case domain.FilterEquality:
+			lo, hi = max(lo, ceil), min(hi, floor)
+		case domain.FilterRange:
+			switch f.Operator {
+			case "gt":
+				lo = max(lo, floor+1)
+			case "gte":
+				lo = max(lo, ceil)
+			case "lt":
+				hi = min(hi, ceil-1)
+			case "lte":
+				hi = min(hi, floor)
+			}
+		}
+	}
+	return lo, hi
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case float64:
+		return n, true
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	default:
+		return 0, false
+	}
+}
+
+func (s *SQLiteStorage) Partitioning(ctx context.Context) (domain.PartitionGranularity, error) {
+	s.parts.mu.RLock()
+	defer s.parts.mu.RUnlock()
+	return s.parts.granularity, nil
+}
+
+// SetPartitioning switches the partitioning scheme. Existing records are not
+// moved, so the scheme can only change while the database is empty.
+func (s *SQLiteStorage) SetPartitioning(ctx context.Context, g domain.PartitionGranularity) error {
+	if g == "" {
+		g = domain.PartitionNone
+	}
+	if g != domain.PartitionNone && partitionWidth(g) == 0 {
+		return fmt.Errorf("unsupported partitioning: %s", g)
+	}
+
+	s.parts.mu.Lock()
+	defer s.parts.mu.Unlock()
+	if g == s.parts.granularity {
+		return nil
+	}
+
+	var count int64
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM records").Scan(&count); err != nil {
+		return fmt.Errorf("failed to count records: %w", err)
+	}
+	if count > 0 || len(s.parts.parts) > 0 {
+		return fmt.Errorf("partitioning can only be changed on an empty database")
+	}
+
+	if _, err := s.db.ExecContext(ctx,
+		"INSERT INTO storage_settings (key, value) VALUES ('partitioning', ?) ON CONFLICT(key) DO UPDATE SET value = excluded.value",
+		string(g),
+	); err != nil {
+		return fmt.Errorf("failed to store partitioning setting: %w", err)
+	}
+	s.parts.granularity = g
+	return nil
+}
+
+func (s *SQLiteStorage) ListPartitions(ctx context.Context) ([]domain.PartitionInfo, error) {
+	release := s.usePartitions()
+	defer release()
+
+	s.parts.mu.RLock()
+	parts := append([]partition(nil), s.parts.parts...)
+	s.parts.mu.RUnlock()
+
+	infos := make([]domain.PartitionInfo, 0, len(parts))
+	for _, p := range parts {
+		info := domain.PartitionInfo{Name: p.name, Start: p.start, End: p.end}
+		if err := s.rdb.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+p.name).Scan(&info.Rows); err != nil {
+			return nil, fmt.Errorf("failed to count partition %s: %w", p.name, err)
+		}
+		infos = append(infos, info)
+	}
+	return infos, nil
+}
+
+// DropPartitionsBefore drops every partition that ends at or before beforeMs.
+// Unlike a row-level delete it never reads the rows: the tables and their
+// compressed blocks are dropped as a whole. The field catalog still counts
+// the dropped records until the next Compact rebuilds it. It waits for the
+// reads and writes already planned over the partitions.
+func (s *SQLiteStorage) DropPartitionsBefore(ctx context.Context, beforeMs int64, dryRun bool) (*domain.DeleteResult, error) {
+	startTime := time.Now()
+	result := &domain.DeleteResult{DryRun: dryRun}
+
+	s.parts.inUse.Lock()
+	defer s.parts.inUse.Unlock()
+
+	s.parts.mu.RLock()
+	var expired []partition
+	for _, p := range s.parts.parts {
+		if p.end <= beforeMs {
+			expired = append(expired, p)
+		}
+	}
+	s.parts.mu.RUnlock()
+
+	for _, p := range expired {
+		var rows int64
+		if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+p.name).Scan(&rows); err != nil {
+			return nil, fmt.Errorf("failed to count partition %s: %w", p.name, err)
+		}
+		result.Matched += rows
+	}
+
+	if !dryRun && len(expired) > 0 {
+		if err := s.dropPartitions(ctx, expired); err != nil {
+			return nil, err
+		}
+		result.Deleted = result.Matched
+	}
+
+	result.Took = time.Since(startTime).Milliseconds()
+	return result, nil
+}
+
+func (s *SQLiteStorage) dropPartitions(ctx context.Context, expired []partition) error {
+	s.parts.mu.Lock()
+	defer s.parts.mu.Unlock()
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	dropped := make(map[string]bool, len(expired))
+	for _, p := range expired {
+		if _, err := tx.ExecContext(ctx, "DELETE FROM record_blocks WHERE tbl = ?", p.name); err != nil {
+			return fmt.Errorf("failed to delete record blocks of %s: %w", p.name, err)
+		}
+		if _, err := tx.ExecContext(ctx, "DROP TABLE IF EXISTS "+p.name); err != nil {
+			return fmt.Errorf("failed to drop partition %s: %w", p.name, err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM partitions WHERE name = ?", p.name); err != nil {
+			return fmt.Errorf("failed to unregister partition %s: %w", p.name, err)
+		}
+		dropped[p.name] = true
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit partition drop: %w", err)
+	}
+	s.compression.invalidateStats()
+
+	kept := s.parts.parts[:0]
+	for _, p := range s.parts.parts {
+		if !dropped[p.name] {
+			kept = append(kept, p)
+		}
+	}
+	s.parts.parts = kept
+	return nil
+}
@@ -0,0 +1,473 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"LogLens/internal/domain"
+)
+
+const dayMs = 24 * 60 * 60 * 1000
+
+func newPartitionedStorage(t *testing.T) (*SQLiteStorage, func()) {
+	t.Helper()
+	storage, cleanup := newTestStorage(t)
+	if err := storage.SetPartitioning(context.Background(), domain.PartitionDay); err != nil {
+		cleanup()
+		t.Fatalf("SetPartitioning failed: %v", err)
+	}
+	return storage, cleanup
+}
+
+// threeDays returns perDay records for each of three consecutive days
+// starting at base.
+func threeDays(base int64, perDay int) []domain.LogRecord {
+	var records []domain.LogRecord
+	for day := 0; day < 3; day++ {
+		for i := 0; i < perDay; i++ {
+			level := "INFO"
+			if i%4 == 0 {
+				level = "ERROR"
+			}
+			records = append(records, domain.LogRecord{
+				ID:        fmt.Sprintf("d%d_%d", day, i),
+				Timestamp: base + int64(day)*dayMs + int64(i)*1000,
+				Level:     level,
+				Message:   fmt.Sprintf("day %d message %d", day, i),
+				Fields:    make(map[string]interface{}),
+				Raw:       "raw",
+			})
+		}
+	}
+	return records
+}
+
+func TestPartitionedStorage_QueryPrunesAndFansOut(t *testing.T) {
+	for _, opts := range []domain.StoreOptions{{}, {Bulk: true}} {
+		t.Run(fmt.Sprintf("bulk=%v", opts.Bulk), func(t *testing.T) {
+			storage, cleanup := newPartitionedStorage(t)
+			defer cleanup()
+
+			base := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC).UnixMilli()
+			storeWithOptions(t, storage, threeDays(base, 100), opts)
+
+			parts, err := storage.ListPartitions(context.Background())
+			if err != nil {
+				t.Fatalf("ListPartitions failed: %v", err)
+			}
+			if len(parts) != 3 || parts[0].Name != "records_p20240115" || parts[0].Rows != 100 {
+				t.Fatalf("unexpected partitions: %+v", parts)
+			}
+
+			dayTwo := []domain.FilterCondition{
+				{Type: domain.FilterRange, Field: "timestamp", Operator: "gte", Value: float64(base + dayMs)},
+				{Type: domain.FilterRange, Field: "timestamp", Operator: "lt", Value: float64(base + 2*dayMs)},
+			}
+			if tables := storage.recordTables(dayTwo); len(tables) != 1 || tables[0] != "records_p20240116" {
+				t.Errorf("expected pruning to a single partition, got %v", tables)
+			}
+
+			ctx := context.Background()
+			result, err := storage.Query(ctx, domain.Query{Filters: dayTwo, Limit: 10})
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if result.Total != 100 {
+				t.Errorf("expected 100 records on day two, got %d", result.Total)
+			}
+
+			all, err := storage.Query(ctx, domain.Query{
+				Filters: []domain.FilterCondition{{Type: domain.FilterEquality, Field: "level", Value: "ERROR"}},
+				Limit:   1000,
+			})
+			if err != nil {
+				t.Fatalf("Query failed: %v", err)
+			}
+			if all.Total != 75 || len(all.Records) != 75 {
+				t.Errorf("expected 75 ERROR records across partitions, got total=%d len=%d", all.Total, len(all.Records))
+			}
+			for i := 1; i < len(all.Records); i++ {
+				if all.Records[i-1].Timestamp < all.Records[i].Timestamp {
+					t.Fatal("fan-out results not ordered by timestamp")
+				}
+			}
+
+			points, err := storage.Timeline(ctx, nil, dayMs)
+			if err != nil {
+				t.Fatalf("Timeline failed: %v", err)
+			}
+			if len(points) != 3 || points[1].Count != 100 {
+				t.Errorf("unexpected timeline: %+v", points)
+			}
+
+			aggs, err := storage.Aggregate(ctx, dayTwo, []domain.Aggregation{{Function: "count"}})
+			if err != nil {
+				t.Fatalf("Aggregate failed: %v", err)
+			}
+			if aggs["count"] != int64(100) {
+				t.Errorf("expected count 100, got %v", aggs["count"])
+			}
+
+			if _, err := storage.GetRecord(ctx, "d2_7"); err != nil {
+				t.Errorf("GetRecord across partitions failed: %v", err)
+			}
+		})
+	}
+}
+
+func TestPartitionedStorage_CursorAcrossPartitions(t *testing.T) {
+	storage, cleanup := newPartitionedStorage(t)
+	defer cleanup()
+
+	base := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC).UnixMilli()
+	storeRecords(t, storage, threeDays(base, 30))
+
+	seen := make(map[string]bool)
+	query := domain.Query{Limit: 17}
+	for {
+		result, err := storage.Query(context.Background(), query)
+		if err != nil {
+			t.Fatalf("Query failed: %v", err)
+		}
+		for _, r := range result.Records {
+			seen[r.ID] = true
+		}
+		if result.NextCursor == "" {
+			break
+		}
+		query.Cursor = result.NextCursor
+	}
+	if len(seen) != 90 {
+		t.Errorf("expected 90 records across pages, got %d", len(seen))
+	}
+}
+
+func TestPartitionedStorage_DropAndRetention(t *testing.T) {
+	storage, cleanup := newPartitionedStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	today := time.Now().UTC().Truncate(24 * time.Hour).UnixMilli()
+	storeRecords(t, storage, threeDays(today-2*dayMs, 10))
+
+	dry, err := storage.DropPartitionsBefore(ctx, today-dayMs, true)
+	if err != nil {
+		t.Fatalf("DropPartitionsBefore failed: %v", err)
+	}
+	if dry.Matched != 10 || dry.Deleted != 0 {
+		t.Errorf("expected dry run to match 10 and delete none, got %+v", dry)
+	}
+
+	dropped, err := storage.DropPartitionsBefore(ctx, today-dayMs, false)
+	if err != nil {
+		t.Fatalf("DropPartitionsBefore failed: %v", err)
+	}
+	if dropped.Deleted != 10 {
+		t.Errorf("expected 10 records dropped, got %d", dropped.Deleted)
+	}
+	if count, _ := storage.GetTotalCount(ctx); count != 20 {
+		t.Errorf("expected 20 records left, got %d", count)
+	}
+
+	if err := storage.SetRetentionPolicies(ctx, []domain.RetentionPolicy{{MaxAgeMs: 36 * 60 * 60 * 1000}}); err != nil {
+		t.Fatalf("SetRetentionPolicies failed: %v", err)
+	}
+	preview, err := storage.ApplyRetention(ctx, true)
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	applied, err := storage.ApplyRetention(ctx, false)
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if preview.Matched != applied.Deleted {
+		t.Errorf("dry run matched %d but retention deleted %d", preview.Matched, applied.Deleted)
+	}
+
+	parts, err := storage.ListPartitions(ctx)
+	if err != nil {
+		t.Fatalf("ListPartitions failed: %v", err)
+	}
+	for _, p := range parts {
+		if p.End <= time.Now().UnixMilli()-36*60*60*1000 {
+			t.Errorf("expired partition %s was not dropped", p.Name)
+		}
+	}
+}
+
+func TestPartitionedStorage_SettingPersistsAndLocks(t *testing.T) {
+	storage, cleanup := newPartitionedStorage(t)
+	defer cleanup()
+
+	storeRecords(t, storage, newRecords(5))
+	if err := storage.SetPartitioning(context.Background(), domain.PartitionHour); err == nil {
+		t.Error("expected error changing partitioning on a non-empty database")
+	}
+	storage.Close()
+
+	reopened, err := NewSQLiteStorage(storage.dbPath)
+	if err != nil {
+		t.Fatalf("reopen failed: %v", err)
+	}
+	defer reopened.Close()
+
+	g, _ := reopened.Partitioning(context.Background())
+	if g != domain.PartitionDay {
+		t.Errorf("expected day partitioning after reopen, got %s", g)
+	}
+	if count, _ := reopened.GetTotalCount(context.Background()); count != 5 {
+		t.Errorf("expected 5 records after reopen, got %d", count)
+	}
+}
+
+func TestPartitionedStorage_MorePartitionsThanUnionTerms(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+	if err := storage.SetPartitioning(ctx, domain.PartitionHour); err != nil {
+		t.Fatalf("SetPartitioning failed: %v", err)
+	}
+
+	const hours = 600
+	hourMs := int64(60 * 60 * 1000)
+	base := time.Now().UTC().Truncate(time.Hour).UnixMilli() - hours*hourMs
+	records := make([]domain.LogRecord, hours)
+	for h := range records {
+		records[h] = domain.LogRecord{
+			ID:        fmt.Sprintf("h%d", h),
+			Timestamp: base + int64(h)*hourMs,
+			Level:     "INFO",
+			Message:   fmt.Sprintf("hour %d", h),
+			Source:    "a.log",
+			Line:      int64(h + 1),
+			Fields:    map[string]interface{}{"hour": float64(h)},
+			Raw:       "raw",
+		}
+	}
+	storeRecords(t, storage, records)
+	if tables := storage.recordTables(nil); len(tables) != hours {
+		t.Fatalf("expected %d partitions, got %d", hours, len(tables))
+	}
+
+	result, err := storage.Query(ctx, domain.Query{Limit: 250, SortDesc: true})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if result.Total != hours || result.Records[0].ID != "h599" {
+		t.Errorf("expected %d records newest first, got total %d first %s", hours, result.Total, result.Records[0].ID)
+	}
+	next, err := storage.Query(ctx, domain.Query{Limit: 250, SortDesc: true, Cursor: result.NextCursor})
+	if err != nil {
+		t.Fatalf("Query with cursor failed: %v", err)
+	}
+	if len(next.Records) != 250 || next.Records[0].ID != "h349" {
+		t.Errorf("expected the second page to continue at h349, got %d records", len(next.Records))
+	}
+	if count, err := storage.GetTotalCount(ctx); err != nil || count != hours {
+		t.Errorf("expected %d records, got %d: %v", hours, count, err)
+	}
+	if _, err := storage.GetRecord(ctx, "h3"); err != nil {
+		t.Errorf("GetRecord failed: %v", err)
+	}
+	if aggs, err := storage.Aggregate(ctx, nil, []domain.Aggregation{{Function: "count"}}); err != nil || aggs["count"] != int64(hours) {
+		t.Errorf("expected count %d, got %v: %v", hours, aggs, err)
+	}
+	if _, err := storage.Timeline(ctx, nil, 24*hourMs); err != nil {
+		t.Errorf("Timeline failed: %v", err)
+	}
+	if _, err := storage.Facets(ctx, domain.Query{}, []string{"level"}, 5); err != nil {
+		t.Errorf("Facets failed: %v", err)
+	}
+	if _, err := storage.GetContext(ctx, "h300", 2, 2); err != nil {
+		t.Errorf("GetContext failed: %v", err)
+	}
+
+	filters := []domain.FilterCondition{{Type: domain.FilterRange, Field: "hour", Operator: "lt", Value: float64(10)}}
+	if deleted, err := storage.DeleteByQuery(ctx, filters, false); err != nil || deleted.Deleted != 10 {
+		t.Errorf("expected 10 deleted, got %+v: %v", deleted, err)
+	}
+	if err := storage.SetRetentionPolicies(ctx, []domain.RetentionPolicy{{Source: "a.log", MaxRows: 500}}); err != nil {
+		t.Fatalf("SetRetentionPolicies failed: %v", err)
+	}
+	if applied, err := storage.ApplyRetention(ctx, false); err != nil || applied.Deleted != 90 {
+		t.Errorf("expected retention to delete 90, got %+v: %v", applied, err)
+	}
+}
+
+func TestPartitionedStorage_IDsUniqueAcrossPartitions(t *testing.T) {
+	for _, bulk := range []bool{false, true} {
+		t.Run(fmt.Sprintf("bulk=%v", bulk), func(t *testing.T) {
+			storage, cleanup := newPartitionedStorage(t)
+			defer cleanup()
+			ctx := context.Background()
+
+			base := time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC).UnixMilli()
+			record := func(ts int64, message string) domain.LogRecord {
+				return domain.LogRecord{ID: "x", Timestamp: ts, Level: "INFO", Message: message, Source: "a.log", Raw: "raw"}
+			}
+			store := func(r domain.LogRecord, dedup domain.DedupPolicy) *domain.ImportResult {
+				t.Helper()
+				return storeWithOptions(t, storage, []domain.LogRecord{r}, domain.StoreOptions{Bulk: bulk, Dedup: dedup})
+			}
+			check := func(want string) {
+				t.Helper()
+				if count, _ := storage.GetTotalCount(ctx); count != 1 {
+					t.Errorf("expected one record, got %d", count)
+				}
+				got, err := storage.GetRecord(ctx, "x")
+				if err != nil || got.Message != want {
+					t.Errorf("expected %q, got %+v: %v", want, got, err)
+				}
+			}
+
+			store(record(base, "day one"), domain.DedupReplace)
+			if result := store(record(base+dayMs, "skipped"), domain.DedupSkip); result.Duplicates != 1 {
+				t.Errorf("expected a skipped duplicate, got %+v", result)
+			}
+			check("day one")
+			if result := store(record(base+dayMs, "day two"), domain.DedupReplace); result.Duplicates != 1 {
+				t.Errorf("expected a replaced duplicate, got %+v", result)
+			}
+			check("day two")
+
+			if !bulk {
+				store(record(base+2*dayMs, "copy"), domain.DedupKeepBoth)
+				if copied, err := storage.GetRecord(ctx, "x~2"); err != nil || copied.Message != "copy" {
+					t.Errorf("expected a keep-both copy, got %+v: %v", copied, err)
+				}
+				if _, err := storage.DropPartitionsBefore(ctx, base+3*dayMs, false); err != nil {
+					t.Fatalf("DropPartitionsBefore failed: %v", err)
+				}
+			} else {
+				filters := []domain.FilterCondition{{Type: domain.FilterEquality, Field: "id", Value: "x"}}
+				if _, err := storage.DeleteByQuery(ctx, filters, false); err != nil {
+					t.Fatalf("DeleteByQuery failed: %v", err)
+				}
+			}
+
+			// Deleted IDs are released, so the record is new again.
+			if result := store(record(base, "again"), domain.DedupSkip); result.Duplicates != 0 {
+				t.Errorf("expected the deleted id to be free, got %+v", result)
+			}
+			check("again")
+		})
+	}
+}
+
+func TestTimestampBounds(t *testing.T) {
+	lo, hi := timestampBounds([]domain.FilterCondition{
+		{Type: domain.FilterRange, Field: "timestamp", Operator: "gte", Value: 1000.5},
+		{Type: domain.FilterRange, Field: "Timestamp", Operator: "lte", Value: int64(5000)},
+		{Type: domain.FilterEquality, Field: "level", Value: "ERROR"},
+	})
+	if lo != 1001 || hi != 5000 {
+		t.Errorf("expected [1001, 5000], got [%d, %d]", lo, hi)
+	}
+}
+
+func TestPartitionedStorage_ConcurrentBatchesRegisterPartitionOnce(t *testing.T) {
+	storage, cleanup := newPartitionedStorage(t)
+	defer cleanup()
+
+	batch := requestRecords(0, 1)
+	start := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			<-start
+			if err := storage.ensurePartitions(context.Background(), batch); err != nil {
+				t.Errorf("ensurePartitions failed: %v", err)
+			}
+		}()
+	}
+	close(start)
+	wg.Wait()
+
+	if tables := storage.recordTables(nil); len(tables) != 1 {
+		t.Errorf("expected one partition, got %v", tables)
+	}
+}
+
+func TestPartitionedStorage_DropLeavesCleanupToCompact(t *testing.T) {
+	storage, cleanup := newPartitionedStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	record := func(id string, day int64, field string) domain.LogRecord {
+		return domain.LogRecord{ID: id, Timestamp: day * dayMs, Level: "INFO", Message: "m", Source: "a.log", Raw: "r",
+			Fields: map[string]interface{}{field: "v"}}
+	}
+	storeRecords(t, storage, []domain.LogRecord{record("a", 0, "old"), record("b", 1, "new")})
+	if _, err := storage.DropPartitionsBefore(ctx, dayMs, false); err != nil {
+		t.Fatalf("DropPartitionsBefore failed: %v", err)
+	}
+
+	// The dropped partition's id entry is left behind but no longer taken.
+	result := storeWithOptions(t, storage, []domain.LogRecord{record("a", 1, "new")}, domain.StoreOptions{Bulk: true, Dedup: domain.DedupSkip})
+	if result.Duplicates != 0 {
+		t.Errorf("expected the dropped record's id to be free, got %+v", result)
+	}
+	if result := storeWithOptions(t, storage, []domain.LogRecord{record("a", 1, "new")}, domain.StoreOptions{Dedup: domain.DedupSkip}); result.Duplicates != 1 {
+		t.Errorf("expected the reclaimed id to be taken, got %+v", result)
+	}
+
+	if _, err := storage.Compact(ctx); err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	var ids int64
+	if err := storage.db.QueryRow("SELECT COUNT(*) FROM partition_ids").Scan(&ids); err != nil {
+		t.Fatalf("count failed: %v", err)
+	}
+	if ids != 2 {
+		t.Errorf("expected the stale id entry to be removed, got %d entries", ids)
+	}
+	catalog, err := storage.GetFieldCatalog(ctx, "a.log")
+	if err != nil {
+		t.Fatalf("GetFieldCatalog failed: %v", err)
+	}
+	if len(catalog) != 1 || catalog[0].Path != "new" || catalog[0].Seen != 2 {
+		t.Errorf("expected only the stored records in the rebuilt catalog, got %+v", catalog)
+	}
+}
+
+func TestPartitionedStorage_ReadsDuringDrop(t *testing.T) {
+	storage, cleanup := newPartitionedStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	const days = 20
+	for day := int64(0); day < days; day++ {
+		storeRecords(t, storage, requestRecords(day, 20))
+	}
+
+	done := make(chan struct{})
+	errs := make(chan error, 1)
+	go func() {
+		defer close(errs)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+			}
+			if _, err := storage.Query(ctx, domain.Query{Limit: 10}); err != nil {
+				errs <- err
+				return
+			}
+		}
+	}()
+	for day := int64(1); day <= days; day++ {
+		if _, err := storage.DropPartitionsBefore(ctx, day*dayMs, false); err != nil {
+			t.Fatalf("DropPartitionsBefore failed: %v", err)
+		}
+	}
+	close(done)
+	if err := <-errs; err != nil {
+		t.Errorf("query failed during a partition drop: %v", err)
+	}
+}
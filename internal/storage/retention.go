@@ -0,0 +1,345 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"os"
+	"time"
+
+	"LogLens/internal/domain"
+)
+
+// DeleteByQuery removes every record matching filters. With dryRun set it only
+// reports how many records would be removed.
+func (s *SQLiteStorage) DeleteByQuery(ctx context.Context, filters []domain.FilterCondition, dryRun bool) (*domain.DeleteResult, error) {
+	release := s.usePartitions()
+	defer release()
+
+	startTime := time.Now()
+
+	where, args, err := s.buildWhere(filters)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build delete filter: %w", err)
+	}
+	if where == "" {
+		return nil, fmt.Errorf("delete requires at least one filter")
+	}
+
+	result := &domain.DeleteResult{DryRun: dryRun}
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+s.recordsFrom(filters)+where, args...).Scan(&result.Matched); err != nil {
+		return nil, fmt.Errorf("failed to count matching records: %w", err)
+	}
+
+	if !dryRun && result.Matched > 0 {
+		tx, err := s.db.BeginTx(ctx, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to begin transaction: %w", err)
+		}
+		defer tx.Rollback()
+
+		if err := s.forgetRecords(ctx, tx, "SELECT source, "+columnExpr("fields")+" FROM "+s.recordsFrom(filters)+where, args...); err != nil {
+			return nil, err
+		}
+		for _, table := range s.recordTables(filters) {
+			if err := releaseIDs(ctx, tx, table, where, args...); err != nil {
+				return nil, err
+			}
+			res, err := tx.ExecContext(ctx, "DELETE FROM "+table+where, args...)
+			if err != nil {
+				return nil, fmt.Errorf("failed to delete records: %w", err)
+			}
+			n, _ := res.RowsAffected()
+			result.Deleted += n
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, fmt.Errorf("failed to delete records: %w", err)
+		}
+		s.compression.invalidateStats()
+	}
+
+	result.Took = time.Since(startTime).Milliseconds()
+	return result, nil
+}
+
+func (s *SQLiteStorage) GetRetentionPolicies(ctx context.Context) ([]domain.RetentionPolicy, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT source, max_age_ms, max_rows, max_bytes FROM retention_policies ORDER BY source")
+	if err != nil {
+		return nil, fmt.Errorf("failed to query retention policies: %w", err)
+	}
+	defer rows.Close()
+
+	policies := make([]domain.RetentionPolicy, 0)
+	for rows.Next() {
+		var p domain.RetentionPolicy
+		if err := rows.Scan(&p.Source, &p.MaxAgeMs, &p.MaxRows, &p.MaxBytes); err != nil {
+			return nil, fmt.Errorf("failed to scan retention policy: %w", err)
+		}
+		policies = append(policies, p)
+	}
+	return policies, rows.Err()
+}
+
+// SetRetentionPolicies replaces the stored policy set. A policy with an empty
+// Source applies to the whole database rather than to a single source.
+func (s *SQLiteStorage) SetRetentionPolicies(ctx context.Context, policies []domain.RetentionPolicy) error {
+	seen := make(map[string]bool)
+	for _, p := range policies {
+		if p.MaxAgeMs < 0 || p.MaxRows < 0 || p.MaxBytes < 0 {
+			return fmt.Errorf("retention limits cannot be negative")
+		}
+		if seen[p.Source] {
+			return fmt.Errorf("duplicate retention policy for source %q", p.Source)
+		}
+		seen[p.Source] = true
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, "DELETE FROM retention_policies"); err != nil {
+		return fmt.Errorf("failed to clear retention policies: %w", err)
+	}
+	for _, p := range policies {
+		if _, err := tx.ExecContext(ctx,
+			"INSERT INTO retention_policies (source, max_age_ms, max_rows, max_bytes) VALUES (?, ?, ?, ?)",
+			p.Source, p.MaxAgeMs, p.MaxRows, p.MaxBytes,
+		); err != nil {
+			return fmt.Errorf("failed to store retention policy: %w", err)
+		}
+	}
+
+	return tx.Commit()
+}
+
+// ApplyRetention enforces the stored policies. Limits are evaluated newest
+// first, so the rows kept are always the most recent ones.
+func (s *SQLiteStorage) ApplyRetention(ctx context.Context, dryRun bool) (*domain.RetentionResult, error) {
+	startTime := time.Now()
+
+	policies, err := s.GetRetentionPolicies(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &domain.RetentionResult{DryRun: dryRun, Outcomes: make([]domain.RetentionOutcome, 0, len(policies))}
+	now := time.Now().UnixMilli()
+
+	for _, p := range policies {
+		if p.MaxAgeMs == 0 && p.MaxRows == 0 && p.MaxBytes == 0 {
+			continue
+		}
+
+		outcome := domain.RetentionOutcome{Policy: p}
+
+		// Whole partitions past a database-wide age limit are dropped outright;
+		// the row-level pass below then only has the boundary partition to scan.
+		if p.Source == "" && p.MaxAgeMs > 0 {
+			dropped, err := s.DropPartitionsBefore(ctx, now-p.MaxAgeMs, dryRun)
+			if err != nil {
+				return nil, fmt.Errorf("failed to apply retention for %q: %w", p.Source, err)
+			}
+			outcome.Matched += dropped.Matched
+			outcome.Deleted += dropped.Deleted
+		}
+
+		matched, deleted, err := s.retainRows(ctx, p, now, dryRun)
+		if err != nil {
+			return nil, fmt.Errorf("failed to apply retention for %q: %w", p.Source, err)
+		}
+		if dryRun && p.Source == "" && p.MaxAgeMs > 0 {
+			// The dry-run drop did not remove anything, so those rows are
+			// counted again by the row-level select.
+			matched -= outcome.Matched
+		}
+		outcome.Matched += matched
+		outcome.Deleted += deleted
+
+		result.Matched += outcome.Matched
+		result.Deleted += outcome.Deleted
+		result.Outcomes = append(result.Outcomes, outcome)
+	}
+
+	result.Took = time.Since(startTime).Milliseconds()
+	return result, nil
+}
+
+// retainRows is the row-level pass of policy p: it counts the records p
+// removes and, unless dryRun is set, deletes them.
+func (s *SQLiteStorage) retainRows(ctx context.Context, p domain.RetentionPolicy, now int64, dryRun bool) (matched, deleted int64, err error) {
+	release := s.usePartitions()
+	defer release()
+
+	selectQ, args := retentionSelect(s.recordsFrom(nil), p, now)
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM ("+selectQ+")", args...).Scan(&matched); err != nil {
+		return 0, 0, fmt.Errorf("failed to evaluate retention: %w", err)
+	}
+	if dryRun || matched == 0 {
+		return matched, 0, nil
+	}
+	deleted, err = s.deleteSelected(ctx, selectQ, args)
+	return matched, deleted, err
+}
+
+// retentionSelect returns the ids of the rows p removes. MaxBytes counts
+// uncompressed sizes, since a raw line stored in a block has no stored size
+// of its own.
+func retentionSelect(from string, p domain.RetentionPolicy, now int64) (string, []interface{}) {
+	cutoff := int64(math.MinInt64)
+	if p.MaxAgeMs > 0 {
+		cutoff = now - p.MaxAgeMs
+	}
+	maxRows := int64(math.MaxInt64)
+	if p.MaxRows > 0 {
+		maxRows = p.MaxRows
+	}
+	maxBytes := int64(math.MaxInt64)
+	if p.MaxBytes > 0 {
+		maxBytes = p.MaxBytes
+	}
+
+	scope := ""
+	var args []interface{}
+	if p.Source != "" {
+		scope = " WHERE source = ?"
+		args = append(args, p.Source)
+	}
+
+	q := `SELECT id FROM (
+		SELECT id, timestamp,
+			ROW_NUMBER() OVER w AS rn,
+			SUM(inflated_size(raw) + length(CAST(message AS BLOB)) + inflated_size(fields)) OVER w AS running
+		FROM ` + from + scope + `
+		WINDOW w AS (ORDER BY timestamp DESC, id DESC ROWS UNBOUNDED PRECEDING)
+	) WHERE timestamp < ? OR rn > ? OR running > ?`
+	args = append(args, cutoff, maxRows, maxBytes)
+	return q, args
+}
+
+// deleteSelected removes the records whose ids selectQ returns. With several
+// record tables the ids are materialized first, since deleting from one
+// partition would change what a window-based select returns for the next.
+func (s *SQLiteStorage) deleteSelected(ctx context.Context, selectQ string, args []interface{}) (int64, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+	defer s.compression.invalidateStats()
+
+	tables := s.recordTables(nil)
+	if len(tables) == 1 {
+		if err := s.forgetRecords(ctx, tx, "SELECT source, "+columnExpr("fields")+" FROM "+tables[0]+" WHERE id IN ("+selectQ+")", args...); err != nil {
+			return 0, err
+		}
+		if err := releaseIDs(ctx, tx, tables[0], " WHERE id IN ("+selectQ+")", args...); err != nil {
+			return 0, err
+		}
+		res, err := tx.ExecContext(ctx, "DELETE FROM "+tables[0]+" WHERE id IN ("+selectQ+")", args...)
+		if err != nil {
+			return 0, err
+		}
+		deleted, _ := res.RowsAffected()
+		return deleted, tx.Commit()
+	}
+
+	if _, err := tx.ExecContext(ctx, "CREATE TEMP TABLE IF NOT EXISTS doomed_ids (id TEXT PRIMARY KEY)"); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM temp.doomed_ids"); err != nil {
+		return 0, err
+	}
+	if _, err := tx.ExecContext(ctx, "INSERT OR IGNORE INTO temp.doomed_ids "+selectQ, args...); err != nil {
+		return 0, err
+	}
+
+	var deleted int64
+	for _, table := range tables {
+		if err := s.forgetRecords(ctx, tx, "SELECT source, "+columnExpr("fields")+" FROM "+table+" WHERE id IN (SELECT id FROM temp.doomed_ids)"); err != nil {
+			return 0, err
+		}
+		if err := releaseIDs(ctx, tx, table, " WHERE id IN (SELECT id FROM temp.doomed_ids)"); err != nil {
+			return 0, err
+		}
+		res, err := tx.ExecContext(ctx, "DELETE FROM "+table+" WHERE id IN (SELECT id FROM temp.doomed_ids)")
+		if err != nil {
+			return 0, err
+		}
+		n, _ := res.RowsAffected()
+		deleted += n
+	}
+	if _, err := tx.ExecContext(ctx, "DELETE FROM temp.doomed_ids"); err != nil {
+		return 0, err
+	}
+	return deleted, tx.Commit()
+}
+
+// Compact deletes the compressed blocks no record points to any more,
+// rebuilds the field catalog, then returns free pages to the filesystem and truncates the WAL. A
+// database created before incremental auto-vacuum was enabled is converted
+// with a one-off full VACUUM.
+func (s *SQLiteStorage) Compact(ctx context.Context) (*domain.CompactResult, error) {
+	startTime := time.Now()
+	result := &domain.CompactResult{SizeBefore: s.fileSize()}
+
+	var mode int
+	if err := s.db.QueryRowContext(ctx, "PRAGMA auto_vacuum").Scan(&mode); err != nil {
+		return nil, fmt.Errorf("failed to read auto_vacuum mode: %w", err)
+	}
+
+	if err := s.collectGarbage(ctx); err != nil {
+		return nil, err
+	}
+
+	if mode != 2 {
+		if _, err := s.db.ExecContext(ctx, "PRAGMA auto_vacuum=INCREMENTAL"); err != nil {
+			return nil, fmt.Errorf("failed to enable incremental vacuum: %w", err)
+		}
+		if _, err := s.db.ExecContext(ctx, "VACUUM"); err != nil {
+			return nil, fmt.Errorf("failed to vacuum database: %w", err)
+		}
+	} else if _, err := s.db.ExecContext(ctx, "PRAGMA incremental_vacuum"); err != nil {
+		return nil, fmt.Errorf("failed to run incremental vacuum: %w", err)
+	}
+
+	if _, err := s.db.ExecContext(ctx, "PRAGMA wal_checkpoint(TRUNCATE)"); err != nil {
+		return nil, fmt.Errorf("failed to checkpoint WAL: %w", err)
+	}
+
+	result.SizeAfter = s.fileSize()
+	result.ReclaimedBytes = result.SizeBefore - result.SizeAfter
+	if result.ReclaimedBytes < 0 {
+		result.ReclaimedBytes = 0
+	}
+	result.Took = time.Since(startTime).Milliseconds()
+	return result, nil
+}
+
+// collectGarbage cleans up what deletes and partition drops leave behind to
+// stay cheap: unused blocks, partition_ids entries of dropped partitions and
+// the catalog counts of dropped records.
+func (s *SQLiteStorage) collectGarbage(ctx context.Context) error {
+	release := s.usePartitions()
+	defer release()
+
+	if err := s.collectBlocks(ctx); err != nil {
+		return err
+	}
+	if _, err := s.db.ExecContext(ctx, "DELETE FROM partition_ids WHERE part NOT IN (SELECT name FROM partitions)"); err != nil {
+		return fmt.Errorf("failed to delete record ids of dropped partitions: %w", err)
+	}
+	return s.rebuildCatalog(ctx)
+}
+
+func (s *SQLiteStorage) fileSize() int64 {
+	var total int64
+	for _, path := range []string{s.dbPath, s.dbPath + "-wal"} {
+		if info, err := os.Stat(path); err == nil {
+			total += info.Size()
+		}
+	}
+	return total
+}
@@ -0,0 +1,181 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"LogLens/internal/domain"
+)
+
+func TestSQLiteStorage_DeleteByQuery(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	records := []domain.LogRecord{
+		{ID: "1", Timestamp: 1000, Level: "DEBUG", Message: "noise", Source: "a.log", Raw: "raw1", Fields: map[string]interface{}{"k": "v"}},
+		{ID: "2", Timestamp: 2000, Level: "DEBUG", Message: "noise", Source: "a.log", Raw: "raw2"},
+		{ID: "3", Timestamp: 3000, Level: "ERROR", Message: "boom", Source: "b.log", Raw: "raw3"},
+	}
+	storeRecords(t, storage, records)
+
+	filters := []domain.FilterCondition{{Type: domain.FilterEquality, Field: "level", Value: "DEBUG"}}
+
+	dry, err := storage.DeleteByQuery(context.Background(), filters, true)
+	if err != nil {
+		t.Fatalf("DeleteByQuery dry run failed: %v", err)
+	}
+	if dry.Matched != 2 || dry.Deleted != 0 {
+		t.Errorf("dry run: expected matched=2 deleted=0, got %+v", dry)
+	}
+	if total, _ := storage.GetTotalCount(context.Background()); total != 3 {
+		t.Errorf("dry run must not delete, total=%d", total)
+	}
+
+	res, err := storage.DeleteByQuery(context.Background(), filters, false)
+	if err != nil {
+		t.Fatalf("DeleteByQuery failed: %v", err)
+	}
+	if res.Deleted != 2 {
+		t.Errorf("expected 2 deleted, got %d", res.Deleted)
+	}
+	if total, _ := storage.GetTotalCount(context.Background()); total != 1 {
+		t.Errorf("expected 1 remaining record, got %d", total)
+	}
+
+	catalog, err := storage.GetFieldCatalog(context.Background(), "a.log")
+	if err != nil {
+		t.Fatalf("GetFieldCatalog failed: %v", err)
+	}
+	if len(catalog) != 0 {
+		t.Errorf("expected catalog for emptied source to be pruned, got %d fields", len(catalog))
+	}
+
+	if _, err := storage.DeleteByQuery(context.Background(), nil, false); err == nil {
+		t.Error("expected error for delete without filters")
+	}
+}
+
+func TestSQLiteStorage_ApplyRetention(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	now := time.Now().UnixMilli()
+	var records []domain.LogRecord
+	for i := 0; i < 10; i++ {
+		records = append(records, domain.LogRecord{
+			ID: fmt.Sprintf("a%d", i), Timestamp: now - int64(i)*time.Hour.Milliseconds(),
+			Level: "INFO", Message: "m", Source: "a.log", Raw: strings.Repeat("x", 100),
+		})
+		records = append(records, domain.LogRecord{
+			ID: fmt.Sprintf("b%d", i), Timestamp: now - int64(i)*time.Hour.Milliseconds(),
+			Level: "INFO", Message: "m", Source: "b.log", Raw: strings.Repeat("x", 100),
+		})
+	}
+	storeRecords(t, storage, records)
+
+	policies := []domain.RetentionPolicy{
+		{Source: "a.log", MaxAgeMs: (5*time.Hour + 30*time.Minute).Milliseconds()},
+		{Source: "b.log", MaxRows: 3},
+	}
+	if err := storage.SetRetentionPolicies(context.Background(), policies); err != nil {
+		t.Fatalf("SetRetentionPolicies failed: %v", err)
+	}
+
+	stored, err := storage.GetRetentionPolicies(context.Background())
+	if err != nil || len(stored) != 2 {
+		t.Fatalf("expected 2 stored policies, got %v (%v)", stored, err)
+	}
+
+	dry, err := storage.ApplyRetention(context.Background(), true)
+	if err != nil {
+		t.Fatalf("ApplyRetention dry run failed: %v", err)
+	}
+	if dry.Matched != 4+7 || dry.Deleted != 0 {
+		t.Errorf("dry run: expected matched=11 deleted=0, got matched=%d deleted=%d", dry.Matched, dry.Deleted)
+	}
+
+	res, err := storage.ApplyRetention(context.Background(), false)
+	if err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if res.Deleted != 11 {
+		t.Errorf("expected 11 deleted, got %d", res.Deleted)
+	}
+
+	result, err := storage.Query(context.Background(), domain.Query{
+		Filters: []domain.FilterCondition{{Type: domain.FilterEquality, Field: "source", Value: "b.log"}},
+		Limit:   100,
+	})
+	if err != nil {
+		t.Fatalf("Query failed: %v", err)
+	}
+	if result.Total != 3 {
+		t.Fatalf("expected 3 b.log records kept, got %d", result.Total)
+	}
+	for _, r := range result.Records {
+		if r.ID != "b0" && r.ID != "b1" && r.ID != "b2" {
+			t.Errorf("expected newest records to survive, got %s", r.ID)
+		}
+	}
+}
+
+func TestSQLiteStorage_ApplyRetention_MaxBytes(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	var records []domain.LogRecord
+	for i := 0; i < 10; i++ {
+		records = append(records, domain.LogRecord{
+			ID: fmt.Sprintf("r%d", i), Timestamp: int64(i), Level: "INFO", Message: "", Raw: strings.Repeat("x", 98),
+		})
+	}
+	storeRecords(t, storage, records)
+
+	// each row is 98 bytes of raw plus "null" for empty fields
+	if err := storage.SetRetentionPolicies(context.Background(), []domain.RetentionPolicy{{MaxBytes: 450}}); err != nil {
+		t.Fatalf("SetRetentionPolicies failed: %v", err)
+	}
+	if _, err := storage.ApplyRetention(context.Background(), false); err != nil {
+		t.Fatalf("ApplyRetention failed: %v", err)
+	}
+	if total, _ := storage.GetTotalCount(context.Background()); total != 4 {
+		t.Errorf("expected 4 records within 450 bytes, got %d", total)
+	}
+}
+
+func TestSQLiteStorage_Compact(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	records := make([]domain.LogRecord, 3000)
+	for i := range records {
+		records[i] = domain.LogRecord{
+			ID: fmt.Sprintf("r%d", i), Timestamp: int64(i), Level: "DEBUG", Message: "m", Raw: strings.Repeat("payload ", 64),
+		}
+	}
+	storeRecords(t, storage, records)
+
+	if _, err := storage.Compact(context.Background()); err != nil {
+		t.Fatalf("initial Compact failed: %v", err)
+	}
+
+	if _, err := storage.DeleteByQuery(context.Background(), []domain.FilterCondition{
+		{Type: domain.FilterEquality, Field: "level", Value: "DEBUG"},
+	}, false); err != nil {
+		t.Fatalf("DeleteByQuery failed: %v", err)
+	}
+
+	res, err := storage.Compact(context.Background())
+	if err != nil {
+		t.Fatalf("Compact failed: %v", err)
+	}
+	if res.ReclaimedBytes <= 0 {
+		t.Errorf("expected reclaimed bytes > 0, got %+v", res)
+	}
+	if res.SizeAfter >= res.SizeBefore {
+		t.Errorf("expected database to shrink, got %+v", res)
+	}
+}
@@ -0,0 +1,300 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"LogLens/internal/domain"
+)
+
+const (
+	// maxQueryHistory is the number of executed queries kept; older ones are
+	// pruned as new ones are recorded.
+	maxQueryHistory     = 1000
+	defaultHistoryLimit = 100
+)
+
+// SavedQueryStore keeps saved queries and the query history in a small
+// database of their own, so they are shared by every workspace and untouched
+// by deletes, retention and compaction of the log databases.
+type SavedQueryStore struct {
+	db *sql.DB
+}
+
+func NewSavedQueryStore(dbPath string) (*SavedQueryStore, error) {
+	db, err := sql.Open("sqlite", fmt.Sprintf("%s?_pragma=busy_timeout(%d)&_pragma=journal_mode(WAL)", dbPath, busyTimeoutMs))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open query library: %w", err)
+	}
+	// Library statements are tiny; one connection keeps them serialized.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`
+	CREATE TABLE IF NOT EXISTS query_history (
+		id INTEGER PRIMARY KEY,
+		query TEXT NOT NULL,
+		executed_at INTEGER NOT NULL,
+		duration_ms INTEGER NOT NULL DEFAULT 0,
+		result_count INTEGER NOT NULL DEFAULT 0,
+		error TEXT NOT NULL DEFAULT ''
+	);
+	CREATE TABLE IF NOT EXISTS saved_queries (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL UNIQUE,
+		description TEXT NOT NULL DEFAULT '',
+		tags TEXT NOT NULL DEFAULT '[]',
+		query TEXT NOT NULL,
+		created_at INTEGER NOT NULL,
+		updated_at INTEGER NOT NULL
+	);
+	`); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to initialize query library: %w", err)
+	}
+	return &SavedQueryStore{db: db}, nil
+}
+
+func (l *SavedQueryStore) Close() error {
+	return l.db.Close()
+}
+
+// RecordQuery appends entry to the query history. A zero ExecutedAt is set
+// to now.
+func (l *SavedQueryStore) RecordQuery(ctx context.Context, entry domain.QueryHistoryEntry) error {
+	query, err := json.Marshal(entry.Query)
+	if err != nil {
+		return fmt.Errorf("failed to encode query: %w", err)
+	}
+	if entry.ExecutedAt == 0 {
+		entry.ExecutedAt = time.Now().UnixMilli()
+	}
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx,
+		"INSERT INTO query_history (query, executed_at, duration_ms, result_count, error) VALUES (?, ?, ?, ?, ?)",
+		string(query), entry.ExecutedAt, entry.DurationMs, entry.ResultCount, entry.Error,
+	); err != nil {
+		return fmt.Errorf("failed to record query: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx,
+		"DELETE FROM query_history WHERE id <= (SELECT id FROM query_history ORDER BY id DESC LIMIT 1 OFFSET ?)",
+		maxQueryHistory,
+	); err != nil {
+		return fmt.Errorf("failed to prune query history: %w", err)
+	}
+	return tx.Commit()
+}
+
+// ListQueryHistory returns the most recently executed queries, newest first.
+func (l *SavedQueryStore) ListQueryHistory(ctx context.Context, limit int) ([]domain.QueryHistoryEntry, error) {
+	if limit <= 0 {
+		limit = defaultHistoryLimit
+	}
+	rows, err := l.db.QueryContext(ctx,
+		"SELECT id, query, executed_at, duration_ms, result_count, error FROM query_history ORDER BY id DESC LIMIT ?",
+		limit,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query history: %w", err)
+	}
+	defer rows.Close()
+
+	entries := make([]domain.QueryHistoryEntry, 0)
+	for rows.Next() {
+		var e domain.QueryHistoryEntry
+		var query string
+		if err := rows.Scan(&e.ID, &query, &e.ExecutedAt, &e.DurationMs, &e.ResultCount, &e.Error); err != nil {
+			return nil, fmt.Errorf("failed to scan history entry: %w", err)
+		}
+		if err := json.Unmarshal([]byte(query), &e.Query); err != nil {
+			return nil, fmt.Errorf("failed to decode history entry %d: %w", e.ID, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+func (l *SavedQueryStore) ClearQueryHistory(ctx context.Context) error {
+	if _, err := l.db.ExecContext(ctx, "DELETE FROM query_history"); err != nil {
+		return fmt.Errorf("failed to clear query history: %w", err)
+	}
+	return nil
+}
+
+// SaveQuery stores q under its name, replacing the description, tags and
+// query of an existing saved query with the same name.
+func (l *SavedQueryStore) SaveQuery(ctx context.Context, q domain.SavedQuery) (*domain.SavedQuery, error) {
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	saved, err := saveQuery(ctx, tx, q, time.Now().UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to save query: %w", err)
+	}
+	return saved, nil
+}
+
+// ImportSavedQueries saves every query in one transaction, so that a bad
+// entry leaves the library unchanged.
+func (l *SavedQueryStore) ImportSavedQueries(ctx context.Context, queries []domain.SavedQuery) (int, error) {
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	now := time.Now().UnixMilli()
+	for _, q := range queries {
+		if _, err := saveQuery(ctx, tx, q, now); err != nil {
+			return 0, err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("failed to import saved queries: %w", err)
+	}
+	return len(queries), nil
+}
+
+func saveQuery(ctx context.Context, tx *sql.Tx, q domain.SavedQuery, now int64) (*domain.SavedQuery, error) {
+	q.Name = strings.TrimSpace(q.Name)
+	if q.Name == "" {
+		return nil, fmt.Errorf("saved query name is required")
+	}
+	q.Tags = normalizeTags(q.Tags)
+
+	tags, err := json.Marshal(q.Tags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode tags: %w", err)
+	}
+	query, err := json.Marshal(q.Query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode query: %w", err)
+	}
+
+	err = tx.QueryRowContext(ctx, `
+		INSERT INTO saved_queries (name, description, tags, query, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET
+			description = excluded.description,
+			tags = excluded.tags,
+			query = excluded.query,
+			updated_at = excluded.updated_at
+		RETURNING id, created_at, updated_at`,
+		q.Name, q.Description, string(tags), string(query), now, now,
+	).Scan(&q.ID, &q.CreatedAt, &q.UpdatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to save query %q: %w", q.Name, err)
+	}
+	return &q, nil
+}
+
+// normalizeTags trims and drops empty and duplicate tags, keeping their
+// order.
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	out := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		out = append(out, tag)
+	}
+	return out
+}
+
+// ListSavedQueries returns the saved queries by name. A non-empty search
+// keeps those whose name, description or tags contain it, ignoring case.
+func (l *SavedQueryStore) ListSavedQueries(ctx context.Context, search string) ([]domain.SavedQuery, error) {
+	q := "SELECT id, name, description, tags, query, created_at, updated_at FROM saved_queries"
+	var args []interface{}
+	if search = strings.TrimSpace(search); search != "" {
+		escaped := strings.NewReplacer("\\", "\\\\", "%", "\\%", "_", "\\_").Replace(strings.ToLower(search))
+		pattern := "%" + escaped + "%"
+		q += ` WHERE LOWER(name) LIKE ? ESCAPE '\' OR LOWER(description) LIKE ? ESCAPE '\'
+			OR EXISTS (SELECT 1 FROM json_each(saved_queries.tags) WHERE LOWER(value) LIKE ? ESCAPE '\')`
+		args = append(args, pattern, pattern, pattern)
+	}
+	q += " ORDER BY name COLLATE NOCASE"
+
+	rows, err := l.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query saved queries: %w", err)
+	}
+	defer rows.Close()
+
+	queries := make([]domain.SavedQuery, 0)
+	for rows.Next() {
+		var sq domain.SavedQuery
+		var tags, query string
+		if err := rows.Scan(&sq.ID, &sq.Name, &sq.Description, &tags, &query, &sq.CreatedAt, &sq.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan saved query: %w", err)
+		}
+		if err := json.Unmarshal([]byte(tags), &sq.Tags); err != nil {
+			return nil, fmt.Errorf("failed to decode tags of %q: %w", sq.Name, err)
+		}
+		if err := json.Unmarshal([]byte(query), &sq.Query); err != nil {
+			return nil, fmt.Errorf("failed to decode saved query %q: %w", sq.Name, err)
+		}
+		queries = append(queries, sq)
+	}
+	return queries, rows.Err()
+}
+
+func (l *SavedQueryStore) RenameSavedQuery(ctx context.Context, id int64, name string) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return fmt.Errorf("saved query name is required")
+	}
+
+	tx, err := l.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var other int64
+	err = tx.QueryRowContext(ctx, "SELECT id FROM saved_queries WHERE name = ?", name).Scan(&other)
+	switch {
+	case err == nil && other != id:
+		return fmt.Errorf("saved query %q already exists", name)
+	case err != nil && err != sql.ErrNoRows:
+		return fmt.Errorf("failed to check saved query name: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, "UPDATE saved_queries SET name = ?, updated_at = ? WHERE id = ?", name, time.Now().UnixMilli(), id)
+	if err != nil {
+		return fmt.Errorf("failed to rename saved query: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("saved query %d not found", id)
+	}
+	return tx.Commit()
+}
+
+func (l *SavedQueryStore) DeleteSavedQuery(ctx context.Context, id int64) error {
+	res, err := l.db.ExecContext(ctx, "DELETE FROM saved_queries WHERE id = ?", id)
+	if err != nil {
+		return fmt.Errorf("failed to delete saved query: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return fmt.Errorf("saved query %d not found", id)
+	}
+	return nil
+}
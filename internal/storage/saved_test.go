@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"LogLens/internal/domain"
+)
+
+func newTestStore(t *testing.T) *SavedQueryStore {
+	t.Helper()
+	store, err := NewSavedQueryStore(filepath.Join(t.TempDir(), "library.db"))
+	if err != nil {
+		t.Fatalf("failed to open query store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func savedNames(queries []domain.SavedQuery) string {
+	names := ""
+	for _, q := range queries {
+		names += q.Name + " "
+	}
+	return names
+}
+
+func TestQueryHistory_RecordListAndPrune(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	for i := 0; i < maxQueryHistory+5; i++ {
+		entry := domain.QueryHistoryEntry{
+			Query:       domain.Query{Text: "level:ERROR", Limit: i},
+			DurationMs:  3,
+			ResultCount: int64(i),
+		}
+		if err := store.RecordQuery(ctx, entry); err != nil {
+			t.Fatalf("RecordQuery failed: %v", err)
+		}
+	}
+
+	var count int
+	if err := store.db.QueryRow("SELECT COUNT(*) FROM query_history").Scan(&count); err != nil {
+		t.Fatalf("failed to count history: %v", err)
+	}
+	if count != maxQueryHistory {
+		t.Errorf("expected history pruned to %d entries, got %d", maxQueryHistory, count)
+	}
+
+	entries, err := store.ListQueryHistory(ctx, 2)
+	if err != nil {
+		t.Fatalf("ListQueryHistory failed: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	latest := entries[0]
+	if latest.Query.Limit != maxQueryHistory+4 || latest.Query.Text != "level:ERROR" || latest.ResultCount != maxQueryHistory+4 || latest.ExecutedAt == 0 {
+		t.Errorf("expected the latest query first, got %+v", latest)
+	}
+
+	if err := store.ClearQueryHistory(ctx); err != nil {
+		t.Fatalf("ClearQueryHistory failed: %v", err)
+	}
+	if entries, _ := store.ListQueryHistory(ctx, 0); len(entries) != 0 {
+		t.Errorf("expected an empty history, got %d entries", len(entries))
+	}
+}
+
+func TestSavedQueries_SaveSearchRenameDelete(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	errors, err := store.SaveQuery(ctx, domain.SavedQuery{
+		Name:  " API errors ",
+		Tags:  []string{"api", " oncall", "api", ""},
+		Query: domain.Query{Text: "level:ERROR service:api"},
+	})
+	if err != nil {
+		t.Fatalf("SaveQuery failed: %v", err)
+	}
+	if errors.ID == 0 || errors.Name != "API errors" || len(errors.Tags) != 2 || errors.Tags[1] != "oncall" {
+		t.Errorf("expected a trimmed name and deduplicated tags, got %+v", errors)
+	}
+	slow, err := store.SaveQuery(ctx, domain.SavedQuery{Name: "slow requests", Description: "Latency over 1s_", Query: domain.Query{Text: "latency>1000"}})
+	if err != nil {
+		t.Fatalf("SaveQuery failed: %v", err)
+	}
+
+	// Saving under an existing name updates it in place.
+	updated, err := store.SaveQuery(ctx, domain.SavedQuery{Name: "API errors", Description: "5xx from the API", Query: domain.Query{Text: "level:ERROR"}})
+	if err != nil {
+		t.Fatalf("SaveQuery failed: %v", err)
+	}
+	if updated.ID != errors.ID || updated.CreatedAt != errors.CreatedAt {
+		t.Errorf("expected the existing query to be updated, got %+v", updated)
+	}
+
+	tests := []struct {
+		search string
+		want   string
+	}{
+		{"", "API errors slow requests "},
+		{"api", "API errors "},
+		{"ONCALL", ""},
+		{"latency", "slow requests "},
+		{"1s_", "slow requests "},
+		{"%", ""},
+	}
+	for _, tt := range tests {
+		queries, err := store.ListSavedQueries(ctx, tt.search)
+		if err != nil {
+			t.Fatalf("ListSavedQueries(%q) failed: %v", tt.search, err)
+		}
+		if got := savedNames(queries); got != tt.want {
+			t.Errorf("search %q: expected %q, got %q", tt.search, tt.want, got)
+		}
+	}
+	queries, _ := store.ListSavedQueries(ctx, "api")
+	if len(queries) != 1 || queries[0].Query.Text != "level:ERROR" || queries[0].Description != "5xx from the API" {
+		t.Errorf("expected the updated query, got %+v", queries)
+	}
+
+	if err := store.RenameSavedQuery(ctx, slow.ID, "API errors"); err == nil {
+		t.Error("expected an error renaming onto an existing name")
+	}
+	if err := store.RenameSavedQuery(ctx, slow.ID, "latency"); err != nil {
+		t.Fatalf("RenameSavedQuery failed: %v", err)
+	}
+	if err := store.RenameSavedQuery(ctx, 999, "other"); err == nil {
+		t.Error("expected an error renaming a missing query")
+	}
+
+	if err := store.DeleteSavedQuery(ctx, errors.ID); err != nil {
+		t.Fatalf("DeleteSavedQuery failed: %v", err)
+	}
+	if err := store.DeleteSavedQuery(ctx, errors.ID); err == nil {
+		t.Error("expected an error deleting a missing query")
+	}
+	if queries, _ := store.ListSavedQueries(ctx, ""); savedNames(queries) != "latency " {
+		t.Errorf("expected only the renamed query, got %q", savedNames(queries))
+	}
+}
+
+func TestImportSavedQueries_AllOrNothing(t *testing.T) {
+	store := newTestStore(t)
+	ctx := context.Background()
+
+	_, err := store.ImportSavedQueries(ctx, []domain.SavedQuery{{Name: "a"}, {Name: " "}})
+	if err == nil {
+		t.Fatal("expected an error for a query without a name")
+	}
+	if queries, _ := store.ListSavedQueries(ctx, ""); len(queries) != 0 {
+		t.Errorf("expected nothing imported, got %q", savedNames(queries))
+	}
+
+	n, err := store.ImportSavedQueries(ctx, []domain.SavedQuery{{Name: "a"}, {Name: "b", Tags: []string{"x"}}})
+	if err != nil || n != 2 {
+		t.Fatalf("expected 2 imported, got %d: %v", n, err)
+	}
+}
@@ -8,9 +8,11 @@ import (
 	"fmt"
 	"log"
 	"regexp"
+	"runtime"
 	"strings"
 	"sync"
 	"time"
+	"unicode"
 
 	"LogLens/internal/domain"
 	sqlite3 "modernc.org/sqlite"
@@ -22,16 +24,41 @@ var (
 	regexpErr  error
 )
 
+// SQLiteStorage writes through a single connection and reads through a
+// separate pool of query_only connections, so WAL readers keep working while
+// an import holds the write lock.
 type SQLiteStorage struct {
-	db *sql.DB
+	db     *sql.DB
+	rdb    *sql.DB
+	dbPath      string
+	parts       *partitionSet
+	compression *compressionState
+}
+
+const busyTimeoutMs = 5000
+
+// queryer is satisfied by *sql.DB and *sql.Tx so read helpers can run either
+// on the reader pool or inside a snapshot transaction.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
 func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
 	if err := registerRegexpFunc(); err != nil {
 		return nil, fmt.Errorf("failed to register regexp function: %w", err)
 	}
+	if err := registerCompressionFuncs(); err != nil {
+		return nil, fmt.Errorf("failed to register compression functions: %w", err)
+	}
+	if err := registerAggregateFuncs(); err != nil {
+		return nil, fmt.Errorf("failed to register aggregate functions: %w", err)
+	}
+	if err := registerTimeBucketFunc(); err != nil {
+		return nil, fmt.Errorf("failed to register time_bucket function: %w", err)
+	}
 
-	db, err := sql.Open("sqlite", dbPath)
+	db, err := sql.Open("sqlite", fmt.Sprintf("%s?_pragma=busy_timeout(%d)", dbPath, busyTimeoutMs))
 	if err != nil {
 		return nil, fmt.Errorf("failed to open database: %w", err)
 	}
@@ -39,41 +66,65 @@ func NewSQLiteStorage(dbPath string) (*SQLiteStorage, error) {
 	db.SetMaxOpenConns(1)
 	db.SetMaxIdleConns(1)
 	
-	storage := &SQLiteStorage{db: db}
+	storage := &SQLiteStorage{db: db, dbPath: dbPath}
 	
 	if err := storage.init(); err != nil {
+		db.Close()
 		return nil, fmt.Errorf("failed to initialize database: %w", err)
 	}
+
+	// Opened after init so readers never see a half-migrated schema.
+	rdb, err := sql.Open("sqlite", fmt.Sprintf("%s?_pragma=busy_timeout(%d)&_pragma=query_only(1)", dbPath, busyTimeoutMs))
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open reader pool: %w", err)
+	}
+	readers := runtime.NumCPU()
+	if readers > 8 {
+		readers = 8
+	}
+	rdb.SetMaxOpenConns(readers)
+	rdb.SetMaxIdleConns(readers)
+	storage.rdb = rdb
 	
 	return storage, nil
 }
 
+// snapshot starts a read transaction on the reader pool. Every statement run
+// through it sees the same committed state of the database.
+func (s *SQLiteStorage) snapshot(ctx context.Context) (*sql.Tx, error) {
+	tx, err := s.rdb.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin read snapshot: %w", err)
+	}
+	return tx, nil
+}
+
 func (s *SQLiteStorage) init() error {
+	// Only takes effect on a fresh file; existing databases are converted by Compact.
+	if _, err := s.db.Exec("PRAGMA auto_vacuum=INCREMENTAL"); err != nil {
+		return fmt.Errorf("failed to set auto_vacuum: %w", err)
+	}
+
 	if _, err := s.db.Exec("PRAGMA journal_mode=WAL"); err != nil {
 		return fmt.Errorf("failed to enable WAL mode: %w", err)
 	}
 	
-	createRecordsTable := `
-	CREATE TABLE IF NOT EXISTS records (
-		id TEXT PRIMARY KEY,
-		timestamp INTEGER NOT NULL,
-		level TEXT NOT NULL,
-		message TEXT NOT NULL,
-		service TEXT,
-		fields TEXT,
-		raw TEXT NOT NULL,
-		created_at INTEGER DEFAULT (strftime('%s', 'now'))
-	);
-	CREATE INDEX IF NOT EXISTS idx_records_timestamp ON records(timestamp);
-	CREATE INDEX IF NOT EXISTS idx_records_timestamp_level ON records(timestamp, level);
-	CREATE INDEX IF NOT EXISTS idx_records_timestamp_service ON records(timestamp, service);
-	CREATE INDEX IF NOT EXISTS idx_records_level ON records(level);
-	CREATE INDEX IF NOT EXISTS idx_records_service ON records(service);
-	CREATE INDEX IF NOT EXISTS idx_records_created_at ON records(created_at);
-	`
-	
-	if _, err := s.db.Exec(createRecordsTable); err != nil {
-		return fmt.Errorf("failed to create records table: %w", err)
+	if err := s.migrate(context.Background()); err != nil {
+		return err
+	}
+
+	if err := s.loadPartitions(context.Background()); err != nil {
+		return err
+	}
+
+	if err := s.loadCompression(context.Background()); err != nil {
+		return err
+	}
+
+	// A bulk import that died before rebuilding its indexes leaves them here.
+	if err := s.restoreRecordIndexes(context.Background()); err != nil {
+		return err
 	}
 	
 	return nil
@@ -93,90 +144,354 @@ func (s *SQLiteStorage) allowedColumn(field string) (string, bool) {
 		return "service", true
 	case "raw":
 		return "raw", true
+	case "source":
+		return "source", true
+	case "line":
+		return "line", true
 	default:
 		return "", false
 	}
 }
 
+// fieldExpr returns the SQL expression for a built-in column or, failing
+// that, for a custom field read from the fields JSON.
+func (s *SQLiteStorage) fieldExpr(field string) (string, bool) {
+	if col, ok := s.allowedColumn(field); ok {
+		return columnExpr(col), true
+	}
+	if field == "" || strings.ContainsAny(field, "\"\\") || strings.IndexFunc(field, unicode.IsControl) >= 0 {
+		return "", false
+	}
+	path := strings.ReplaceAll(`$."`+field+`"`, "'", "''")
+	return "json_extract(" + columnExpr("fields") + ", '" + path + "')", true
+}
+
 func (s *SQLiteStorage) Store(ctx context.Context, records <-chan domain.LogRecord) (*domain.ImportResult, error) {
+	return s.StoreWithOptions(ctx, records, domain.StoreOptions{})
+}
+
+func (s *SQLiteStorage) StoreWithOptions(ctx context.Context, records <-chan domain.LogRecord, opts domain.StoreOptions) (*domain.ImportResult, error) {
+	if opts.Bulk || opts.DeferIndexes {
+		return s.storeBulk(ctx, records, opts)
+	}
+
 	startTime := time.Now()
-	result := &domain.ImportResult{}
-	
-	stmt, err := s.db.PrepareContext(ctx, `
-		INSERT OR REPLACE INTO records (id, timestamp, level, message, service, fields, raw)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
-	`)
+
+	ins, err := s.prepareInsert(ctx, opts.Dedup)
 	if err != nil {
-		return nil, fmt.Errorf("failed to prepare insert statement: %w", err)
+		return nil, err
 	}
-	defer stmt.Close()
+	defer ins.Close()
+
+	result := s.storeBatches(ctx, records, 1000, func(ctx context.Context, batch []domain.LogRecord) (int64, error) {
+		return s.insertBatch(ctx, ins, batch)
+	})
 	
-	batchSize := 1000
+	result.Duration = time.Since(startTime).Milliseconds()
+	return result, nil
+}
+
+// maxRecordErrors caps ImportResult.RecordErrors; Failed keeps the full count.
+const maxRecordErrors = 1000
+
+// batchWriter commits one batch atomically, together with its field catalog
+// changes, and reports how many of its records were duplicates.
+type batchWriter func(ctx context.Context, batch []domain.LogRecord) (duplicates int64, err error)
+
+// storeBatches feeds records to write in batches. Cancelling ctx stops the
+// import; whatever was committed stays and the result is marked Partial.
+func (s *SQLiteStorage) storeBatches(ctx context.Context, records <-chan domain.LogRecord, batchSize int, write batchWriter) *domain.ImportResult {
+	result := &domain.ImportResult{}
 	batch := make([]domain.LogRecord, 0, batchSize)
-	
+
 	for record := range records {
+		if ctx.Err() != nil {
+			break
+		}
 		batch = append(batch, record)
 		result.TotalRecords++
 		
 		if len(batch) >= batchSize {
-			if err := s.insertBatch(ctx, stmt, batch); err != nil {
-				result.Errors = append(result.Errors, err.Error())
-				continue
+			if err := s.commitBatch(ctx, write, batch, result); err != nil {
+				break
 			}
-			result.Processed += int64(len(batch))
-			batch = batch[:0] // Reset batch
+			batch = batch[:0]
 		}
 	}
 	
-	if len(batch) > 0 {
-		if err := s.insertBatch(ctx, stmt, batch); err != nil {
-			result.Errors = append(result.Errors, err.Error())
-		} else {
-			result.Processed += int64(len(batch))
+	if ctx.Err() == nil && len(batch) > 0 {
+		s.commitBatch(ctx, write, batch, result)
+	}
+
+	if err := ctx.Err(); err != nil {
+		result.Partial = true
+		result.Errors = append(result.Errors, fmt.Sprintf("import cancelled: %v", err))
+	}
+
+	return result
+}
+
+// commitBatch writes batch, bisecting it on failure until the records that
+// cannot be stored are isolated and reported in RecordErrors. It only returns
+// an error when ctx is done.
+func (s *SQLiteStorage) commitBatch(ctx context.Context, write batchWriter, batch []domain.LogRecord, result *domain.ImportResult) error {
+	release := s.usePartitions()
+	duplicates, err := write(ctx, batch)
+	release()
+	if err == nil {
+		result.Processed += int64(len(batch))
+		result.Duplicates += duplicates
+		return nil
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	if len(batch) == 1 {
+		result.Failed++
+		if len(result.RecordErrors) < maxRecordErrors {
+			result.RecordErrors = append(result.RecordErrors, domain.RecordError{
+				ID:     batch[0].ID,
+				Source: batch[0].Source,
+				Line:   batch[0].Line,
+				Reason: err.Error(),
+			})
 		}
+		return nil
 	}
-	
-	result.Duration = time.Since(startTime).Milliseconds()
-	return result, nil
+
+	mid := len(batch) / 2
+	if err := s.commitBatch(ctx, write, batch[:mid], result); err != nil {
+		return err
+	}
+	return s.commitBatch(ctx, write, batch[mid:], result)
+}
+
+// prepareBatch creates whatever batch needs before its write transaction
+// starts: missing partitions and the first compression dictionary.
+func (s *SQLiteStorage) prepareBatch(ctx context.Context, batch []domain.LogRecord) error {
+	if err := s.ensurePartitions(ctx, batch); err != nil {
+		return err
+	}
+	return s.ensureDictionary(ctx, batch)
+}
+
+// recordInserter holds the statements for one Store call. Every record is
+// first inserted with OR IGNORE; how a record whose ID already exists is then
+// handled depends on the dedup policy.
+type recordInserter struct {
+	dedup   domain.DedupPolicy
+	insert  *sql.Stmt
+	replace *sql.Stmt
+}
+
+// maxKeepBothCopies bounds the "~n" suffixes tried for one keep-both record.
+const maxKeepBothCopies = 1000
+
+func (s *SQLiteStorage) prepareInsert(ctx context.Context, dedup domain.DedupPolicy) (*recordInserter, error) {
+	switch dedup {
+	case "", domain.DedupReplace:
+		dedup = domain.DedupReplace
+	case domain.DedupSkip, domain.DedupKeepBoth:
+	default:
+		return nil, fmt.Errorf("unsupported dedup policy: %s", dedup)
+	}
+
+	ins := &recordInserter{dedup: dedup}
+	insert, err := s.db.PrepareContext(ctx, insertSQL("INSERT OR IGNORE", "records"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to prepare insert statement: %w", err)
+	}
+
+	replace, err := s.db.PrepareContext(ctx, insertSQL("INSERT OR REPLACE", "records"))
+	if err != nil {
+		insert.Close()
+		return nil, fmt.Errorf("failed to prepare replace statement: %w", err)
+	}
+
+	ins.insert, ins.replace = insert, replace
+	return ins, nil
+}
+
+func insertSQL(verb, table string) string {
+	return verb + " INTO " + table + " (" + recordColumns + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)"
+}
+
+// statements returns the insert and replace statements for table bound to
+// tx. Partition tables come and go, so only the records table is prepared
+// ahead.
+func (ins *recordInserter) statements(ctx context.Context, tx *sql.Tx, cache map[string][2]*sql.Stmt, table string) (*sql.Stmt, *sql.Stmt, error) {
+	if st, ok := cache[table]; ok {
+		return st[0], st[1], nil
+	}
+
+	var st [2]*sql.Stmt
+	if table == "records" {
+		st = [2]*sql.Stmt{tx.StmtContext(ctx, ins.insert), tx.StmtContext(ctx, ins.replace)}
+	} else {
+		var err error
+		if st[0], err = tx.PrepareContext(ctx, insertSQL("INSERT OR IGNORE", table)); err != nil {
+			return nil, nil, fmt.Errorf("failed to prepare insert statement: %w", err)
+		}
+		if st[1], err = tx.PrepareContext(ctx, insertSQL("INSERT OR REPLACE", table)); err != nil {
+			return nil, nil, fmt.Errorf("failed to prepare replace statement: %w", err)
+		}
+	}
+	cache[table] = st
+	return st[0], st[1], nil
+}
+
+func (ins *recordInserter) Close() {
+	ins.insert.Close()
+	ins.replace.Close()
 }
 
-func (s *SQLiteStorage) insertBatch(ctx context.Context, stmt *sql.Stmt, batch []domain.LogRecord) error {
+func (s *SQLiteStorage) insertBatch(ctx context.Context, ins *recordInserter, batch []domain.LogRecord) (int64, error) {
+	if err := s.prepareBatch(ctx, batch); err != nil {
+		return 0, err
+	}
+
 	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
-		return fmt.Errorf("failed to begin transaction: %w", err)
+		return 0, fmt.Errorf("failed to begin transaction: %w", err)
 	}
 	defer tx.Rollback()
-	
+
+	stmts := make(map[string][2]*sql.Stmt)
+	enc := s.newRecordEncoder(tx)
+	var ids *partitionIDs
+	var duplicates int64
+	catalog := newCatalogDelta()
 	for _, record := range batch {
-		fieldsJSON, _ := json.Marshal(record.Fields)
-		ts := record.Timestamp
-		
-		_, err := tx.StmtContext(ctx, stmt).Exec(
-			record.ID,
-			ts,
-			record.Level,
-			record.Message,
-			record.Service,
-			string(fieldsJSON),
-			record.Raw,
-		)
+		table := s.tableFor(record.Timestamp)
+		insert, replace, err := ins.statements(ctx, tx, stmts, table)
+		if err != nil {
+			return 0, err
+		}
+
+		args, err := enc.appendRecordArgs(ctx, make([]interface{}, 0, recordColumnCount), record)
+		if err != nil {
+			return 0, err
+		}
+
+		if table != "records" {
+			if ids == nil {
+				if ids, err = newPartitionIDs(ctx, tx); err != nil {
+					return 0, err
+				}
+			}
+			owner, err := ids.Claim(ctx, record.ID, table)
+			if err != nil {
+				return 0, err
+			}
+			if owner != "" {
+				duplicates++
+				switch ins.dedup {
+				case domain.DedupReplace:
+					if err := catalog.forgetStored(ctx, tx, owner, []string{record.ID}); err != nil {
+						return 0, err
+					}
+					catalog.observed.Observe(record)
+					if err := ids.Move(ctx, record.ID, owner, table); err != nil {
+						return 0, err
+					}
+					if _, err := replace.ExecContext(ctx, args...); err != nil {
+						return 0, fmt.Errorf("failed to replace record %s: %w", record.ID, err)
+					}
+				case domain.DedupKeepBoth:
+					if err := insertCopy(ctx, insert, ids, table, record.ID, args); err != nil {
+						return 0, err
+					}
+					catalog.observed.Observe(record)
+				}
+				continue
+			}
+		}
+
+		res, err := insert.ExecContext(ctx, args...)
 		if err != nil {
-			return fmt.Errorf("failed to insert record %s: %w", record.ID, err)
+			return 0, fmt.Errorf("failed to insert record %s: %w", record.ID, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			catalog.observed.Observe(record)
+			continue
+		}
+
+		duplicates++
+		switch ins.dedup {
+		case domain.DedupReplace:
+			if err := catalog.forgetStored(ctx, tx, table, []string{record.ID}); err != nil {
+				return 0, err
+			}
+			catalog.observed.Observe(record)
+			if _, err := replace.ExecContext(ctx, args...); err != nil {
+				return 0, fmt.Errorf("failed to replace record %s: %w", record.ID, err)
+			}
+		case domain.DedupKeepBoth:
+			if err := insertCopy(ctx, insert, nil, table, record.ID, args); err != nil {
+				return 0, err
+			}
+			catalog.observed.Observe(record)
 		}
 	}
-	
-	return tx.Commit()
+
+	if err := enc.flush(ctx); err != nil {
+		return 0, err
+	}
+	if err := s.applyCatalog(ctx, tx, catalog); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	enc.committed(duplicates)
+	return duplicates, nil
+}
+
+// insertCopy stores args in table under the first free "id~n" for n from 2.
+// ids is nil unless table is a partition.
+func insertCopy(ctx context.Context, insert *sql.Stmt, ids *partitionIDs, table, id string, args []interface{}) error {
+	for copyNum := 2; copyNum <= maxKeepBothCopies; copyNum++ {
+		copyID := fmt.Sprintf("%s~%d", id, copyNum)
+		if ids != nil {
+			owner, err := ids.Claim(ctx, copyID, table)
+			if err != nil {
+				return err
+			}
+			if owner != "" {
+				continue
+			}
+		}
+		args[0] = copyID
+		res, err := insert.ExecContext(ctx, args...)
+		if err != nil {
+			return fmt.Errorf("failed to insert record %s: %w", id, err)
+		}
+		if n, _ := res.RowsAffected(); n > 0 {
+			return nil
+		}
+	}
+	return fmt.Errorf("record %s already has %d copies", id, maxKeepBothCopies)
 }
 
 func (s *SQLiteStorage) Query(ctx context.Context, query domain.Query) (*domain.QueryResult, error) {
+	release := s.usePartitions()
+	defer release()
+
 	startTime := time.Now()
 	
-	sqlQuery, args, err := s.buildSQLQuery(query)
+	sqlQuery, args, plan, err := s.buildSQLQuery(query)
 	if err != nil {
 		return nil, fmt.Errorf("failed to build SQL query: %w", err)
 	}
 	
-	rows, err := s.db.QueryContext(ctx, sqlQuery, args...)
+	tx, err := s.snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	
+	rows, err := tx.QueryContext(ctx, sqlQuery, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute query: %w", err)
 	}
@@ -184,78 +499,67 @@ func (s *SQLiteStorage) Query(ctx context.Context, query domain.Query) (*domain.
 	
 	records := make([]domain.LogRecord, 0)
 	for rows.Next() {
-		var record domain.LogRecord
-		var ts int64
-		var fieldsJSON string
-		
-		err := rows.Scan(
-			&record.ID,
-			&ts,
-			&record.Level,
-			&record.Message,
-			&record.Service,
-			&fieldsJSON,
-			&record.Raw,
-		)
+		record, err := scanRecord(rows)
 		if err != nil {
 			log.Printf("Failed to scan row: %v", err)
 			continue
 		}
 		
-		record.Timestamp = ts
-		if fieldsJSON != "" {
-			if err := json.Unmarshal([]byte(fieldsJSON), &record.Fields); err != nil {
-				log.Printf("Failed to unmarshal fields: %v", err)
-				record.Fields = make(map[string]interface{})
-			}
-		} else {
-			record.Fields = make(map[string]interface{})
-		}
-		
 		records = append(records, record)
 	}
+	rows.Close()
+
+	hasMore := query.Limit > 0 && len(records) > query.Limit
+	if hasMore {
+		records = records[:query.Limit]
+	}
+	back := plan.cursor != nil && plan.cursor.Back
+	if back {
+		for i, j := 0, len(records)-1; i < j; i, j = i+1, j-1 {
+			records[i], records[j] = records[j], records[i]
+		}
+	}
 	
-	total, err := s.getTotalCount(ctx, query)
-	if err != nil {
+	result := &domain.QueryResult{Records: records}
+	if err := s.countMatching(ctx, tx, query, result); err != nil {
 		log.Printf("Failed to get total count: %v", err)
-		total = int64(len(records))
+		result.Total = int64(len(records))
+		result.TotalLowerBound = true
 	}
-	
-	return &domain.QueryResult{
-		Records: records,
-		Total:   total,
-		Took:    time.Since(startTime).Milliseconds(),
-	}, nil
+
+	if query.Limit > 0 && len(records) > 0 {
+		first, last := records[0], records[len(records)-1]
+		if (back && hasMore) || (!back && (query.Cursor != "" || query.Offset > 0)) {
+			result.PrevCursor = encodeCursor(plan.sort, first, true)
+		}
+		if back || hasMore {
+			result.NextCursor = encodeCursor(plan.sort, last, false)
+		}
+	}
+
+	result.Took = time.Since(startTime).Milliseconds()
+	return result, nil
 }
 
 func (s *SQLiteStorage) Timeline(ctx context.Context, filters []domain.FilterCondition, bucketMs int64) ([]domain.TimelinePoint, error) {
+	release := s.usePartitions()
+	defer release()
+
 	if bucketMs <= 0 {
 		return nil, fmt.Errorf("bucketMs must be > 0")
 	}
 
-	var whereClauses []string
-	var whereArgs []interface{}
-
-	for _, filter := range filters {
-		clause, clauseArgs, err := s.buildFilterClause(filter)
-		if err != nil {
-			return nil, err
-		}
-		if clause != "" {
-			whereClauses = append(whereClauses, clause)
-			whereArgs = append(whereArgs, clauseArgs...)
-		}
+	where, whereArgs, err := s.buildWhere(filters)
+	if err != nil {
+		return nil, err
 	}
 
-	q := "SELECT (CAST(timestamp / ? AS INTEGER) * ?) AS bucket_start, COUNT(*) AS cnt FROM records"
-	args := []interface{}{bucketMs, bucketMs}
-	if len(whereClauses) > 0 {
-		q += " WHERE " + strings.Join(whereClauses, " AND ")
-		args = append(args, whereArgs...)
-	}
+	bucket, args := bucketExpr(bucketMs)
+	q := "SELECT " + bucket + " AS bucket_start, COUNT(*) AS cnt FROM " + s.recordsFrom(filters) + where
+	args = append(args, whereArgs...)
 	q += " GROUP BY bucket_start ORDER BY bucket_start ASC"
 
-	rows, err := s.db.QueryContext(ctx, q, args...)
+	rows, err := s.rdb.QueryContext(ctx, q, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to execute timeline query: %w", err)
 	}
@@ -277,11 +581,62 @@ func (s *SQLiteStorage) Timeline(ctx context.Context, filters []domain.FilterCon
 	return points, nil
 }
 
-func (s *SQLiteStorage) buildSQLQuery(query domain.Query) (string, []interface{}, error) {
+type queryPlan struct {
+	sort   pageSort
+	cursor *pageCursor
+}
+
+// buildSQLQuery fetches one row past Limit so Query can tell whether another
+// page follows without a separate count.
+func (s *SQLiteStorage) buildSQLQuery(query domain.Query) (string, []interface{}, queryPlan, error) {
+	var plan queryPlan
+
+	where, args, err := s.buildWhere(query.Filters)
+	if err != nil {
+		return "", nil, plan, err
+	}
+
+	plan.sort, err = s.resolveSort(query)
+	if err != nil {
+		return "", nil, plan, err
+	}
+	plan.cursor, err = decodeCursor(query.Cursor, plan.sort)
+	if err != nil {
+		return "", nil, plan, err
+	}
+
+	desc := plan.sort.desc
+	if plan.cursor != nil {
+		if plan.cursor.Back {
+			desc = !desc
+		}
+		clause, clauseArgs := keysetClause(plan.sort.col, desc, plan.cursor)
+		if where == "" {
+			where = " WHERE " + clause
+		} else {
+			where += " AND " + clause
+		}
+		args = append(args, clauseArgs...)
+	}
+	
+	baseQuery := "SELECT " + recordSelect + " FROM " + s.recordsFrom(query.Filters) + where
+	baseQuery += orderClause(plan.sort.col, desc)
+	
+	if query.Limit > 0 {
+		baseQuery += fmt.Sprintf(" LIMIT %d", query.Limit+1)
+		if query.Offset > 0 && plan.cursor == nil {
+			baseQuery += fmt.Sprintf(" OFFSET %d", query.Offset)
+		}
+	}
+	
+	return baseQuery, args, plan, nil
+}
+
+func (s *SQLiteStorage) buildWhere(filters []domain.FilterCondition) (string, []interface{}, error) {
 	var whereClauses []string
 	var args []interface{}
-	
-	for _, filter := range query.Filters {
+
+	for _, filter := range filters {
 		clause, clauseArgs, err := s.buildFilterClause(filter)
 		if err != nil {
 			return "", nil, err
@@ -291,39 +646,19 @@ func (s *SQLiteStorage) buildSQLQuery(query domain.Query) (string, []interface{}
 			args = append(args, clauseArgs...)
 		}
 	}
-	
-	baseQuery := "SELECT id, timestamp, level, message, service, fields, raw FROM records"
-	
-	if len(whereClauses) > 0 {
-		baseQuery += " WHERE " + strings.Join(whereClauses, " AND ")
-	}
-	
-	if query.SortBy != "" {
-		col, ok := s.allowedColumn(query.SortBy)
-		if !ok {
-			return "", nil, fmt.Errorf("invalid sort field: %s", query.SortBy)
-		}
-		direction := "ASC"
-		if query.SortDesc {
-			direction = "DESC"
-		}
-		baseQuery += fmt.Sprintf(" ORDER BY %s %s", col, direction)
-	} else {
-		baseQuery += " ORDER BY timestamp DESC"
-	}
-	
-	if query.Limit > 0 {
-		baseQuery += fmt.Sprintf(" LIMIT %d", query.Limit)
-		if query.Offset > 0 {
-			baseQuery += fmt.Sprintf(" OFFSET %d", query.Offset)
-		}
+
+	if len(whereClauses) == 0 {
+		return "", nil, nil
 	}
-	
-	return baseQuery, args, nil
+	return " WHERE " + strings.Join(whereClauses, " AND "), args, nil
 }
 
 func (s *SQLiteStorage) buildFilterClause(filter domain.FilterCondition) (string, []interface{}, error) {
-	col, ok := s.allowedColumn(filter.Field)
+	if filter.IsGroup() {
+		return s.buildGroupClause(filter)
+	}
+
+	col, ok := s.fieldExpr(filter.Field)
 	if !ok {
 		return "", nil, fmt.Errorf("invalid filter field: %s", filter.Field)
 	}
@@ -332,7 +667,8 @@ func (s *SQLiteStorage) buildFilterClause(filter domain.FilterCondition) (string
 	case domain.FilterEquality:
 		return fmt.Sprintf("%s = ?", col), []interface{}{filter.Value}, nil
 	case domain.FilterExclusion:
-		return fmt.Sprintf("%s != ?", col), []interface{}{filter.Value}, nil
+		// A record without the field is not equal to the value, as in ExclusionFilter.
+		return fmt.Sprintf("COALESCE(%s != ?, 1)", col), []interface{}{filter.Value}, nil
 	case domain.FilterContains:
 		escaped := strings.ReplaceAll(fmt.Sprintf("%v", filter.Value), "%", "\\%")
 		escaped = strings.ReplaceAll(escaped, "_", "\\_")
@@ -357,72 +693,187 @@ func (s *SQLiteStorage) buildFilterClause(filter domain.FilterCondition) (string
 	}
 }
 
-func (s *SQLiteStorage) getTotalCount(ctx context.Context, query domain.Query) (int64, error) {
-	var whereClauses []string
+// buildGroupClause renders an and/or/not node. Empty groups follow the
+// in-memory filters: AND of nothing matches everything, OR of nothing matches
+// nothing. NOT treats a NULL comparison as false before negating it, as
+// FilterEngine does for missing values.
+func (s *SQLiteStorage) buildGroupClause(filter domain.FilterCondition) (string, []interface{}, error) {
+	if filter.Type == domain.FilterNot && len(filter.Children) != 1 {
+		return "", nil, fmt.Errorf("not filter requires exactly one child, got %d", len(filter.Children))
+	}
+
+	var clauses []string
 	var args []interface{}
-	
-	for _, filter := range query.Filters {
-		clause, clauseArgs, err := s.buildFilterClause(filter)
+	for _, child := range filter.Children {
+		clause, clauseArgs, err := s.buildFilterClause(child)
 		if err != nil {
-			return 0, err
+			return "", nil, err
 		}
-		if clause != "" {
-			whereClauses = append(whereClauses, clause)
-			args = append(args, clauseArgs...)
+		if clause == "" {
+			clause = "1"
 		}
+		clauses = append(clauses, clause)
+		args = append(args, clauseArgs...)
 	}
-	
-	countQuery := "SELECT COUNT(*) FROM records"
-	if len(whereClauses) > 0 {
-		countQuery += " WHERE " + strings.Join(whereClauses, " AND ")
+
+	switch filter.Type {
+	case domain.FilterNot:
+		return fmt.Sprintf("NOT COALESCE(%s, 0)", clauses[0]), args, nil
+	case domain.FilterOr:
+		if len(clauses) == 0 {
+			return "0", nil, nil
+		}
+		return "(" + strings.Join(clauses, " OR ") + ")", args, nil
+	default:
+		if len(clauses) == 0 {
+			return "1", nil, nil
+		}
+		return "(" + strings.Join(clauses, " AND ") + ")", args, nil
+	}
+}
+
+func (s *SQLiteStorage) getTotalCount(ctx context.Context, q queryer, query domain.Query) (int64, error) {
+	where, args, err := s.buildWhere(query.Filters)
+	if err != nil {
+		return 0, err
 	}
 	
+	countQuery := "SELECT COUNT(*) FROM " + s.recordsFrom(query.Filters) + where
+	
 	var count int64
-	err := s.db.QueryRowContext(ctx, countQuery, args...).Scan(&count)
+	err = q.QueryRowContext(ctx, countQuery, args...).Scan(&count)
 	return count, err
 }
 
+// Scan calls fn for every record matching filters, in no particular order,
+// from a single read snapshot. It stops at the first error fn returns.
+func (s *SQLiteStorage) Scan(ctx context.Context, filters []domain.FilterCondition, fn func(domain.LogRecord) error) error {
+	release := s.usePartitions()
+	defer release()
+
+	where, args, err := s.buildWhere(filters)
+	if err != nil {
+		return err
+	}
+
+	tx, err := s.snapshot(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.QueryContext(ctx, "SELECT "+recordSelect+" FROM "+s.recordsFrom(filters)+where, args...)
+	if err != nil {
+		return fmt.Errorf("failed to scan records: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		record, err := scanRecord(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan record: %w", err)
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
 func (s *SQLiteStorage) GetRecord(ctx context.Context, id string) (*domain.LogRecord, error) {
-	query := `
-		SELECT id, timestamp, level, message, service, fields, raw
-		FROM records WHERE id = ?
-	`
+	release := s.usePartitions()
+	defer release()
+
+	query := "SELECT " + recordSelect + " FROM " + s.recordsFrom(nil) + " WHERE id = ?"
 	
+	record, err := scanRecord(s.rdb.QueryRowContext(ctx, query, id))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get record: %w", err)
+	}
+	
+	return &record, nil
+}
+
+const (
+	recordColumns     = "id, timestamp, level, message, service, fields, raw, source, line"
+	recordColumnCount = 9
+)
+
+var recordSelect = "id, timestamp, level, message, service, " + columnExpr("fields") + ", " + columnExpr("raw") + ", source, line"
+
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanRecord(row rowScanner) (domain.LogRecord, error) {
 	var record domain.LogRecord
-	var ts int64
 	var fieldsJSON string
-	
-	err := s.db.QueryRowContext(ctx, query, id).Scan(
+
+	err := row.Scan(
 		&record.ID,
-		&ts,
+		&record.Timestamp,
 		&record.Level,
 		&record.Message,
 		&record.Service,
 		&fieldsJSON,
 		&record.Raw,
+		&record.Source,
+		&record.Line,
 	)
-	
 	if err != nil {
-		return nil, fmt.Errorf("failed to get record: %w", err)
+		return record, err
 	}
-	
-	record.Timestamp = ts
+
 	if fieldsJSON != "" {
 		if err := json.Unmarshal([]byte(fieldsJSON), &record.Fields); err != nil {
 			log.Printf("Failed to unmarshal fields: %v", err)
 			record.Fields = make(map[string]interface{})
 		}
-	} else {
+	}
+	if record.Fields == nil {
 		record.Fields = make(map[string]interface{})
 	}
-	
-	return &record, nil
+
+	return record, nil
 }
 
 func (s *SQLiteStorage) Close() error {
+	if s.rdb != nil {
+		s.rdb.Close()
+	}
 	return s.db.Close()
 }
 
+// regexpCache holds compiled REGEXP patterns; the function is called once
+// per row, usually with the same pattern.
+var (
+	regexpCache   = make(map[string]*regexp.Regexp)
+	regexpCacheMu sync.RWMutex
+)
+
+const maxCachedRegexps = 256
+
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	regexpCacheMu.RLock()
+	re, ok := regexpCache[pattern]
+	regexpCacheMu.RUnlock()
+	if ok {
+		return re, nil
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+	regexpCacheMu.Lock()
+	if len(regexpCache) >= maxCachedRegexps {
+		clear(regexpCache)
+	}
+	regexpCache[pattern] = re
+	regexpCacheMu.Unlock()
+	return re, nil
+}
+
 func registerRegexpFunc() error {
 	regexpOnce.Do(func() {
 		regexpErr = sqlite3.RegisterDeterministicScalarFunction("regexp", 2,
@@ -434,15 +885,17 @@ func registerRegexpFunc() error {
 				if !ok {
 					return nil, fmt.Errorf("regexp: first argument must be a string")
 				}
-				text, ok := args[1].(string)
-				if !ok {
-					return nil, fmt.Errorf("regexp: second argument must be a string")
+				if args[1] == nil {
+					return int64(0), nil
 				}
-				matched, err := regexp.MatchString(pattern, text)
+				// Non-text values (numeric custom fields) match as the empty
+				// string, like RegexpFilter.
+				text, _ := args[1].(string)
+				re, err := compileRegexp(pattern)
 				if err != nil {
 					return nil, fmt.Errorf("regexp: %w", err)
 				}
-				if matched {
+				if re.MatchString(text) {
 					return int64(1), nil
 				}
 				return int64(0), nil
@@ -453,13 +906,19 @@ func registerRegexpFunc() error {
 }
 
 func (s *SQLiteStorage) GetTotalCount(ctx context.Context) (int64, error) {
+	release := s.usePartitions()
+	defer release()
+
 	var count int64
-	err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM records").Scan(&count)
+	err := s.rdb.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+s.recordsFrom(nil)).Scan(&count)
 	return count, err
 }
 
 func (s *SQLiteStorage) GetLevelCounts(ctx context.Context) (map[string]int64, error) {
-	rows, err := s.db.QueryContext(ctx, "SELECT level, COUNT(*) FROM records GROUP BY level")
+	release := s.usePartitions()
+	defer release()
+
+	rows, err := s.rdb.QueryContext(ctx, "SELECT level, COUNT(*) FROM "+s.recordsFrom(nil)+" GROUP BY level")
 	if err != nil {
 		return nil, err
 	}
@@ -478,71 +937,36 @@ func (s *SQLiteStorage) GetLevelCounts(ctx context.Context) (map[string]int64, e
 }
 
 func (s *SQLiteStorage) Aggregate(ctx context.Context, filters []domain.FilterCondition, aggs []domain.Aggregation) (map[string]interface{}, error) {
-	var whereClauses []string
-	var args []interface{}
-	for _, filter := range filters {
-		clause, clauseArgs, err := s.buildFilterClause(filter)
-		if err != nil {
-			return nil, err
-		}
-		if clause != "" {
-			whereClauses = append(whereClauses, clause)
-			args = append(args, clauseArgs...)
-		}
-	}
-
-	where := ""
-	if len(whereClauses) > 0 {
-		where = " WHERE " + strings.Join(whereClauses, " AND ")
-	}
+	release := s.usePartitions()
+	defer release()
 
 	results := make(map[string]interface{})
-	for _, agg := range aggs {
-		alias := agg.Alias
-		if alias == "" {
-			alias = agg.Function
-			if agg.Field != "" {
-				alias += "_" + agg.Field
-			}
-		}
-
-		field := agg.Field
-		if field == "" || field == "*" {
-			field = "*"
-		} else {
-			col, ok := s.allowedColumn(field)
-			if !ok {
-				return nil, fmt.Errorf("invalid aggregation field: %s", field)
-			}
-			field = col
-		}
+	if len(aggs) == 0 {
+		return results, nil
+	}
 
-		var sqlExpr string
-		switch agg.Function {
-		case "count":
-			if field == "*" {
-				sqlExpr = "COUNT(*)"
-			} else {
-				sqlExpr = "COUNT(DISTINCT " + field + ")"
-			}
-		case "avg":
-			sqlExpr = "AVG(CAST(" + field + " AS REAL))"
-		case "sum":
-			sqlExpr = "SUM(CAST(" + field + " AS REAL))"
-		case "min":
-			sqlExpr = "MIN(" + field + ")"
-		case "max":
-			sqlExpr = "MAX(" + field + ")"
-		default:
-			return nil, fmt.Errorf("unsupported aggregation function: %s", agg.Function)
-		}
+	where, args, err := s.buildWhere(filters)
+	if err != nil {
+		return nil, err
+	}
+	exprs, columns, err := s.aggregationSelect(aggs)
+	if err != nil {
+		return nil, err
+	}
 
-		q := "SELECT " + sqlExpr + " FROM records" + where
-		var val interface{}
-		if err := s.db.QueryRowContext(ctx, q, args...).Scan(&val); err != nil {
+	q := "SELECT " + strings.Join(exprs, ", ") + " FROM " + s.recordsFrom(filters) + where
+	values := make([]interface{}, len(aggs))
+	ptrs := make([]interface{}, len(aggs))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	if err := s.rdb.QueryRowContext(ctx, q, args...).Scan(ptrs...); err != nil {
+		return nil, err
+	}
+	for i, alias := range columns.aliases {
+		if results[alias], err = columns.value(alias, values[i]); err != nil {
 			return nil, err
 		}
-		results[alias] = val
 	}
 
 	return results, nil
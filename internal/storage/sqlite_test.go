@@ -2,9 +2,13 @@ package storage
 
 import (
 	"context"
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"LogLens/internal/domain"
 )
@@ -44,6 +48,21 @@ func storeRecords(t *testing.T, storage *SQLiteStorage, records []domain.LogReco
 	return result
 }
 
+func newRecords(n int) []domain.LogRecord {
+	records := make([]domain.LogRecord, n)
+	for i := range records {
+		records[i] = domain.LogRecord{
+			ID:        fmt.Sprintf("rec_%d", i),
+			Timestamp: int64(i * 1000),
+			Level:     "INFO",
+			Message:   fmt.Sprintf("message %d", i),
+			Fields:    make(map[string]interface{}),
+			Raw:       fmt.Sprintf("raw %d", i),
+		}
+	}
+	return records
+}
+
 func TestSQLiteStorage_StoreAndQuery(t *testing.T) {
 	storage, cleanup := newTestStorage(t)
 	defer cleanup()
@@ -195,3 +214,168 @@ func TestSQLiteStorage_Close(t *testing.T) {
 		t.Errorf("Close failed: %v", err)
 	}
 }
+
+func TestSQLiteStorage_StoreIsolatesBadRecords(t *testing.T) {
+	for _, opts := range []domain.StoreOptions{{}, {Bulk: true}} {
+		t.Run(fmt.Sprintf("bulk=%v", opts.Bulk), func(t *testing.T) {
+			storage, cleanup := newTestStorage(t)
+			defer cleanup()
+
+			if _, err := storage.db.Exec(`CREATE TRIGGER reject_poison BEFORE INSERT ON records
+				WHEN NEW.message = 'poison' BEGIN SELECT RAISE(ABORT, 'poisoned record'); END`); err != nil {
+				t.Fatalf("failed to create trigger: %v", err)
+			}
+
+			records := newRecords(2500)
+			records[10].Message = "poison"
+			records[1700].Message = "poison"
+			records[2301].Fields = map[string]interface{}{"ratio": math.NaN()}
+			records[2301].Line = 2302
+
+			ch := make(chan domain.LogRecord, len(records))
+			for _, r := range records {
+				ch <- r
+			}
+			close(ch)
+			result, err := storage.StoreWithOptions(context.Background(), ch, opts)
+			if err != nil {
+				t.Fatalf("StoreWithOptions failed: %v", err)
+			}
+
+			if result.Processed != 2497 || result.Failed != 3 {
+				t.Fatalf("expected 2497 processed and 3 failed, got %d and %d", result.Processed, result.Failed)
+			}
+			if result.Partial {
+				t.Error("complete import should not be partial")
+			}
+
+			failed := make(map[string]domain.RecordError)
+			for _, re := range result.RecordErrors {
+				failed[re.ID] = re
+			}
+			for _, id := range []string{"rec_10", "rec_1700", "rec_2301"} {
+				if _, ok := failed[id]; !ok {
+					t.Errorf("expected %s in record errors, got %+v", id, result.RecordErrors)
+				}
+			}
+			if re := failed["rec_2301"]; re.Line != 2302 || !strings.Contains(re.Reason, "NaN") {
+				t.Errorf("expected line and reason for NaN record, got %+v", re)
+			}
+			if re := failed["rec_10"]; !strings.Contains(re.Reason, "poisoned record") {
+				t.Errorf("expected trigger message in reason, got %q", re.Reason)
+			}
+
+			count, err := storage.GetTotalCount(context.Background())
+			if err != nil {
+				t.Fatalf("GetTotalCount failed: %v", err)
+			}
+			if count != 2497 {
+				t.Errorf("expected 2497 stored records, got %d", count)
+			}
+		})
+	}
+}
+
+func TestSQLiteStorage_StoreCancelled(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stop := make(chan struct{})
+	defer close(stop)
+
+	done := make(chan *domain.ImportResult)
+	go func() {
+		result, err := storage.Store(ctx, generateRecords(1_000_000, stop))
+		if err != nil {
+			t.Errorf("Store failed: %v", err)
+		}
+		done <- result
+	}()
+
+	for {
+		count, err := storage.GetTotalCount(context.Background())
+		if err != nil {
+			t.Fatalf("GetTotalCount failed: %v", err)
+		}
+		if count > 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+
+	var result *domain.ImportResult
+	select {
+	case result = <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("Store did not stop after cancellation")
+	}
+	if result == nil {
+		t.Fatal("expected a partial result")
+	}
+	if !result.Partial {
+		t.Error("expected result to be flagged partial")
+	}
+
+	count, err := storage.GetTotalCount(context.Background())
+	if err != nil {
+		t.Fatalf("GetTotalCount failed: %v", err)
+	}
+	if count != result.Processed {
+		t.Errorf("processed %d does not match committed rows %d", result.Processed, count)
+	}
+	if result.Processed >= 1_000_000 {
+		t.Error("import was not interrupted")
+	}
+}
+
+func TestSQLiteStorage_DedupPolicies(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	ctx := context.Background()
+
+	original := domain.LogRecord{ID: "a", Timestamp: 1000, Level: "INFO", Message: "first", Source: "a.log", Raw: "r"}
+	storeRecords(t, storage, []domain.LogRecord{original})
+
+	for _, tt := range []struct {
+		dedup   domain.DedupPolicy
+		stored  string
+		message string
+	}{
+		{domain.DedupSkip, "skipped", "first"},
+		{domain.DedupReplace, "second", "second"},
+		{domain.DedupKeepBoth, "copied", "second"},
+	} {
+		again := original
+		again.Message = tt.stored
+		result := storeWithOptions(t, storage, []domain.LogRecord{again, again}, domain.StoreOptions{Dedup: tt.dedup})
+		if result.Duplicates != 2 {
+			t.Errorf("%s: expected 2 duplicates, got %d", tt.dedup, result.Duplicates)
+		}
+		record, err := storage.GetRecord(ctx, "a")
+		if err != nil {
+			t.Fatalf("%s: GetRecord failed: %v", tt.dedup, err)
+		}
+		if record.Message != tt.message {
+			t.Errorf("%s: expected message %q, got %q", tt.dedup, tt.message, record.Message)
+		}
+	}
+	if total, _ := storage.GetTotalCount(ctx); total != 3 {
+		t.Errorf("expected the original and two keep-both copies, got %d records", total)
+	}
+
+	// Once every copy suffix is taken, keep-both reports the record as failed.
+	copies := make([]domain.LogRecord, 0, maxKeepBothCopies)
+	for n := 4; n <= maxKeepBothCopies; n++ {
+		c := original
+		c.ID = fmt.Sprintf("a~%d", n)
+		copies = append(copies, c)
+	}
+	storeRecords(t, storage, copies)
+	result := storeWithOptions(t, storage, []domain.LogRecord{original}, domain.StoreOptions{Dedup: domain.DedupKeepBoth})
+	if result.Failed != 1 || len(result.RecordErrors) != 1 || !strings.Contains(result.RecordErrors[0].Reason, "copies") {
+		t.Errorf("expected the record to fail once copies run out, got %+v", result)
+	}
+}
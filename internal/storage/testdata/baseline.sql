@@ -0,0 +1,21 @@
+-- Schema of the first release, before schema_version existed. Frozen: do not
+-- edit to match later migrations.
+CREATE TABLE records (
+	id TEXT PRIMARY KEY,
+	timestamp INTEGER NOT NULL,
+	level TEXT NOT NULL,
+	message TEXT NOT NULL,
+	service TEXT,
+	fields TEXT,
+	raw TEXT NOT NULL,
+	created_at INTEGER DEFAULT (strftime('%s', 'now'))
+);
+CREATE INDEX idx_records_timestamp ON records(timestamp);
+CREATE INDEX idx_records_timestamp_level ON records(timestamp, level);
+CREATE INDEX idx_records_timestamp_service ON records(timestamp, service);
+CREATE INDEX idx_records_level ON records(level);
+CREATE INDEX idx_records_service ON records(service);
+CREATE INDEX idx_records_created_at ON records(created_at);
+
+INSERT INTO records (id, timestamp, level, message, service, fields, raw)
+VALUES ('legacy-1', 1000, 'ERROR', 'boom', 'api', '{"k":"v"}', 'raw line');
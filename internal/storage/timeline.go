@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"LogLens/internal/domain"
+)
+
+const (
+	defaultTimelineSeries = 10
+	maxTimelineBuckets    = 10000
+)
+
+// bucketExpr returns the SQL for the start of the bucketMs wide bucket
+// holding a record's timestamp, and its arguments.
+func bucketExpr(bucketMs int64) (string, []interface{}) {
+	return "(CAST(timestamp / ? AS INTEGER) * ?)", []interface{}{bucketMs, bucketMs}
+}
+
+func bucketStart(ts, bucketMs int64) int64 {
+	return ts / bucketMs * bucketMs
+}
+
+// timelineFilters adds the request's [From, To) range to its filters.
+func timelineFilters(req domain.TimelineRequest) []domain.FilterCondition {
+	filters := append([]domain.FilterCondition(nil), req.Filters...)
+	if req.From != 0 {
+		filters = append(filters, domain.FilterCondition{Type: domain.FilterRange, Field: "timestamp", Operator: "gte", Value: req.From})
+	}
+	if req.To != 0 {
+		filters = append(filters, domain.FilterCondition{Type: domain.FilterRange, Field: "timestamp", Operator: "lt", Value: req.To})
+	}
+	return filters
+}
+
+// timelineMetric is a resolved TimelineMetric. zero marks metrics that are
+// zero rather than undefined over no records.
+type timelineMetric struct {
+	expr string
+	name string
+	zero bool
+	rate bool
+}
+
+func (s *SQLiteStorage) timelineMetrics(req domain.TimelineRequest) ([]timelineMetric, error) {
+	metrics := req.Metrics
+	if len(metrics) == 0 {
+		metrics = []domain.TimelineMetric{{Aggregation: domain.Aggregation{Function: "count"}}}
+	}
+	resolved := make([]timelineMetric, len(metrics))
+	seen := make(map[string]bool)
+	for i, m := range metrics {
+		if m.Function == "histogram" {
+			return nil, fmt.Errorf("histogram is not supported as a timeline metric")
+		}
+		expr, err := s.aggregationExpr(m.Aggregation)
+		if err != nil {
+			return nil, err
+		}
+		name := aggregationAlias(m.Aggregation)
+		if m.Rate && m.Alias == "" {
+			name += "_per_sec"
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("duplicate timeline metric: %s", name)
+		}
+		seen[name] = true
+		resolved[i] = timelineMetric{
+			expr: fmt.Sprintf("%s AS m%d", expr, i),
+			name: name,
+			zero: m.Function == "count" || m.Function == "sum" || m.Function == "cardinality",
+			rate: m.Rate,
+		}
+	}
+	return resolved, nil
+}
+
+// timelineBucketing resolves the request's interval. Automatic sizing
+// needs the time range, taken from the filters or else the matching data.
+func (s *SQLiteStorage) timelineBucketing(ctx context.Context, req domain.TimelineRequest, filters []domain.FilterCondition) (bucketing, error) {
+	switch {
+	case req.Interval != "" && req.Interval != "auto":
+		return parseBucketing(req.Interval, req.Timezone)
+	case req.Interval == "" && req.BucketMs > 0:
+		return parseBucketing(strconv.FormatInt(req.BucketMs, 10)+"ms", req.Timezone)
+	case req.BucketMs < 0:
+		return bucketing{}, fmt.Errorf("bucketMs must be > 0")
+	}
+
+	points := req.Points
+	if points <= 0 {
+		points = defaultTimelinePoints
+	}
+	points = min(points, maxTimelineBuckets)
+
+	lo, hi := timestampBounds(filters)
+	if lo == math.MinInt64 || hi == math.MaxInt64 {
+		where, args, err := s.buildWhere(filters)
+		if err != nil {
+			return bucketing{}, err
+		}
+		var dataLo, dataHi sql.NullInt64
+		q := "SELECT MIN(timestamp), MAX(timestamp) FROM " + s.recordsFrom(filters) + where
+		if err := s.rdb.QueryRowContext(ctx, q, args...).Scan(&dataLo, &dataHi); err != nil {
+			return bucketing{}, fmt.Errorf("failed to read timeline range: %w", err)
+		}
+		if lo == math.MinInt64 {
+			lo = dataLo.Int64
+		}
+		if hi == math.MaxInt64 {
+			hi = max(dataHi.Int64, lo)
+		}
+	}
+	return autoBucketing(lo, hi, points, req.Timezone)
+}
+
+type timelineSeries struct {
+	value  interface{}
+	other  bool
+	total  int64
+	counts map[int64]int64
+	values map[int64][]interface{}
+}
+
+// TimelineSeries computes req.Metrics per bucket, split into one series per
+// value of req.SplitBy. The req.TopK values with the most records get their
+// own series, largest first, followed by an "other" series for the remaining
+// and missing values. Every series has a point for each bucket between the
+// request's time range, or the data's if unbounded; empty buckets count zero
+// records.
+func (s *SQLiteStorage) TimelineSeries(ctx context.Context, req domain.TimelineRequest) (*domain.TimelineResult, error) {
+	release := s.usePartitions()
+	defer release()
+
+	topK := req.TopK
+	if topK <= 0 {
+		topK = defaultTimelineSeries
+	}
+	filters := timelineFilters(req)
+
+	where, whereArgs, err := s.buildWhere(filters)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := s.timelineMetrics(req)
+	if err != nil {
+		return nil, err
+	}
+	bk, err := s.timelineBucketing(ctx, req, filters)
+	if err != nil {
+		return nil, err
+	}
+	bucket, bucketArgs := bk.expr()
+	selects := make([]string, len(metrics))
+	for i, m := range metrics {
+		selects[i] = m.expr
+	}
+
+	var q string
+	var args []interface{}
+	if req.SplitBy == "" {
+		q = "SELECT " + bucket + ", NULL, 0, COUNT(*), " + strings.Join(selects, ", ") +
+			" FROM " + s.recordsFrom(filters) + where + " GROUP BY 1"
+		args = append(bucketArgs, whereArgs...)
+	} else {
+		split, ok := s.fieldExpr(req.SplitBy)
+		if !ok {
+			return nil, fmt.Errorf("invalid split field: %s", req.SplitBy)
+		}
+		// Metrics such as percentiles cannot be merged across values, so
+		// the top values are found first and everything else is grouped
+		// into a NULL split value.
+		q = `WITH top AS MATERIALIZED (
+				SELECT ` + split + ` AS top_value, COUNT(*) AS top_count FROM ` + s.recordsFrom(filters) + where + `
+				GROUP BY 1 HAVING top_value IS NOT NULL AND top_value != '' ORDER BY 2 DESC, 1 LIMIT ?
+			)
+			SELECT ` + bucket + `, top_value, top_count IS NULL, COUNT(*), ` + strings.Join(selects, ", ") + `
+			FROM ` + s.recordsFrom(filters) + ` LEFT JOIN top ON top_value = ` + split + where + ` GROUP BY 1, 2`
+		args = append(append(append(append(args, whereArgs...), topK), bucketArgs...), whereArgs...)
+	}
+
+	rows, err := s.rdb.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute timeline query: %w", err)
+	}
+	defer rows.Close()
+
+	series := make(map[string]*timelineSeries)
+	first, last := int64(math.MaxInt64), int64(math.MinInt64)
+	for rows.Next() {
+		var b, count int64
+		var value interface{}
+		var other bool
+		values := make([]interface{}, len(metrics))
+		dest := []interface{}{&b, &value, &other, &count}
+		for i := range values {
+			dest = append(dest, &values[i])
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return nil, fmt.Errorf("failed to scan timeline row: %w", err)
+		}
+		key := distinctKey(value)
+		if other {
+			key = ""
+		}
+		ts, ok := series[key]
+		if !ok {
+			ts = &timelineSeries{value: value, other: other, counts: make(map[int64]int64), values: make(map[int64][]interface{})}
+			series[key] = ts
+		}
+		ts.total += count
+		ts.counts[b] = count
+		ts.values[b] = values
+		first, last = min(first, b), max(last, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("timeline rows error: %w", err)
+	}
+
+	if req.SplitBy == "" && len(series) == 0 {
+		series[""] = &timelineSeries{}
+	}
+
+	lo, hi := timestampBounds(filters)
+	if lo != math.MinInt64 {
+		first = bk.start(lo)
+	}
+	if hi != math.MaxInt64 {
+		last = bk.start(hi)
+	}
+	result := &domain.TimelineResult{
+		BucketMs: bk.width(),
+		Interval: bk.String(),
+		Timezone: bk.loc.String(),
+		Series:   make([]domain.TimelineSeries, 0, len(series)*len(metrics)),
+	}
+	if first > last {
+		return result, nil
+	}
+	var buckets []int64
+	for b := first; b <= last; b = bk.next(b) {
+		if len(buckets) == maxTimelineBuckets {
+			return nil, fmt.Errorf("timeline needs more than the maximum of %d buckets; use a wider interval", maxTimelineBuckets)
+		}
+		buckets = append(buckets, b)
+	}
+	result.From, result.To = first, bk.next(last)
+
+	ordered := make([]*timelineSeries, 0, len(series))
+	for _, ts := range series {
+		ordered = append(ordered, ts)
+	}
+	sort.Slice(ordered, func(i, j int) bool {
+		a, b := ordered[i], ordered[j]
+		if a.other != b.other {
+			return b.other
+		}
+		if a.total != b.total {
+			return a.total > b.total
+		}
+		return fmt.Sprint(a.value) < fmt.Sprint(b.value)
+	})
+
+	for m, metric := range metrics {
+		for _, ts := range ordered {
+			out := domain.TimelineSeries{Metric: metric.name, Value: ts.value, Other: ts.other, Total: ts.total}
+			switch {
+			case req.SplitBy == "":
+				out.Name, out.Value, out.Other = metric.name, nil, false
+			case ts.other:
+				out.Name, out.Value = "other", nil
+			default:
+				out.Name = fmt.Sprint(ts.value)
+			}
+			out.Points = make([]domain.TimelinePoint, 0, len(buckets))
+			for _, b := range buckets {
+				point := domain.TimelinePoint{BucketStart: b, Count: ts.counts[b]}
+				if values, ok := ts.values[b]; ok {
+					if v, ok := numericValue(values[m]); ok {
+						point.Value = &v
+					}
+				}
+				if point.Value == nil && metric.zero {
+					point.Value = new(float64)
+				}
+				// Calendar buckets vary in width, so rates are per bucket.
+				if point.Value != nil && metric.rate {
+					*point.Value = *point.Value * 1000 / float64(bk.next(b)-b)
+				}
+				out.Points = append(out.Points, point)
+			}
+			result.Series = append(result.Series, out)
+		}
+	}
+	return result, nil
+}
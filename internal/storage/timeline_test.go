@@ -0,0 +1,185 @@
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"LogLens/internal/domain"
+)
+
+func seriesCounts(s domain.TimelineSeries) []int64 {
+	counts := make([]int64, len(s.Points))
+	for i, p := range s.Points {
+		counts[i] = p.Count
+	}
+	return counts
+}
+
+func TestTimelineSeries_SplitTopKWithOther(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	// 120 records one second apart: api 60, worker 30, db 30.
+	storeRecords(t, storage, serviceRecords(120))
+
+	result, err := storage.TimelineSeries(context.Background(), domain.TimelineRequest{
+		BucketMs: 60000,
+		SplitBy:  "service",
+		TopK:     2,
+	})
+	if err != nil {
+		t.Fatalf("TimelineSeries failed: %v", err)
+	}
+	if result.From != 0 || result.To != 120000 {
+		t.Errorf("expected range [0, 120000), got [%d, %d)", result.From, result.To)
+	}
+
+	var names []string
+	for _, s := range result.Series {
+		names = append(names, s.Name)
+		if len(s.Points) != 2 {
+			t.Errorf("series %s: expected 2 points, got %d", s.Name, len(s.Points))
+		}
+	}
+	if len(names) != 3 || names[0] != "api" || names[1] != "db" || names[2] != "other" {
+		t.Fatalf("expected api, db, other, got %v", names)
+	}
+	if got := seriesCounts(result.Series[0]); got[0] != 30 || got[1] != 30 {
+		t.Errorf("api: expected [30 30], got %v", got)
+	}
+	if other := result.Series[2]; !other.Other || other.Total != 30 {
+		t.Errorf("expected other series with 30 records, got %+v", other)
+	}
+}
+
+func TestTimelineSeries_ZeroFillsRequestedRange(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	storeRecords(t, storage, []domain.LogRecord{
+		{ID: "1", Timestamp: 2500, Level: "INFO", Message: "a", Raw: "a"},
+		{ID: "2", Timestamp: 2600, Level: "ERROR", Message: "b", Raw: "b"},
+		{ID: "3", Timestamp: 5100, Level: "INFO", Message: "c", Raw: "c"},
+		{ID: "4", Timestamp: 9000, Level: "INFO", Message: "d", Raw: "d"},
+	})
+
+	result, err := storage.TimelineSeries(context.Background(), domain.TimelineRequest{
+		BucketMs: 1000,
+		SplitBy:  "level",
+		From:     1000,
+		To:       7000,
+	})
+	if err != nil {
+		t.Fatalf("TimelineSeries failed: %v", err)
+	}
+	if result.From != 1000 || result.To != 7000 {
+		t.Errorf("expected range [1000, 7000), got [%d, %d)", result.From, result.To)
+	}
+	if len(result.Series) != 2 {
+		t.Fatalf("expected INFO and ERROR series, got %+v", result.Series)
+	}
+
+	want := map[string][]int64{
+		"INFO":  {0, 1, 0, 0, 1, 0},
+		"ERROR": {0, 1, 0, 0, 0, 0},
+	}
+	for _, s := range result.Series {
+		got := seriesCounts(s)
+		if len(got) != len(want[s.Name]) {
+			t.Fatalf("%s: expected %v, got %v", s.Name, want[s.Name], got)
+		}
+		for i := range got {
+			if got[i] != want[s.Name][i] {
+				t.Errorf("%s: expected %v, got %v", s.Name, want[s.Name], got)
+				break
+			}
+		}
+	}
+}
+
+func TestTimelineSeries_Unsplit(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+
+	result, err := storage.TimelineSeries(context.Background(), domain.TimelineRequest{BucketMs: 1000, From: 1000, To: 4000})
+	if err != nil {
+		t.Fatalf("TimelineSeries failed: %v", err)
+	}
+	if len(result.Series) != 1 || result.Series[0].Name != "count" || len(result.Series[0].Points) != 3 {
+		t.Errorf("expected one zero-filled series over an empty store, got %+v", result.Series)
+	}
+
+	if _, err := storage.TimelineSeries(context.Background(), domain.TimelineRequest{BucketMs: 1, From: 1, To: 1 << 40}); err == nil {
+		t.Error("expected an error for too many buckets")
+	}
+}
+
+func TestTimelineSeries_Metrics(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	// status cycles 200, 300, 400; every fourth record is an ERROR.
+	storeRecords(t, storage, serviceRecords(120))
+
+	result, err := storage.TimelineSeries(context.Background(), domain.TimelineRequest{
+		BucketMs: 60000,
+		To:       180000,
+		Metrics: []domain.TimelineMetric{
+			{Aggregation: domain.Aggregation{Function: "avg", Field: "status"}},
+			{Aggregation: domain.Aggregation{Function: "p95", Field: "status"}},
+			{Aggregation: domain.Aggregation{Function: "error_ratio"}},
+			{Aggregation: domain.Aggregation{Function: "count"}, Rate: true},
+		},
+	})
+	if err != nil {
+		t.Fatalf("TimelineSeries failed: %v", err)
+	}
+	if len(result.Series) != 4 {
+		t.Fatalf("expected 4 series, got %+v", result.Series)
+	}
+
+	want := []struct {
+		name   string
+		values []float64
+	}{
+		{"avg_status", []float64{300, 300}},
+		{"p95_status", []float64{400, 400}},
+		{"error_ratio", []float64{0.25, 0.25}},
+		{"count_per_sec", []float64{1, 1, 0}},
+	}
+	for i, w := range want {
+		s := result.Series[i]
+		if s.Name != w.name || s.Metric != w.name || len(s.Points) != 3 {
+			t.Fatalf("series %d: expected %s over 3 buckets, got %+v", i, w.name, s)
+		}
+		for j, v := range w.values {
+			if got := s.Points[j].Value; got == nil || *got != v {
+				t.Errorf("%s[%d]: expected %v, got %v", w.name, j, v, got)
+			}
+		}
+	}
+	if empty := result.Series[0].Points[2]; empty.Count != 0 || empty.Value != nil {
+		t.Errorf("expected no average for an empty bucket, got %+v", empty)
+	}
+}
+
+func TestTimelineSeries_SplitMetricsPerValue(t *testing.T) {
+	storage, cleanup := newTestStorage(t)
+	defer cleanup()
+	storeRecords(t, storage, serviceRecords(120))
+
+	result, err := storage.TimelineSeries(context.Background(), domain.TimelineRequest{
+		BucketMs: 120000,
+		SplitBy:  "service",
+		TopK:     1,
+		Metrics:  []domain.TimelineMetric{{Aggregation: domain.Aggregation{Function: "max", Field: "status"}}},
+	})
+	if err != nil {
+		t.Fatalf("TimelineSeries failed: %v", err)
+	}
+	if len(result.Series) != 2 || result.Series[0].Name != "api" || !result.Series[1].Other {
+		t.Fatalf("expected api and other series, got %+v", result.Series)
+	}
+	for _, s := range result.Series {
+		if v := s.Points[0].Value; s.Metric != "max_status" || v == nil || *v != 400 {
+			t.Errorf("%s: expected max_status 400, got %+v", s.Name, s.Points[0])
+		}
+	}
+}
@@ -0,0 +1,297 @@
+// Package trace assembles OpenTelemetry-style span trees from log records
+// carrying trace, span and parent span IDs.
+package trace
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"LogLens/internal/correlation"
+	"LogLens/internal/domain"
+)
+
+// MaxSlowSpans is the number of spans flagged as slow in a trace.
+const MaxSlowSpans = 3
+
+// Fields names the record fields a trace is assembled from. Duration and
+// Name are optional.
+type Fields struct {
+	Trace    string `json:"trace"`
+	Span     string `json:"span"`
+	Parent   string `json:"parent"`
+	Duration string `json:"duration"`
+	Name     string `json:"name"`
+}
+
+// candidates lists the recognised names of each field, normalized by
+// correlation.NormalizeName, in order of preference. Durations are in
+// milliseconds.
+var candidates = struct {
+	trace, span, parent, duration, name []string
+}{
+	trace:    []string{"traceid"},
+	span:     []string{"spanid"},
+	parent:   []string{"parentspanid", "parentid"},
+	duration: []string{"durationms", "duration", "elapsedms", "latencyms", "tookms"},
+	name:     []string{"spanname", "operationname", "operation", "name"},
+}
+
+// DetectFields picks the trace fields among the top-level catalog fields.
+// Of several spellings of a field, the most seen one is picked.
+func DetectFields(catalog []domain.FieldInfo) (Fields, error) {
+	pick := func(names []string) string {
+		for _, name := range names {
+			var best *domain.FieldInfo
+			for i, f := range catalog {
+				if strings.Contains(f.Path, ".") || correlation.NormalizeName(f.Path) != name {
+					continue
+				}
+				if best == nil || f.Seen > best.Seen {
+					best = &catalog[i]
+				}
+			}
+			if best != nil {
+				return best.Path
+			}
+		}
+		return ""
+	}
+	f := Fields{
+		Trace:    pick(candidates.trace),
+		Span:     pick(candidates.span),
+		Parent:   pick(candidates.parent),
+		Duration: pick(candidates.duration),
+		Name:     pick(candidates.name),
+	}
+	if f.Trace == "" || f.Span == "" {
+		return f, fmt.Errorf("no trace and span ID fields found")
+	}
+	return f, nil
+}
+
+// idString renders an ID field value, or "" if there is none.
+func idString(v interface{}) string {
+	switch id := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return id
+	case float64:
+		return strconv.FormatFloat(id, 'f', -1, 64)
+	default:
+		return fmt.Sprint(id)
+	}
+}
+
+func durationMs(v interface{}) (int64, bool) {
+	switch d := v.(type) {
+	case float64:
+		return int64(d), true
+	case string:
+		f, err := strconv.ParseFloat(d, 64)
+		return int64(f), err == nil
+	default:
+		return 0, false
+	}
+}
+
+type span struct {
+	domain.TraceSpan
+	end      int64
+	duration int64
+	timed    bool
+	children []*span
+	visited  bool
+}
+
+// field returns record's value of name, or of a top-level field spelling
+// it differently, such as spanId for span_id.
+func field(record domain.LogRecord, name string) interface{} {
+	if v, ok := record.Fields[name]; ok || name == "" {
+		return v
+	}
+	normalized := correlation.NormalizeName(name)
+	for k, v := range record.Fields {
+		if correlation.NormalizeName(k) == normalized {
+			return v
+		}
+	}
+	return nil
+}
+
+// Assemble links the spans of records into a tree. A span runs from its
+// first record to its last. A logged duration is taken to end at the last
+// record, as in "request finished, duration=X", and extends the start back.
+// Fields are read in any spelling, and records without a span ID are
+// ignored.
+func Assemble(traceID string, records []domain.LogRecord, f Fields) *domain.Trace {
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Timestamp < records[j].Timestamp })
+
+	spans := make(map[string]*span)
+	var order []*span
+	for _, r := range records {
+		id := idString(field(r, f.Span))
+		if id == "" {
+			continue
+		}
+		s, ok := spans[id]
+		if !ok {
+			s = &span{TraceSpan: domain.TraceSpan{SpanID: id, Start: r.Timestamp, Name: r.Message}}
+			s.end = r.Timestamp
+			spans[id] = s
+			order = append(order, s)
+		}
+		s.RecordIDs = append(s.RecordIDs, r.ID)
+		s.end = max(s.end, r.Timestamp)
+		if s.ParentID == "" {
+			s.ParentID = idString(field(r, f.Parent))
+		}
+		if name := idString(field(r, f.Name)); name != "" {
+			s.Name = name
+		}
+		if s.Service == "" {
+			s.Service = r.Service
+		}
+		if d, ok := durationMs(field(r, f.Duration)); ok {
+			s.duration, s.timed = max(s.duration, d), true
+		}
+		if domain.IsErrorLevel(r.Level) || r.Fields["error"] == true {
+			s.Error = true
+		}
+	}
+
+	t := &domain.Trace{TraceID: traceID, Spans: make([]domain.TraceSpan, 0, len(order))}
+	if len(order) == 0 {
+		return t
+	}
+
+	var roots []*span
+	for _, s := range order {
+		if s.timed {
+			s.Start = min(s.Start, s.end-s.duration)
+		}
+		parent, ok := spans[s.ParentID]
+		switch {
+		case s.ParentID == "" || s.ParentID == s.SpanID:
+			s.ParentID = ""
+			roots = append(roots, s)
+		case !ok:
+			s.Orphan = true
+			roots = append(roots, s)
+		default:
+			parent.children = append(parent.children, s)
+		}
+	}
+
+	t.Start, t.End = order[0].Start, order[0].end
+	for _, s := range order {
+		t.Start, t.End = min(t.Start, s.Start), max(t.End, s.end)
+		s.DurationMs = s.end - s.Start
+		sort.SliceStable(s.children, func(i, j int) bool { return s.children[i].Start < s.children[j].Start })
+		s.SelfTimeMs = selfTime(s)
+	}
+	t.DurationMs = t.End - t.Start
+
+	// The critical path runs through the root that finishes last.
+	var main *span
+	for _, r := range roots {
+		if !r.Orphan && (main == nil || r.end > main.end) {
+			main = r
+		}
+	}
+	if main != nil {
+		markCritical(main)
+	}
+
+	var walk func(s *span, depth int)
+	walk = func(s *span, depth int) {
+		if s.visited {
+			return
+		}
+		s.visited = true
+		s.Depth = depth
+		s.OffsetMs = s.Start - t.Start
+		t.Spans = append(t.Spans, s.TraceSpan)
+		for _, c := range s.children {
+			walk(c, depth+1)
+		}
+	}
+	sort.SliceStable(roots, func(i, j int) bool { return roots[i].Start < roots[j].Start })
+	for _, r := range roots {
+		walk(r, 0)
+	}
+	// Spans in a parent cycle are unreachable from any root.
+	for _, s := range order {
+		if !s.visited {
+			s.Orphan = true
+			walk(s, 0)
+		}
+	}
+
+	markSlow(t.Spans)
+	for _, s := range t.Spans {
+		if s.Error {
+			t.Errors++
+		}
+		if s.Orphan {
+			t.Orphans++
+		}
+	}
+	return t
+}
+
+// selfTime is the part of s not covered by any of its children, which are
+// sorted by start.
+func selfTime(s *span) int64 {
+	covered := int64(0)
+	cursor := s.Start
+	for _, c := range s.children {
+		start, end := max(c.Start, cursor), min(c.end, s.end)
+		if end > start {
+			covered += end - start
+			cursor = end
+		}
+	}
+	return s.DurationMs - covered
+}
+
+// markCritical marks the chain of spans that determines when s finishes:
+// the child finishing last, then walking back in time, the child finishing
+// last before that one started, and so on.
+func markCritical(s *span) {
+	s.Critical = true
+	until := s.end
+	used := make(map[*span]bool)
+	for {
+		var best *span
+		for _, c := range s.children {
+			if used[c] || c.Start >= until {
+				continue
+			}
+			if best == nil || min(c.end, until) > min(best.end, until) {
+				best = c
+			}
+		}
+		if best == nil {
+			return
+		}
+		used[best] = true
+		markCritical(best)
+		until = best.Start
+	}
+}
+
+func markSlow(spans []domain.TraceSpan) {
+	idx := make([]int, len(spans))
+	for i := range idx {
+		idx[i] = i
+	}
+	sort.SliceStable(idx, func(a, b int) bool { return spans[idx[a]].SelfTimeMs > spans[idx[b]].SelfTimeMs })
+	for i := 0; i < len(idx) && i < MaxSlowSpans; i++ {
+		if spans[idx[i]].SelfTimeMs > 0 {
+			spans[idx[i]].Slow = true
+		}
+	}
+}
@@ -0,0 +1,139 @@
+package trace
+
+import (
+	"testing"
+
+	"LogLens/internal/domain"
+)
+
+var otelFields = Fields{Trace: "trace_id", Span: "span_id", Parent: "parent_span_id", Duration: "duration_ms", Name: "name"}
+
+// spanRecord logs span finishing at start+duration, or at start when
+// duration is 0.
+func spanRecord(id, span, parent string, start, duration int64, level string) domain.LogRecord {
+	fields := map[string]interface{}{"trace_id": "t1", "span_id": span, "name": "op " + span}
+	if parent != "" {
+		fields["parent_span_id"] = parent
+	}
+	if duration > 0 {
+		fields["duration_ms"] = float64(duration)
+	}
+	return domain.LogRecord{ID: id, Timestamp: start + duration, Level: level, Message: "span " + span, Service: "svc", Fields: fields}
+}
+
+func TestDetectFields(t *testing.T) {
+	catalog := []domain.FieldInfo{
+		{Path: "traceId"}, {Path: "spanId"}, {Path: "parentId"}, {Path: "elapsed_ms"}, {Path: "operation"}, {Path: "http.name"},
+	}
+	f, err := DetectFields(catalog)
+	if err != nil {
+		t.Fatalf("DetectFields failed: %v", err)
+	}
+	want := Fields{Trace: "traceId", Span: "spanId", Parent: "parentId", Duration: "elapsed_ms", Name: "operation"}
+	if f != want {
+		t.Errorf("expected %+v, got %+v", want, f)
+	}
+
+	f, _ = DetectFields([]domain.FieldInfo{{Path: "traceId", Seen: 1}, {Path: "trace_id", Seen: 5}, {Path: "span_id"}})
+	if f.Trace != "trace_id" {
+		t.Errorf("expected the most seen spelling, got %s", f.Trace)
+	}
+
+	if _, err := DetectFields([]domain.FieldInfo{{Path: "trace_id"}}); err == nil {
+		t.Error("expected an error without a span ID field")
+	}
+}
+
+func TestAssemble_Waterfall(t *testing.T) {
+	records := []domain.LogRecord{
+		spanRecord("r1", "A", "", 0, 100, "INFO"),
+		spanRecord("r2", "B", "A", 10, 30, "INFO"),
+		spanRecord("r3", "C", "A", 30, 60, "INFO"),
+		spanRecord("r4", "D", "C", 35, 0, "INFO"),
+		spanRecord("r5", "D", "C", 80, 0, "ERROR"),
+		spanRecord("r6", "E", "X", 50, 10, "INFO"),
+	}
+
+	tr := Assemble("t1", records, otelFields)
+	if tr.Start != 0 || tr.End != 100 || tr.DurationMs != 100 || tr.Errors != 1 || tr.Orphans != 1 {
+		t.Errorf("unexpected trace: %+v", tr)
+	}
+
+	want := []struct {
+		id       string
+		depth    int
+		offset   int64
+		duration int64
+		self     int64
+		critical bool
+		slow     bool
+	}{
+		{"A", 0, 0, 100, 20, true, true},
+		{"B", 1, 10, 30, 30, true, true},
+		{"C", 1, 30, 60, 15, true, false},
+		{"D", 2, 35, 45, 45, true, true},
+		{"E", 0, 50, 10, 10, false, false},
+	}
+	if len(tr.Spans) != len(want) {
+		t.Fatalf("expected %d spans, got %+v", len(want), tr.Spans)
+	}
+	for i, w := range want {
+		s := tr.Spans[i]
+		if s.SpanID != w.id || s.Depth != w.depth || s.OffsetMs != w.offset || s.DurationMs != w.duration ||
+			s.SelfTimeMs != w.self || s.Critical != w.critical || s.Slow != w.slow {
+			t.Errorf("span %d: expected %+v, got %+v", i, w, s)
+		}
+	}
+	if d := tr.Spans[3]; !d.Error || len(d.RecordIDs) != 2 || d.Name != "op D" {
+		t.Errorf("expected D with two records and an error, got %+v", d)
+	}
+	if e := tr.Spans[4]; !e.Orphan || e.ParentID != "X" {
+		t.Errorf("expected E to be an orphan of X, got %+v", e)
+	}
+}
+
+func TestAssemble_ParentCycle(t *testing.T) {
+	records := []domain.LogRecord{
+		spanRecord("r1", "A", "", 0, 10, "INFO"),
+		spanRecord("r2", "B", "C", 1, 2, "INFO"),
+		spanRecord("r3", "C", "B", 2, 2, "INFO"),
+	}
+
+	tr := Assemble("t1", records, otelFields)
+	if len(tr.Spans) != 3 || tr.Orphans != 1 {
+		t.Fatalf("expected all spans with the cycle entry as an orphan, got %+v", tr.Spans)
+	}
+	if tr.Spans[1].SpanID != "B" || !tr.Spans[1].Orphan || tr.Spans[2].Depth != 1 {
+		t.Errorf("unexpected cycle layout: %+v", tr.Spans)
+	}
+}
+
+func TestAssemble_DurationEndsAtLastRecord(t *testing.T) {
+	records := []domain.LogRecord{
+		spanRecord("r1", "A", "", 0, 100, "INFO"),
+		spanRecord("r2", "B", "A", 10, 0, "INFO"),
+		spanRecord("r3", "B", "A", 10, 40, "INFO"),
+		spanRecord("r4", "C", "A", 65, 30, "INFO"),
+	}
+
+	tr := Assemble("t1", records, otelFields)
+	if tr.Start != 0 || tr.End != 100 || len(tr.Spans) != 3 {
+		t.Fatalf("unexpected trace: %+v", tr)
+	}
+	want := []struct {
+		id       string
+		offset   int64
+		duration int64
+		self     int64
+	}{
+		{"A", 0, 100, 30},
+		{"B", 10, 40, 40},
+		{"C", 65, 30, 30},
+	}
+	for i, w := range want {
+		s := tr.Spans[i]
+		if s.SpanID != w.id || s.OffsetMs != w.offset || s.DurationMs != w.duration || s.SelfTimeMs != w.self || !s.Critical {
+			t.Errorf("span %d: expected %+v on the critical path, got %+v", i, w, s)
+		}
+	}
+}